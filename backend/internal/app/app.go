@@ -2,12 +2,22 @@ package app
 
 import (
 	"log"
+	"time"
 
+	"github.com/qicro/qicro/backend/internal/artifact"
+	"github.com/qicro/qicro/backend/internal/assistant"
 	"github.com/qicro/qicro/backend/internal/auth"
 	"github.com/qicro/qicro/backend/internal/chat"
 	configManagement "github.com/qicro/qicro/backend/internal/config"
+	"github.com/qicro/qicro/backend/internal/export"
+	"github.com/qicro/qicro/backend/internal/feedback"
+	"github.com/qicro/qicro/backend/internal/importer"
+	"github.com/qicro/qicro/backend/internal/jobs"
+	"github.com/qicro/qicro/backend/internal/knowledge"
 	"github.com/qicro/qicro/backend/internal/llm"
 	"github.com/qicro/qicro/backend/internal/router"
+	"github.com/qicro/qicro/backend/internal/search"
+	"github.com/qicro/qicro/backend/internal/share"
 	"github.com/qicro/qicro/backend/internal/websocket"
 	"github.com/qicro/qicro/backend/pkg/config"
 	"github.com/qicro/qicro/backend/pkg/database"
@@ -45,6 +55,9 @@ func New() (*App, error) {
 	if err := db.CreateTables(); err != nil {
 		return nil, err
 	}
+	if err := db.CreateSearchIndexes(cfg.Chat.TextSearchConfig); err != nil {
+		return nil, err
+	}
 
 	// 连接Redis
 	redisClient, err := database.NewRedisClient(
@@ -74,7 +87,7 @@ func New() (*App, error) {
 // initializeDependencies 初始化依赖
 func initializeDependencies(cfg *config.Config, db *database.DB, redisClient *database.RedisClient) *router.Dependencies {
 	// 初始化WebSocket Hub
-	wsHub := websocket.NewHub()
+	wsHub := websocket.NewHub(redisClient.Client)
 	go wsHub.Run()
 
 	// 初始化配置管理服务
@@ -100,10 +113,74 @@ func initializeDependencies(cfg *config.Config, db *database.DB, redisClient *da
 	// 初始化聊天服务
 	chatRepo := chat.NewRepository(db.DB)
 	chatService := chat.NewService(chatRepo, llmService)
+	chatService.SetGenerationRegistry(chat.NewGenerationRegistry(redisClient.Client))
+	chatService.SetEventLog(chat.NewEventLog(redisClient.Client))
+	chatService.SetTextSearchConfig(cfg.Chat.TextSearchConfig)
+	if cfg.Search.Backend != "" {
+		searchBackend, err := search.NewBackend(cfg.Search.Backend, cfg.Search.APIURL, cfg.Search.APIKey)
+		if err != nil {
+			log.Printf("Warning: web search disabled: %v", err)
+		} else {
+			chatService.SetSearchService(search.NewService(searchBackend, llmService, cfg.Search.MaxResults, cfg.Search.Summarize, cfg.Search.SummaryModel))
+		}
+	}
+
+	// 初始化artifact服务
+	artifactRepo := artifact.NewRepository(db.DB)
+	artifactRenderer := artifact.NewRenderer(
+		cfg.Artifact.PreviewSecret,
+		time.Duration(cfg.Artifact.PreviewTTLMinutes)*time.Minute,
+		cfg.Artifact.FrameAncestors,
+		cfg.Artifact.PNGRenderer,
+	)
+	artifactService := artifact.NewService(artifactRepo, artifactRenderer)
+	artifactHandler := artifact.NewHandler(artifactService)
+	chatService.SetArtifactService(artifactService)
+
 	chatHandler := chat.NewHandler(chatService)
 
-	// 将Eino服务注入到聊天服务中（如果需要扩展功能）
-	_ = einoService // 暂时标记为已使用，后续可以扩展
+	// 初始化知识库服务
+	knowledgeRepo := knowledge.NewRepository(db.DB)
+	knowledgeService := knowledge.NewService(knowledgeRepo, llmService)
+	knowledgeService.SetConnectorAllowedHosts(cfg.Knowledge.ConnectorAllowedHosts)
+	knowledgeHandler := knowledge.NewHandler(knowledgeService)
+	chatService.SetKnowledgeService(knowledgeService)
+
+	// 初始化助手服务
+	assistantRepo := assistant.NewRepository(db.DB)
+	assistantService := assistant.NewService(assistantRepo, configService, knowledgeService, chatService)
+	assistantHandler := assistant.NewHandler(assistantService)
+
+	// 初始化分享服务
+	shareRepo := share.NewRepository(db.DB)
+	shareService := share.NewService(shareRepo, chatService)
+	shareService.SetAttemptLimiter(share.NewAttemptLimiter(redisClient.Client))
+	shareHandler := share.NewHandler(shareService)
+
+	// 初始化后台任务与导入导出服务
+	jobRepo := jobs.NewRepository(db.DB)
+	jobService := jobs.NewService(jobRepo)
+	jobService.Recover()
+	jobHandler := jobs.NewHandler(jobService)
+	exportService := export.NewService(chatService, artifactService, jobService)
+	if cfg.Export.PDFFont != "" {
+		if err := exportService.SetPDFFont(cfg.Export.PDFFont); err != nil {
+			log.Printf("Warning: PDF export will not embed a font: %v", err)
+		}
+	}
+	exportHandler := export.NewHandler(exportService)
+	importService := importer.NewService(chatService, jobService)
+	importHandler := importer.NewHandler(importService)
+
+	// 初始化消息评价服务
+	feedbackRepo := feedback.NewRepository(db.DB)
+	feedbackService := feedback.NewService(feedbackRepo, chatService)
+	feedbackHandler := feedback.NewHandler(feedbackService)
+
+	// 使用Eino标题链在首轮问答后自动生成对话标题，并通过WebSocket推送
+	chatService.SetTitleGenerator(einoService, cfg.Chat.TitleModel)
+	chatService.SetNotifier(wsHub)
+	wsHub.SetChatService(chatService)
 
 	// 初始化认证服务
 	authRepo := auth.NewRepository(db.DB)
@@ -116,14 +193,27 @@ func initializeDependencies(cfg *config.Config, db *database.DB, redisClient *da
 		"http://localhost:3000/auth/callback",
 	)
 	authService := auth.NewService(authRepo, jwtService, oauthService)
+	authService.SetAdminEmails(cfg.Auth.AdminEmails)
 	authHandler := auth.NewHandler(authService)
 
+	// WebSocket握手使用同一套JWT认证
+	wsHub.SetAuthenticator(authService)
+	wsHub.SetAllowedOrigins(cfg.WebSocket.AllowedOrigins)
+
 	return &router.Dependencies{
-		AuthHandler:   authHandler,
-		ChatHandler:   chatHandler,
-		ConfigHandler: configHandler,
-		LLMHandler:    llmHandler,
-		WSHub:         wsHub,
+		ArtifactHandler:  artifactHandler,
+		AssistantHandler: assistantHandler,
+		AuthHandler:      authHandler,
+		ChatHandler:      chatHandler,
+		ConfigHandler:    configHandler,
+		ExportHandler:    exportHandler,
+		FeedbackHandler:  feedbackHandler,
+		ImportHandler:    importHandler,
+		JobHandler:       jobHandler,
+		KnowledgeHandler: knowledgeHandler,
+		LLMHandler:       llmHandler,
+		ShareHandler:     shareHandler,
+		WSHub:            wsHub,
 	}
 }
 
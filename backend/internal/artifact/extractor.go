@@ -0,0 +1,308 @@
+package artifact
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	titlePattern       = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	rawSVGPattern      = regexp.MustCompile(`(?is)<svg\b.*?</svg>`)
+	rawHTMLPattern     = regexp.MustCompile(`(?is)(<!doctype html.*?</html>|<html\b.*?</html>)`)
+	commentFilePattern = regexp.MustCompile(`^\s*(?://|#|--|<!--|/\*)\s*(?:file(?:name)?\s*:\s*)?([\w./-]+\.[A-Za-z0-9]+)\s*(?:-->|\*/)?\s*$`)
+	infoFilePattern    = regexp.MustCompile(`^(?:title|file|filename|path)=["']?([^"']+)["']?$`)
+	filenamePattern    = regexp.MustCompile(`^[\w./-]+\.[A-Za-z0-9]+$`)
+)
+
+// Extracted 从回复文本中解析出的artifact
+type Extracted struct {
+	Identifier string
+	Type       string
+	Title      string
+	Language   string
+	Content    string
+}
+
+// Extract 解析助手回复中的HTML文档、SVG、Mermaid图表以及带文件名的代码块
+// 没有文件名的普通代码片段不会被视为artifact；没有文件名或标题的artifact按类型内的序号生成标识符（如mermaid#1）
+func Extract(content string) []Extracted {
+	var results []Extracted
+	var prose strings.Builder
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openingFence(lines[i])
+		if !ok {
+			prose.WriteString(lines[i])
+			prose.WriteString("\n")
+			continue
+		}
+
+		var body []string
+		closed := false
+		for i++; i < len(lines); i++ {
+			if isClosingFence(lines[i], fence) {
+				closed = true
+				break
+			}
+			body = append(body, lines[i])
+		}
+		if !closed {
+			// 未闭合的代码块（例如生成被中断）不作为artifact
+			break
+		}
+
+		if extracted, ok := classifyBlock(info, strings.Join(body, "\n")); ok {
+			results = append(results, extracted)
+		}
+	}
+
+	results = append(results, extractRaw(prose.String())...)
+	untitled := make(map[string]int)
+	for i := range results {
+		if results[i].Identifier == "" {
+			untitled[results[i].Type]++
+			results[i].Identifier = untitledIdentifier(results[i].Type, untitled[results[i].Type])
+		}
+	}
+	return uniqueIdentifiers(results)
+}
+
+// openingFence 判断是否为代码块起始行，返回围栏字符串与info
+func openingFence(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", "", false
+	}
+	for _, marker := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == marker {
+			n++
+		}
+		if n >= 3 {
+			info := strings.TrimSpace(trimmed[n:])
+			if marker == '`' && strings.Contains(info, "`") {
+				return "", "", false
+			}
+			return trimmed[:n], info, true
+		}
+	}
+	return "", "", false
+}
+
+// isClosingFence 判断是否为对应的代码块结束行
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < len(fence) || trimmed[0] != fence[0] {
+		return false
+	}
+	return strings.Trim(trimmed, string(fence[0])) == ""
+}
+
+// parseInfo 从代码块info中解析语言与文件名
+// 支持 ```python:main.py、```python main.py、```go title="main.go" 等写法
+func parseInfo(info string) (string, string) {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	language := fields[0]
+	filename := ""
+	if idx := strings.Index(language, ":"); idx > 0 {
+		filename = language[idx+1:]
+		language = language[:idx]
+	}
+	language = strings.ToLower(strings.Trim(language, "{}."))
+
+	for _, field := range fields[1:] {
+		if matches := infoFilePattern.FindStringSubmatch(field); matches != nil {
+			filename = matches[1]
+		} else if filename == "" && filenamePattern.MatchString(field) {
+			filename = field
+		}
+	}
+
+	return language, filename
+}
+
+// classifyBlock 根据语言与内容判断代码块的artifact类型
+func classifyBlock(info, body string) (Extracted, bool) {
+	language, filename := parseInfo(info)
+	if filename == "" {
+		if firstLine, _, _ := strings.Cut(body, "\n"); firstLine != "" {
+			if matches := commentFilePattern.FindStringSubmatch(firstLine); matches != nil {
+				filename = matches[1]
+			}
+		}
+	}
+
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return Extracted{}, false
+	}
+	lower := strings.ToLower(trimmed)
+
+	extracted := Extracted{Language: language, Content: trimmed}
+
+	switch {
+	case language == "html" && (isHTMLDocument(lower) || filename != ""):
+		extracted.Type = TypeHTML
+		extracted.Title = titleOr(trimmed, filename, "HTML Document")
+		extracted.Identifier = identifierFor(TypeHTML, documentTitle(trimmed), filename)
+	case language == "svg" || ((language == "xml" || language == "") && strings.HasPrefix(skipXMLProlog(lower), "<svg")):
+		extracted.Type = TypeSVG
+		extracted.Title = titleOr(trimmed, filename, "SVG Image")
+		extracted.Identifier = identifierFor(TypeSVG, documentTitle(trimmed), filename)
+	case language == "mermaid":
+		extracted.Type = TypeMermaid
+		extracted.Title = filename
+		extracted.Identifier = identifierFor(TypeMermaid, "", filename)
+		if extracted.Title == "" {
+			extracted.Title = "Mermaid " + mermaidDiagramType(trimmed)
+		}
+	case filename != "":
+		extracted.Type = TypeCode
+		extracted.Title = filename
+		extracted.Identifier = identifierFor(TypeCode, "", filename)
+		if extracted.Language == "" {
+			extracted.Language = strings.TrimPrefix(path.Ext(filename), ".")
+		}
+	default:
+		return Extracted{}, false
+	}
+
+	return extracted, true
+}
+
+// extractRaw 提取代码块之外直接输出的SVG与HTML文档
+func extractRaw(prose string) []Extracted {
+	var results []Extracted
+
+	for _, match := range rawHTMLPattern.FindAllString(prose, -1) {
+		results = append(results, Extracted{
+			Identifier: identifierFor(TypeHTML, documentTitle(match), ""),
+			Type:       TypeHTML,
+			Title:      titleOr(match, "", "HTML Document"),
+			Language:   "html",
+			Content:    strings.TrimSpace(match),
+		})
+		prose = strings.Replace(prose, match, "", 1)
+	}
+
+	for _, match := range rawSVGPattern.FindAllString(prose, -1) {
+		results = append(results, Extracted{
+			Identifier: identifierFor(TypeSVG, documentTitle(match), ""),
+			Type:       TypeSVG,
+			Title:      titleOr(match, "", "SVG Image"),
+			Language:   "svg",
+			Content:    strings.TrimSpace(match),
+		})
+	}
+
+	return results
+}
+
+// uniqueIdentifiers 同一条回复中出现重复标识符时追加序号，避免互相覆盖版本
+func uniqueIdentifiers(results []Extracted) []Extracted {
+	seen := make(map[string]int)
+	for i := range results {
+		seen[results[i].Identifier]++
+		if count := seen[results[i].Identifier]; count > 1 {
+			results[i].Identifier = fmt.Sprintf("%s#%d", results[i].Identifier, count)
+		}
+	}
+	return results
+}
+
+// identifierFor 生成用于跨消息识别同一artifact的标识符
+// 有文件名时以文件名为准，其次使用类型与显式标题；两者都没有时返回空，由Extract按类型内的序号生成
+func identifierFor(artifactType, title, filename string) string {
+	if filename != "" {
+		return "file:" + filename
+	}
+	if title = strings.ToLower(strings.TrimSpace(title)); title != "" {
+		return artifactType + ":" + title
+	}
+	return ""
+}
+
+// untitledIdentifier 无标题artifact的标识符：类型加回复中同类型无标题artifact的序号
+// 不含冒号，不会与文件名或标题生成的标识符冲突
+func untitledIdentifier(artifactType string, n int) string {
+	return fmt.Sprintf("%s#%d", artifactType, n)
+}
+
+// isUntitled 标识符是否由untitledIdentifier生成
+func isUntitled(identifier string) bool {
+	return !strings.Contains(identifier, ":")
+}
+
+// documentTitle 获取HTML/SVG中<title>标签的内容，没有时返回空
+func documentTitle(content string) string {
+	if matches := titlePattern.FindStringSubmatch(content); matches != nil {
+		return strings.TrimSpace(matches[1])
+	}
+	return ""
+}
+
+// titleOr 优先使用<title>标签，其次使用文件名，最后使用默认标题
+func titleOr(content, filename, fallback string) string {
+	if title := documentTitle(content); title != "" {
+		return title
+	}
+	if filename != "" {
+		return filename
+	}
+	return fallback
+}
+
+// isHTMLDocument 判断是否为完整HTML文档
+func isHTMLDocument(lower string) bool {
+	return strings.HasPrefix(lower, "<!doctype html") || strings.Contains(lower, "<html")
+}
+
+// skipXMLProlog 跳过XML声明与注释
+func skipXMLProlog(lower string) string {
+	for {
+		lower = strings.TrimSpace(lower)
+		switch {
+		case strings.HasPrefix(lower, "<?xml"):
+			_, rest, ok := strings.Cut(lower, "?>")
+			if !ok {
+				return lower
+			}
+			lower = rest
+		case strings.HasPrefix(lower, "<!--"):
+			_, rest, ok := strings.Cut(lower, "-->")
+			if !ok {
+				return lower
+			}
+			lower = rest
+		case strings.HasPrefix(lower, "<!doctype svg"):
+			_, rest, ok := strings.Cut(lower, ">")
+			if !ok {
+				return lower
+			}
+			lower = rest
+		default:
+			return lower
+		}
+	}
+}
+
+// mermaidDiagramType 返回Mermaid图表类型（首个非注释行的第一个关键字）
+func mermaidDiagramType(source string) string {
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") || line == "---" {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return "diagram"
+}
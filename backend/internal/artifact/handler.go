@@ -0,0 +1,112 @@
+package artifact
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler artifact处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建artifact处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetArtifact 获取artifact内容及版本列表
+func (h *Handler) GetArtifact(c *gin.Context) {
+	artifactID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	artifact, versions, err := h.service.GetArtifact(artifactID, userID.(string))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"artifact": artifact,
+		"versions": versions,
+	})
+}
+
+// Render 渲染artifact
+func (h *Handler) Render(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req RenderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ArtifactID == "" && (req.Type == "" || req.Content == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artifact_id or type and content are required"})
+		return
+	}
+
+	result, err := h.service.Render(c.Request.Context(), userID.(string), req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "not configured"):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			writeAccessError(c, err)
+		}
+		return
+	}
+
+	if result.Mermaid != nil && !result.Mermaid.Valid {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Preview 沙箱预览，通过签名令牌授权，不依赖登录态
+func (h *Handler) Preview(c *gin.Context) {
+	artifact, err := h.service.GetPreview(c.Param("id"), c.Query("token"))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "token"):
+			c.String(http.StatusForbidden, err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			c.String(http.StatusNotFound, "artifact not found")
+		default:
+			c.String(http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	contentType, headers := h.service.PreviewHeaders(artifact.Type)
+	for key, value := range headers {
+		c.Header(key, value)
+	}
+	c.Data(http.StatusOK, contentType, []byte(artifact.Content))
+}
+
+// writeAccessError 根据错误类型返回对应状态码
+func writeAccessError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,91 @@
+package artifact
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Artifact类型
+const (
+	TypeHTML    = "html"
+	TypeSVG     = "svg"
+	TypeMermaid = "mermaid"
+	TypeCode    = "code"
+)
+
+// Artifact 从助手回复中提取的版本化产物
+type Artifact struct {
+	ID             string    `json:"id" db:"id"`
+	ConversationID string    `json:"conversation_id" db:"conversation_id"`
+	MessageID      string    `json:"message_id" db:"message_id"`
+	Identifier     string    `json:"identifier" db:"identifier"`
+	Version        int       `json:"version" db:"version"`
+	Type           string    `json:"type" db:"type"`
+	Title          string    `json:"title" db:"title"`
+	Language       string    `json:"language,omitempty" db:"language"`
+	Content        string    `json:"content" db:"content"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Ref 消息中保存的artifact引用
+type Ref struct {
+	ID         string `json:"id"`
+	Identifier string `json:"identifier"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Version    int    `json:"version"`
+}
+
+// VersionInfo artifact版本摘要
+type VersionInfo struct {
+	ID        string    `json:"id"`
+	Version   int       `json:"version"`
+	MessageID string    `json:"message_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewArtifact 创建新artifact实例
+func NewArtifact(conversationID, messageID string, extracted Extracted, version int) *Artifact {
+	return &Artifact{
+		ID:             uuid.New().String(),
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		Identifier:     extracted.Identifier,
+		Version:        version,
+		Type:           extracted.Type,
+		Title:          extracted.Title,
+		Language:       extracted.Language,
+		Content:        extracted.Content,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// Ref 返回artifact引用
+func (a *Artifact) Ref() Ref {
+	return Ref{
+		ID:         a.ID,
+		Identifier: a.Identifier,
+		Type:       a.Type,
+		Title:      a.Title,
+		Version:    a.Version,
+	}
+}
+
+// RenderRequest 渲染请求：指定已保存的artifact，或直接提交类型与内容
+type RenderRequest struct {
+	ArtifactID string `json:"artifact_id"`
+	Type       string `json:"type"`
+	Content    string `json:"content"`
+	Format     string `json:"format"`
+}
+
+// RenderResult 渲染结果
+type RenderResult struct {
+	Type             string             `json:"type"`
+	Content          string             `json:"content"`
+	Mermaid          *MermaidValidation `json:"mermaid,omitempty"`
+	PreviewURL       string             `json:"preview_url,omitempty"`
+	PreviewExpiresAt *time.Time         `json:"preview_expires_at,omitempty"`
+	PNG              string             `json:"png,omitempty"`
+}
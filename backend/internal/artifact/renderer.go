@@ -0,0 +1,137 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pngRenderTimeout PNG渲染命令的超时时间
+const pngRenderTimeout = 30 * time.Second
+
+// pngSignature PNG文件头
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Renderer 负责预览链接签名、预览响应头与可选的PNG渲染
+type Renderer struct {
+	secret         []byte
+	ttl            time.Duration
+	frameAncestors string
+	pngCommand     []string
+}
+
+// NewRenderer 创建渲染器
+// pngCommand为空时不支持PNG渲染；命令从stdin读取源码，向stdout输出PNG，artifact类型通过ARTIFACT_TYPE环境变量传入
+func NewRenderer(secret string, ttl time.Duration, frameAncestors, pngCommand string) *Renderer {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	if frameAncestors == "" {
+		frameAncestors = "'none'"
+	}
+	return &Renderer{
+		secret:         []byte(secret),
+		ttl:            ttl,
+		frameAncestors: frameAncestors,
+		pngCommand:     strings.Fields(pngCommand),
+	}
+}
+
+// SignPreview 为artifact生成限时预览令牌
+func (r *Renderer) SignPreview(artifactID string) (string, time.Time) {
+	expiresAt := time.Now().Add(r.ttl)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + r.sign(artifactID, expiry), expiresAt
+}
+
+// VerifyPreview 校验预览令牌
+func (r *Renderer) VerifyPreview(artifactID, token string) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("invalid preview token")
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid preview token")
+	}
+	if !hmac.Equal([]byte(signature), []byte(r.sign(artifactID, expiry))) {
+		return fmt.Errorf("invalid preview token")
+	}
+	if time.Now().Unix() > expiresAt {
+		return fmt.Errorf("preview token expired")
+	}
+
+	return nil
+}
+
+// sign 计算HMAC签名
+func (r *Renderer) sign(artifactID, expiry string) string {
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(artifactID + "." + expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PreviewHeaders 返回预览响应的Content-Type与安全响应头
+// HTML在无同源权限的沙箱中运行，只允许内联脚本与样式，禁止任何网络请求
+func (r *Renderer) PreviewHeaders(artifactType string) (string, map[string]string) {
+	var contentType, policy string
+	switch artifactType {
+	case TypeHTML:
+		contentType = "text/html; charset=utf-8"
+		policy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src data: blob:; " +
+			"font-src data:; media-src data: blob:; form-action 'none'; base-uri 'none'; sandbox allow-scripts"
+	case TypeSVG:
+		contentType = "image/svg+xml"
+		policy = "default-src 'none'; style-src 'unsafe-inline'; img-src data:; sandbox"
+	default:
+		contentType = "text/plain; charset=utf-8"
+		policy = "default-src 'none'; sandbox"
+	}
+
+	return contentType, map[string]string{
+		"Content-Security-Policy": policy + "; frame-ancestors " + r.frameAncestors,
+		"X-Content-Type-Options":  "nosniff",
+		"Referrer-Policy":         "no-referrer",
+		"Cache-Control":           "private, no-store",
+	}
+}
+
+// PNGEnabled 是否配置了PNG渲染命令
+func (r *Renderer) PNGEnabled() bool {
+	return len(r.pngCommand) > 0
+}
+
+// RenderPNG 调用外部命令将SVG或Mermaid源码渲染为PNG
+func (r *Renderer) RenderPNG(ctx context.Context, artifactType, source string) ([]byte, error) {
+	if !r.PNGEnabled() {
+		return nil, fmt.Errorf("png rendering is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pngRenderTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.pngCommand[0], r.pngCommand[1:]...)
+	cmd.Env = append(os.Environ(), "ARTIFACT_TYPE="+artifactType)
+	cmd.Stdin = strings.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to render png: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if !bytes.HasPrefix(stdout.Bytes(), pngSignature) {
+		return nil, fmt.Errorf("failed to render png: renderer did not produce a png image")
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+package artifact
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Repository artifact仓库
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository 创建artifact仓库
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateArtifact 保存artifact
+func (r *Repository) CreateArtifact(artifact *Artifact) error {
+	query := `
+		INSERT INTO artifacts (id, conversation_id, message_id, identifier, version, type, title, language, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.Exec(query, artifact.ID, artifact.ConversationID, artifact.MessageID,
+		artifact.Identifier, artifact.Version, artifact.Type, artifact.Title,
+		artifact.Language, artifact.Content, artifact.CreatedAt)
+	return err
+}
+
+// GetLatestVersion 获取对话中某个标识符的最新版本
+func (r *Repository) GetLatestVersion(conversationID, identifier string) (*Artifact, error) {
+	query := `
+		SELECT id, conversation_id, message_id, identifier, version, type, title, language, content, created_at
+		FROM artifacts
+		WHERE conversation_id = $1 AND identifier = $2
+		ORDER BY version DESC
+		LIMIT 1`
+
+	artifact, err := scanArtifact(r.db.QueryRow(query, conversationID, identifier))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return artifact, err
+}
+
+// GetLatestUntitledIdentifier 获取对话中某类型最近保存的无标题artifact的标识符，没有时返回空
+func (r *Repository) GetLatestUntitledIdentifier(conversationID, artifactType string) (string, error) {
+	query := `
+		SELECT identifier
+		FROM artifacts
+		WHERE conversation_id = $1 AND type = $2 AND position(':' in identifier) = 0
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var identifier string
+	err := r.db.QueryRow(query, conversationID, artifactType).Scan(&identifier)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return identifier, err
+}
+
+// GetArtifactByID 获取artifact及其所属用户ID
+func (r *Repository) GetArtifactByID(id string) (*Artifact, string, error) {
+	query := `
+		SELECT a.id, a.conversation_id, a.message_id, a.identifier, a.version, a.type, a.title, a.language, a.content, a.created_at,
+			c.user_id
+		FROM artifacts a
+		JOIN conversations c ON c.id = a.conversation_id
+		WHERE a.id = $1`
+
+	var artifact Artifact
+	var userID string
+	err := r.db.QueryRow(query, id).Scan(&artifact.ID, &artifact.ConversationID, &artifact.MessageID,
+		&artifact.Identifier, &artifact.Version, &artifact.Type, &artifact.Title,
+		&artifact.Language, &artifact.Content, &artifact.CreatedAt, &userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("artifact not found")
+		}
+		return nil, "", err
+	}
+
+	return &artifact, userID, nil
+}
+
+// GetVersions 获取对话中某个标识符的所有版本
+func (r *Repository) GetVersions(conversationID, identifier string) ([]VersionInfo, error) {
+	query := `
+		SELECT id, version, message_id, created_at
+		FROM artifacts
+		WHERE conversation_id = $1 AND identifier = $2
+		ORDER BY version ASC`
+
+	rows, err := r.db.Query(query, conversationID, identifier)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []VersionInfo
+	for rows.Next() {
+		var version VersionInfo
+		if err := rows.Scan(&version.ID, &version.Version, &version.MessageID, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// GetArtifactsByConversationID 获取对话中的全部artifact（含所有版本）
+func (r *Repository) GetArtifactsByConversationID(conversationID string) ([]Artifact, error) {
+	query := `
+		SELECT id, conversation_id, message_id, identifier, version, type, title, language, content, created_at
+		FROM artifacts
+		WHERE conversation_id = $1
+		ORDER BY identifier ASC, version ASC`
+
+	rows, err := r.db.Query(query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := []Artifact{}
+	for rows.Next() {
+		var artifact Artifact
+		err := rows.Scan(&artifact.ID, &artifact.ConversationID, &artifact.MessageID,
+			&artifact.Identifier, &artifact.Version, &artifact.Type, &artifact.Title,
+			&artifact.Language, &artifact.Content, &artifact.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, rows.Err()
+}
+
+// scanArtifact 扫描单行artifact
+func scanArtifact(row *sql.Row) (*Artifact, error) {
+	var artifact Artifact
+	err := row.Scan(&artifact.ID, &artifact.ConversationID, &artifact.MessageID,
+		&artifact.Identifier, &artifact.Version, &artifact.Type, &artifact.Title,
+		&artifact.Language, &artifact.Content, &artifact.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
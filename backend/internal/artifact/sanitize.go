@@ -0,0 +1,250 @@
+package artifact
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// svgBlockedElements SVG中连同子节点一起移除的元素
+var svgBlockedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// SanitizeSVG 清理SVG：移除script、foreignObject等可执行元素、事件属性以及指向外部或javascript:的链接
+// 同时丢弃DOCTYPE与处理指令，避免实体扩展
+func SanitizeSVG(source string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = false
+
+	var out bytes.Buffer
+	skipDepth := 0
+	sawRoot := false
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid svg: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			local := strings.ToLower(t.Name.Local)
+			if !sawRoot {
+				if local != "svg" {
+					return "", fmt.Errorf("invalid svg: root element is <%s>", t.Name.Local)
+				}
+				sawRoot = true
+			}
+			if svgBlockedElements[local] || isHrefAnimation(t) {
+				skipDepth = 1
+				continue
+			}
+			writeStartElement(&out, t)
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 || !sawRoot {
+				continue
+			}
+			xml.EscapeText(&out, t)
+		}
+		// Comment、ProcInst、Directive 全部丢弃
+	}
+
+	if !sawRoot {
+		return "", fmt.Errorf("invalid svg: missing <svg> element")
+	}
+
+	return out.String(), nil
+}
+
+// writeStartElement 输出过滤后的起始标签
+func writeStartElement(out *bytes.Buffer, element xml.StartElement) {
+	out.WriteString("<" + qualifiedName(element.Name))
+	for _, attr := range element.Attr {
+		if !safeSVGAttr(attr) {
+			continue
+		}
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+// safeSVGAttr 判断属性是否安全
+func safeSVGAttr(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	value := strings.ToLower(strings.Join(strings.Fields(attr.Value), ""))
+
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	if local == "href" || local == "src" || local == "action" || local == "formaction" {
+		// 只允许文档内引用与内联图片
+		return strings.HasPrefix(value, "#") || strings.HasPrefix(value, "data:image/")
+	}
+	if strings.Contains(value, "javascript:") {
+		return false
+	}
+	return true
+}
+
+// isHrefAnimation 判断是否为通过SMIL动画改写链接的元素
+func isHrefAnimation(element xml.StartElement) bool {
+	switch strings.ToLower(element.Name.Local) {
+	case "animate", "set", "animatetransform", "animatemotion":
+	default:
+		return false
+	}
+	for _, attr := range element.Attr {
+		if strings.ToLower(attr.Name.Local) == "attributename" && strings.Contains(strings.ToLower(attr.Value), "href") {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedName 还原带前缀的名称（RawToken不解析命名空间，Space即前缀）
+func qualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// mermaidDiagramTypes 支持的Mermaid图表类型
+var mermaidDiagramTypes = map[string]bool{
+	"graph": true, "flowchart": true, "sequenceDiagram": true, "classDiagram": true,
+	"stateDiagram": true, "stateDiagram-v2": true, "erDiagram": true, "journey": true,
+	"gantt": true, "pie": true, "quadrantChart": true, "requirementDiagram": true,
+	"gitGraph": true, "mindmap": true, "timeline": true, "sankey-beta": true,
+	"xychart-beta": true, "block-beta": true, "C4Context": true, "C4Container": true,
+	"C4Component": true, "C4Dynamic": true, "C4Deployment": true,
+}
+
+// MermaidValidation Mermaid校验结果
+type MermaidValidation struct {
+	Valid       bool     `json:"valid"`
+	DiagramType string   `json:"diagram_type,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// ValidateMermaid 校验Mermaid源码：图表类型、括号配对，并拒绝可执行的click回调与修改安全级别的init指令
+func ValidateMermaid(source string) MermaidValidation {
+	var result MermaidValidation
+
+	lines := strings.Split(strings.TrimSpace(source), "\n")
+	inFrontMatter := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+
+		if trimmed == "---" && (i == 0 || inFrontMatter) {
+			inFrontMatter = !inFrontMatter
+			continue
+		}
+		if inFrontMatter || trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "%%{") && strings.Contains(lower, "securitylevel") {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: securityLevel cannot be overridden", i+1))
+			continue
+		}
+		if strings.HasPrefix(trimmed, "%%") {
+			continue
+		}
+
+		if result.DiagramType == "" {
+			diagramType := strings.Fields(trimmed)[0]
+			if !mermaidDiagramTypes[diagramType] {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: unknown diagram type %q", i+1, diagramType))
+			}
+			result.DiagramType = diagramType
+			continue
+		}
+
+		if strings.HasPrefix(lower, "click ") && (strings.Contains(lower, "javascript:") || strings.Contains(lower, " call ")) {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: click callbacks are not allowed", i+1))
+		}
+		if result.DiagramType == "graph" || result.DiagramType == "flowchart" {
+			if problem := checkBrackets(trimmed); problem != "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: %s", i+1, problem))
+			}
+		}
+	}
+
+	if result.DiagramType == "" {
+		result.Errors = append(result.Errors, "missing diagram type")
+	}
+	if inFrontMatter {
+		result.Errors = append(result.Errors, "unterminated front matter")
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// checkBrackets 检查流程图一行中的节点括号是否配对（引号内的内容不计）
+// 紧跟在节点ID后的'>'是非对称节点A>text]的起始符
+func checkBrackets(line string) string {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+	inQuote := false
+	var prev rune
+
+	for _, r := range line {
+		current := r
+		if r == '>' && !inQuote && (unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_') {
+			current = '['
+		}
+		prev = r
+
+		switch current {
+		case '"':
+			inQuote = !inQuote
+		case '(', '[', '{':
+			if !inQuote {
+				stack = append(stack, current)
+			}
+		case ')', ']', '}':
+			if inQuote {
+				continue
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return fmt.Sprintf("unbalanced %q", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if inQuote {
+		return "unterminated string"
+	}
+	if len(stack) > 0 {
+		return fmt.Sprintf("unclosed %q", stack[len(stack)-1])
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package artifact
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// Service artifact服务
+type Service struct {
+	repo     *Repository
+	renderer *Renderer
+}
+
+// NewService 创建artifact服务
+func NewService(repo *Repository, renderer *Renderer) *Service {
+	return &Service{
+		repo:     repo,
+		renderer: renderer,
+	}
+}
+
+// ExtractAndSave 从助手回复中提取artifact并保存
+// 同一对话中标识符相同的artifact视为修订：内容变化时保存为新版本，内容未变时复用已有版本
+func (s *Service) ExtractAndSave(conversationID, messageID, content string) ([]Ref, error) {
+	extracted := Extract(content)
+	if len(extracted) == 0 {
+		return nil, nil
+	}
+	if err := s.resolveUntitled(conversationID, extracted); err != nil {
+		return nil, err
+	}
+
+	refs := make([]Ref, 0, len(extracted))
+	for _, item := range extracted {
+		latest, err := s.repo.GetLatestVersion(conversationID, item.Identifier)
+		if err != nil {
+			return refs, fmt.Errorf("failed to get latest artifact version: %w", err)
+		}
+
+		version := 1
+		if latest != nil {
+			if latest.Content == item.Content {
+				refs = append(refs, latest.Ref())
+				continue
+			}
+			version = latest.Version + 1
+		}
+
+		artifact := NewArtifact(conversationID, messageID, item, version)
+		if err := s.repo.CreateArtifact(artifact); err != nil {
+			return refs, fmt.Errorf("failed to save artifact: %w", err)
+		}
+		refs = append(refs, artifact.Ref())
+	}
+
+	return refs, nil
+}
+
+// resolveUntitled 回复中某类型只有一个无标题artifact时，视为对话中该类型最近一个无标题artifact的修订
+// 有多个时无法判断对应关系，保留按序号生成的标识符，即第n个修订上一次回复中的第n个
+func (s *Service) resolveUntitled(conversationID string, extracted []Extracted) error {
+	counts := make(map[string]int)
+	for _, item := range extracted {
+		if isUntitled(item.Identifier) {
+			counts[item.Type]++
+		}
+	}
+
+	for i := range extracted {
+		if !isUntitled(extracted[i].Identifier) || counts[extracted[i].Type] != 1 {
+			continue
+		}
+		identifier, err := s.repo.GetLatestUntitledIdentifier(conversationID, extracted[i].Type)
+		if err != nil {
+			return fmt.Errorf("failed to get latest untitled artifact: %w", err)
+		}
+		if identifier != "" {
+			extracted[i].Identifier = identifier
+		}
+	}
+	return nil
+}
+
+// GetArtifact 获取artifact及其全部版本并检查权限
+func (s *Service) GetArtifact(artifactID, userID string) (*Artifact, []VersionInfo, error) {
+	artifact, ownerID, err := s.repo.GetArtifactByID(artifactID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get artifact: %w", err)
+	}
+
+	// 检查权限
+	if ownerID != userID {
+		return nil, nil, fmt.Errorf("unauthorized access to artifact")
+	}
+
+	versions, err := s.repo.GetVersions(artifact.ConversationID, artifact.Identifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get artifact versions: %w", err)
+	}
+
+	return artifact, versions, nil
+}
+
+// GetConversationArtifacts 获取对话中的全部artifact，调用方负责检查对话权限
+func (s *Service) GetConversationArtifacts(conversationID string) ([]Artifact, error) {
+	artifacts, err := s.repo.GetArtifactsByConversationID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+// Render 渲染artifact：SVG做清理、Mermaid做校验，已保存的artifact附带限时沙箱预览链接，可选输出PNG
+func (s *Service) Render(ctx context.Context, userID string, req RenderRequest) (*RenderResult, error) {
+	result := &RenderResult{Type: req.Type, Content: req.Content}
+
+	if req.ArtifactID != "" {
+		artifact, _, err := s.GetArtifact(req.ArtifactID, userID)
+		if err != nil {
+			return nil, err
+		}
+		result.Type = artifact.Type
+		result.Content = artifact.Content
+
+		token, expiresAt := s.renderer.SignPreview(artifact.ID)
+		result.PreviewURL = fmt.Sprintf("/sandbox/artifacts/%s?token=%s", artifact.ID, token)
+		result.PreviewExpiresAt = &expiresAt
+	}
+
+	switch result.Type {
+	case TypeSVG:
+		sanitized, err := SanitizeSVG(result.Content)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = sanitized
+	case TypeMermaid:
+		validation := ValidateMermaid(result.Content)
+		result.Mermaid = &validation
+		if !validation.Valid {
+			return result, nil
+		}
+	case TypeHTML, TypeCode:
+		// HTML不做改写，依赖预览页面的沙箱与CSP隔离
+	default:
+		return nil, fmt.Errorf("invalid artifact type: %q", result.Type)
+	}
+
+	if req.Format == "png" {
+		if result.Type != TypeSVG && result.Type != TypeMermaid {
+			return nil, fmt.Errorf("invalid format: png rendering supports svg and mermaid only")
+		}
+		png, err := s.renderer.RenderPNG(ctx, result.Type, result.Content)
+		if err != nil {
+			return nil, err
+		}
+		result.PNG = base64.StdEncoding.EncodeToString(png)
+	}
+
+	return result, nil
+}
+
+// GetPreview 校验预览令牌并返回用于沙箱预览的artifact
+func (s *Service) GetPreview(artifactID, token string) (*Artifact, error) {
+	if err := s.renderer.VerifyPreview(artifactID, token); err != nil {
+		return nil, err
+	}
+
+	artifact, _, err := s.repo.GetArtifactByID(artifactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact: %w", err)
+	}
+
+	if artifact.Type == TypeSVG {
+		sanitized, err := SanitizeSVG(artifact.Content)
+		if err != nil {
+			return nil, err
+		}
+		artifact.Content = sanitized
+	}
+
+	return artifact, nil
+}
+
+// PreviewHeaders 返回预览响应的Content-Type与安全响应头
+func (s *Service) PreviewHeaders(artifactType string) (string, map[string]string) {
+	return s.renderer.PreviewHeaders(artifactType)
+}
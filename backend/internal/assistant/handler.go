@@ -0,0 +1,215 @@
+package assistant
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 助手处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建助手处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetCategories 获取助手分类
+func (h *Handler) GetCategories(c *gin.Context) {
+	categories, err := h.service.GetCategories()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"app_types": categories})
+}
+
+// GetAssistants 获取助手列表，支持 app_type_id 与 scope(all/public/mine) 过滤
+func (h *Handler) GetAssistants(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	filter := ListFilter{
+		AppTypeID: c.Query("app_type_id"),
+		Scope:     c.Query("scope"),
+	}
+
+	assistants, err := h.service.GetAssistants(userID.(string), filter)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assistants": assistants})
+}
+
+// GetAssistant 获取助手详情
+func (h *Handler) GetAssistant(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	assistant, err := h.service.GetAssistant(c.Param("id"), userID.(string))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, assistant)
+}
+
+// CreateAssistant 创建私有助手
+func (h *Handler) CreateAssistant(c *gin.Context) {
+	h.createAssistant(c, false)
+}
+
+// UpdateAssistant 更新自己的助手
+func (h *Handler) UpdateAssistant(c *gin.Context) {
+	h.updateAssistant(c, false)
+}
+
+// DeleteAssistant 删除自己的助手
+func (h *Handler) DeleteAssistant(c *gin.Context) {
+	h.deleteAssistant(c, false)
+}
+
+// StartConversation 从助手创建对话
+func (h *Handler) StartConversation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req StartConversationRequest
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	conv, opening, err := h.service.StartConversation(c.Param("id"), userID.(string), req)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"conversation":    conv,
+		"opening_message": opening,
+	})
+}
+
+// AdminGetAssistants 管理员获取全部助手
+func (h *Handler) AdminGetAssistants(c *gin.Context) {
+	assistants, err := h.service.GetAllAssistants(ListFilter{AppTypeID: c.Query("app_type_id")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assistants": assistants})
+}
+
+// AdminCreateAssistant 管理员创建助手，默认发布到公开广场
+func (h *Handler) AdminCreateAssistant(c *gin.Context) {
+	h.createAssistant(c, true)
+}
+
+// AdminUpdateAssistant 管理员更新任意助手（含公开状态、排序与启用）
+func (h *Handler) AdminUpdateAssistant(c *gin.Context) {
+	h.updateAssistant(c, true)
+}
+
+// AdminDeleteAssistant 管理员删除任意助手
+func (h *Handler) AdminDeleteAssistant(c *gin.Context) {
+	h.deleteAssistant(c, true)
+}
+
+func (h *Handler) createAssistant(c *gin.Context, admin bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req CreateAssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if admin && req.IsPublic == nil {
+		isPublic := true
+		req.IsPublic = &isPublic
+	}
+
+	assistant, err := h.service.CreateAssistant(userID.(string), req, admin)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, assistant)
+}
+
+func (h *Handler) updateAssistant(c *gin.Context, admin bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req UpdateAssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assistant, err := h.service.UpdateAssistant(c.Param("id"), userID.(string), req, admin)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, assistant)
+}
+
+func (h *Handler) deleteAssistant(c *gin.Context, admin bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.DeleteAssistant(c.Param("id"), userID.(string), admin); err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "assistant deleted successfully"})
+}
+
+// writeAccessError 根据错误类型返回对应状态码
+func writeAccessError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(err.Error(), "invalid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,123 @@
+package assistant
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 助手可启用的工具
+const (
+	ToolWebSearch = "web_search"
+)
+
+// 助手列表范围
+const (
+	ScopeAll    = "all"
+	ScopePublic = "public"
+	ScopeMine   = "mine"
+)
+
+// supportedTools 支持的工具集合
+var supportedTools = map[string]bool{
+	ToolWebSearch: true,
+}
+
+// Assistant 助手（预设角色），按app_types分类
+// 公开助手由管理员维护，出现在所有用户的广场中；其余助手仅创建者可见
+type Assistant struct {
+	ID               string    `json:"id" db:"id"`
+	UserID           string    `json:"user_id" db:"user_id"`
+	AppTypeID        *string   `json:"app_type_id" db:"app_type_id"`
+	Name             string    `json:"name" db:"name"`
+	Description      string    `json:"description" db:"description"`
+	Icon             *string   `json:"icon" db:"icon"`
+	SystemPrompt     string    `json:"system_prompt" db:"system_prompt"`
+	Model            string    `json:"model" db:"model"`
+	Tools            []string  `json:"tools" db:"tools"`
+	KnowledgeBaseIDs []string  `json:"knowledge_base_ids" db:"knowledge_base_ids"`
+	OpeningMessage   string    `json:"opening_message" db:"opening_message"`
+	IsPublic         bool      `json:"is_public" db:"is_public"`
+	SortNum          int       `json:"sort_num" db:"sort_num"`
+	Enabled          bool      `json:"enabled" db:"enabled"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAssistantRequest 创建助手请求，is_public/sort_num/enabled 仅管理员可设置
+type CreateAssistantRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Description      string   `json:"description"`
+	Icon             *string  `json:"icon"`
+	AppTypeID        *string  `json:"app_type_id"`
+	SystemPrompt     string   `json:"system_prompt"`
+	Model            string   `json:"model"`
+	Tools            []string `json:"tools"`
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`
+	OpeningMessage   string   `json:"opening_message"`
+	IsPublic         *bool    `json:"is_public"`
+	SortNum          *int     `json:"sort_num"`
+	Enabled          *bool    `json:"enabled"`
+}
+
+// UpdateAssistantRequest 更新助手请求，未提供的字段保持不变
+type UpdateAssistantRequest struct {
+	Name             *string   `json:"name"`
+	Description      *string   `json:"description"`
+	Icon             *string   `json:"icon"`
+	AppTypeID        *string   `json:"app_type_id"`
+	SystemPrompt     *string   `json:"system_prompt"`
+	Model            *string   `json:"model"`
+	Tools            *[]string `json:"tools"`
+	KnowledgeBaseIDs *[]string `json:"knowledge_base_ids"`
+	OpeningMessage   *string   `json:"opening_message"`
+	IsPublic         *bool     `json:"is_public"`
+	SortNum          *int      `json:"sort_num"`
+	Enabled          *bool     `json:"enabled"`
+}
+
+// ListFilter 助手列表过滤条件
+type ListFilter struct {
+	AppTypeID string
+	Scope     string
+}
+
+// StartConversationRequest 从助手创建对话的请求
+type StartConversationRequest struct {
+	Title string `json:"title"`
+	Model string `json:"model"`
+}
+
+// NewAssistant 创建新助手实例
+func NewAssistant(userID string, req CreateAssistantRequest) *Assistant {
+	now := time.Now()
+	assistant := &Assistant{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		AppTypeID:        req.AppTypeID,
+		Name:             req.Name,
+		Description:      req.Description,
+		Icon:             req.Icon,
+		SystemPrompt:     req.SystemPrompt,
+		Model:            req.Model,
+		Tools:            req.Tools,
+		KnowledgeBaseIDs: req.KnowledgeBaseIDs,
+		OpeningMessage:   req.OpeningMessage,
+		Enabled:          true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if req.SortNum != nil {
+		assistant.SortNum = *req.SortNum
+	}
+	if req.Enabled != nil {
+		assistant.Enabled = *req.Enabled
+	}
+	if assistant.Tools == nil {
+		assistant.Tools = []string{}
+	}
+	if assistant.KnowledgeBaseIDs == nil {
+		assistant.KnowledgeBaseIDs = []string{}
+	}
+	return assistant
+}
@@ -0,0 +1,172 @@
+package assistant
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Repository 助手仓库
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository 创建助手仓库
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const assistantColumns = `id, user_id, app_type_id, name, description, icon, system_prompt, model,
+	tools, knowledge_base_ids, opening_message, is_public, sort_num, enabled, created_at, updated_at`
+
+// CreateAssistant 保存助手
+func (r *Repository) CreateAssistant(assistant *Assistant) error {
+	toolsJSON, knowledgeJSON, err := marshalLists(assistant)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO assistants (` + assistantColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+
+	_, err = r.db.Exec(query, assistant.ID, assistant.UserID, assistant.AppTypeID, assistant.Name,
+		assistant.Description, assistant.Icon, assistant.SystemPrompt, assistant.Model,
+		toolsJSON, knowledgeJSON, assistant.OpeningMessage, assistant.IsPublic,
+		assistant.SortNum, assistant.Enabled, assistant.CreatedAt, assistant.UpdatedAt)
+	return err
+}
+
+// GetAssistantByID 获取助手
+func (r *Repository) GetAssistantByID(id string) (*Assistant, error) {
+	query := `SELECT ` + assistantColumns + ` FROM assistants WHERE id = $1`
+
+	assistant, err := scanAssistant(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("assistant not found")
+	}
+	return assistant, err
+}
+
+// ListAssistants 获取用户可见的助手：已启用的公开助手与用户自己的助手
+// userID为空时返回全部助手（管理员视图）
+func (r *Repository) ListAssistants(userID string, filter ListFilter) ([]Assistant, error) {
+	var conditions []string
+	var args []interface{}
+
+	if userID != "" {
+		switch filter.Scope {
+		case ScopePublic:
+			conditions = append(conditions, "is_public = true AND enabled = true")
+		case ScopeMine:
+			args = append(args, userID)
+			conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+		default:
+			args = append(args, userID)
+			conditions = append(conditions, fmt.Sprintf("((is_public = true AND enabled = true) OR user_id = $%d)", len(args)))
+		}
+	}
+	if filter.AppTypeID != "" {
+		args = append(args, filter.AppTypeID)
+		conditions = append(conditions, fmt.Sprintf("app_type_id = $%d", len(args)))
+	}
+
+	query := `SELECT ` + assistantColumns + ` FROM assistants`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY is_public DESC, sort_num ASC, created_at DESC`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assistants := []Assistant{}
+	for rows.Next() {
+		assistant, err := scanAssistant(rows)
+		if err != nil {
+			return nil, err
+		}
+		assistants = append(assistants, *assistant)
+	}
+	return assistants, rows.Err()
+}
+
+// UpdateAssistant 更新助手
+func (r *Repository) UpdateAssistant(assistant *Assistant) error {
+	toolsJSON, knowledgeJSON, err := marshalLists(assistant)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE assistants
+		SET app_type_id = $2, name = $3, description = $4, icon = $5, system_prompt = $6, model = $7,
+			tools = $8, knowledge_base_ids = $9, opening_message = $10, is_public = $11, sort_num = $12,
+			enabled = $13, updated_at = $14
+		WHERE id = $1`
+
+	_, err = r.db.Exec(query, assistant.ID, assistant.AppTypeID, assistant.Name, assistant.Description,
+		assistant.Icon, assistant.SystemPrompt, assistant.Model, toolsJSON, knowledgeJSON,
+		assistant.OpeningMessage, assistant.IsPublic, assistant.SortNum, assistant.Enabled,
+		assistant.UpdatedAt)
+	return err
+}
+
+// DeleteAssistant 删除助手，已创建的对话保留（assistant_id置空）
+func (r *Repository) DeleteAssistant(id string) error {
+	query := `DELETE FROM assistants WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// marshalLists 序列化工具与知识库列表
+func marshalLists(assistant *Assistant) ([]byte, []byte, error) {
+	toolsJSON, err := json.Marshal(assistant.Tools)
+	if err != nil {
+		return nil, nil, err
+	}
+	knowledgeJSON, err := json.Marshal(assistant.KnowledgeBaseIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return toolsJSON, knowledgeJSON, nil
+}
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAssistant 扫描一行助手记录
+func scanAssistant(row rowScanner) (*Assistant, error) {
+	var assistant Assistant
+	var appTypeID, icon sql.NullString
+	var toolsJSON, knowledgeJSON []byte
+
+	err := row.Scan(&assistant.ID, &assistant.UserID, &appTypeID, &assistant.Name,
+		&assistant.Description, &icon, &assistant.SystemPrompt, &assistant.Model,
+		&toolsJSON, &knowledgeJSON, &assistant.OpeningMessage, &assistant.IsPublic,
+		&assistant.SortNum, &assistant.Enabled, &assistant.CreatedAt, &assistant.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if appTypeID.Valid {
+		assistant.AppTypeID = &appTypeID.String
+	}
+	if icon.Valid {
+		assistant.Icon = &icon.String
+	}
+	if err := json.Unmarshal(toolsJSON, &assistant.Tools); err != nil || assistant.Tools == nil {
+		assistant.Tools = []string{}
+	}
+	if err := json.Unmarshal(knowledgeJSON, &assistant.KnowledgeBaseIDs); err != nil || assistant.KnowledgeBaseIDs == nil {
+		assistant.KnowledgeBaseIDs = []string{}
+	}
+
+	return &assistant, nil
+}
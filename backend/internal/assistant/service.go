@@ -0,0 +1,283 @@
+package assistant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qicro/qicro/backend/internal/chat"
+	configManagement "github.com/qicro/qicro/backend/internal/config"
+	"github.com/qicro/qicro/backend/internal/knowledge"
+)
+
+const (
+	maxNameRunes        = 100
+	maxDescriptionRunes = 1000
+	maxOpeningRunes     = 2000
+)
+
+// Service 助手服务
+type Service struct {
+	repo             *Repository
+	configService    *configManagement.Service
+	knowledgeService *knowledge.Service
+	chatService      *chat.Service
+}
+
+// NewService 创建助手服务
+func NewService(repo *Repository, configService *configManagement.Service, knowledgeService *knowledge.Service, chatService *chat.Service) *Service {
+	return &Service{
+		repo:             repo,
+		configService:    configService,
+		knowledgeService: knowledgeService,
+		chatService:      chatService,
+	}
+}
+
+// CreateAssistant 创建助手；只有管理员可以创建公开助手及设置排序与启用状态
+func (s *Service) CreateAssistant(userID string, req CreateAssistantRequest, admin bool) (*Assistant, error) {
+	if !admin && (req.IsPublic != nil || req.SortNum != nil || req.Enabled != nil) {
+		return nil, fmt.Errorf("unauthorized: only admins can publish assistants")
+	}
+
+	assistant := NewAssistant(userID, req)
+	if req.IsPublic != nil {
+		assistant.IsPublic = *req.IsPublic
+	}
+	if err := s.validate(assistant); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateAssistant(assistant); err != nil {
+		return nil, fmt.Errorf("failed to create assistant: %w", err)
+	}
+	return assistant, nil
+}
+
+// GetAssistants 获取助手列表（公开广场与用户自己的助手）
+func (s *Service) GetAssistants(userID string, filter ListFilter) ([]Assistant, error) {
+	switch filter.Scope {
+	case "", ScopeAll, ScopePublic, ScopeMine:
+	default:
+		return nil, fmt.Errorf("invalid scope: %s", filter.Scope)
+	}
+
+	assistants, err := s.repo.ListAssistants(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assistants: %w", err)
+	}
+	return assistants, nil
+}
+
+// GetAllAssistants 获取全部助手（管理员视图）
+func (s *Service) GetAllAssistants(filter ListFilter) ([]Assistant, error) {
+	assistants, err := s.repo.ListAssistants("", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assistants: %w", err)
+	}
+	return assistants, nil
+}
+
+// GetAssistant 获取助手详情：已启用的公开助手对所有人可见，其余仅创建者可见
+func (s *Service) GetAssistant(assistantID, userID string) (*Assistant, error) {
+	assistant, err := s.repo.GetAssistantByID(assistantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if assistant.UserID != userID && !(assistant.IsPublic && assistant.Enabled) {
+		return nil, fmt.Errorf("unauthorized access to assistant")
+	}
+	return assistant, nil
+}
+
+// UpdateAssistant 更新助手；创建者可修改自己的助手，管理员可修改任意助手并控制公开状态
+func (s *Service) UpdateAssistant(assistantID, userID string, req UpdateAssistantRequest, admin bool) (*Assistant, error) {
+	assistant, err := s.repo.GetAssistantByID(assistantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !admin {
+		if assistant.UserID != userID {
+			return nil, fmt.Errorf("unauthorized access to assistant")
+		}
+		if req.IsPublic != nil || req.SortNum != nil || req.Enabled != nil {
+			return nil, fmt.Errorf("unauthorized: only admins can publish assistants")
+		}
+	}
+
+	if req.Name != nil {
+		assistant.Name = *req.Name
+	}
+	if req.Description != nil {
+		assistant.Description = *req.Description
+	}
+	if req.Icon != nil {
+		assistant.Icon = req.Icon
+	}
+	if req.AppTypeID != nil {
+		assistant.AppTypeID = req.AppTypeID
+		if *req.AppTypeID == "" {
+			assistant.AppTypeID = nil
+		}
+	}
+	if req.SystemPrompt != nil {
+		assistant.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Model != nil {
+		assistant.Model = *req.Model
+	}
+	if req.Tools != nil {
+		assistant.Tools = *req.Tools
+	}
+	if req.KnowledgeBaseIDs != nil {
+		assistant.KnowledgeBaseIDs = *req.KnowledgeBaseIDs
+	}
+	if req.OpeningMessage != nil {
+		assistant.OpeningMessage = *req.OpeningMessage
+	}
+	if req.IsPublic != nil {
+		assistant.IsPublic = *req.IsPublic
+	}
+	if req.SortNum != nil {
+		assistant.SortNum = *req.SortNum
+	}
+	if req.Enabled != nil {
+		assistant.Enabled = *req.Enabled
+	}
+
+	if err := s.validate(assistant); err != nil {
+		return nil, err
+	}
+
+	assistant.UpdatedAt = time.Now()
+	if err := s.repo.UpdateAssistant(assistant); err != nil {
+		return nil, fmt.Errorf("failed to update assistant: %w", err)
+	}
+	return assistant, nil
+}
+
+// DeleteAssistant 删除助手
+func (s *Service) DeleteAssistant(assistantID, userID string, admin bool) error {
+	assistant, err := s.repo.GetAssistantByID(assistantID)
+	if err != nil {
+		return err
+	}
+	if !admin && assistant.UserID != userID {
+		return fmt.Errorf("unauthorized access to assistant")
+	}
+
+	if err := s.repo.DeleteAssistant(assistantID); err != nil {
+		return fmt.Errorf("failed to delete assistant: %w", err)
+	}
+	return nil
+}
+
+// GetCategories 获取助手广场的分类（已启用的app_types）
+func (s *Service) GetCategories() ([]configManagement.AppType, error) {
+	appTypes, err := s.configService.GetAppTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []configManagement.AppType{}
+	for _, appType := range appTypes {
+		if appType.Enabled {
+			categories = append(categories, appType)
+		}
+	}
+	return categories, nil
+}
+
+// StartConversation 以助手的预设创建对话，返回对话及开场白消息（如有）
+func (s *Service) StartConversation(assistantID, userID string, req StartConversationRequest) (*chat.Conversation, *chat.Message, error) {
+	assistant, err := s.GetAssistant(assistantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !assistant.Enabled {
+		return nil, nil, fmt.Errorf("assistant not found")
+	}
+
+	model := assistant.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	return s.chatService.StartConversation(userID, chat.ConversationPreset{
+		AssistantID:    assistant.ID,
+		Title:          req.Title,
+		Model:          model,
+		Settings:       presetSettings(assistant),
+		OpeningMessage: assistant.OpeningMessage,
+	})
+}
+
+// presetSettings 将助手配置转换为对话设置
+func presetSettings(assistant *Assistant) chat.ConversationSettings {
+	settings := chat.ConversationSettings{
+		SystemPrompt:     assistant.SystemPrompt,
+		KnowledgeBaseIDs: assistant.KnowledgeBaseIDs,
+	}
+	for _, tool := range assistant.Tools {
+		if tool == ToolWebSearch {
+			settings.WebSearch = true
+		}
+	}
+	return settings
+}
+
+// validate 校验助手字段、分类、工具与知识库归属
+func (s *Service) validate(assistant *Assistant) error {
+	assistant.Name = strings.TrimSpace(assistant.Name)
+	if assistant.Name == "" || utf8.RuneCountInString(assistant.Name) > maxNameRunes {
+		return fmt.Errorf("invalid name: must be 1-%d characters", maxNameRunes)
+	}
+	if utf8.RuneCountInString(assistant.Description) > maxDescriptionRunes {
+		return fmt.Errorf("invalid description: must be at most %d characters", maxDescriptionRunes)
+	}
+	if utf8.RuneCountInString(assistant.OpeningMessage) > maxOpeningRunes {
+		return fmt.Errorf("invalid opening_message: must be at most %d characters", maxOpeningRunes)
+	}
+
+	for _, tool := range assistant.Tools {
+		if !supportedTools[tool] {
+			return fmt.Errorf("invalid tool: %s", tool)
+		}
+	}
+
+	settings := presetSettings(assistant)
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	if assistant.AppTypeID != nil {
+		if err := s.checkAppType(*assistant.AppTypeID); err != nil {
+			return err
+		}
+	}
+
+	// 知识库必须属于助手的创建者
+	for _, id := range assistant.KnowledgeBaseIDs {
+		if _, err := s.knowledgeService.GetKnowledgeBase(id, assistant.UserID); err != nil {
+			return fmt.Errorf("invalid knowledge base: %s", id)
+		}
+	}
+	return nil
+}
+
+// checkAppType 检查分类存在且已启用
+func (s *Service) checkAppType(appTypeID string) error {
+	categories, err := s.GetCategories()
+	if err != nil {
+		return fmt.Errorf("failed to get app types: %w", err)
+	}
+	for _, category := range categories {
+		if category.ID == appTypeID {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid app_type_id: %s", appTypeID)
+}
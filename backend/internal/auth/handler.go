@@ -128,7 +128,7 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		user, err := h.service.ValidateToken(tokenString)
+		user, claims, err := h.service.ValidateTokenClaims(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
@@ -137,6 +137,11 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 
 		c.Set("user", user)
 		c.Set("user_id", user.ID)
+		// 以数据库中的角色为准，降级立即生效，不必等待旧令牌过期
+		c.Set("user_role", user.Role)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 		c.Next()
 	}
 }
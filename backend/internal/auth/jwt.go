@@ -16,6 +16,7 @@ type JWTService struct {
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -26,10 +27,11 @@ func NewJWTService(secret, issuer string) *JWTService {
 	}
 }
 
-func (j *JWTService) GenerateToken(userID, email string) (string, error) {
+func (j *JWTService) GenerateToken(userID, email, role string) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -74,5 +76,5 @@ func (j *JWTService) RefreshToken(tokenString string) (string, error) {
 		return tokenString, nil
 	}
 
-	return j.GenerateToken(claims.UserID, claims.Email)
+	return j.GenerateToken(claims.UserID, claims.Email, claims.Role)
 }
\ No newline at end of file
@@ -13,6 +13,7 @@ type User struct {
 	PasswordHash   *string   `json:"-" db:"password_hash"`
 	OAuthProvider  *string   `json:"oauth_provider,omitempty" db:"oauth_provider"`
 	OAuthID        *string   `json:"oauth_id,omitempty" db:"oauth_id"`
+	Role           string    `json:"role" db:"role"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -63,6 +64,7 @@ func NewUser(email string) *User {
 	return &User{
 		ID:        uuid.New().String(),
 		Email:     email,
+		Role:      RoleUser,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
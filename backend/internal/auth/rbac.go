@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 用户角色，对应 users.role
+const (
+	RoleUser     = "user"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// 权限，管理接口按权限而非角色校验
+const (
+	// PermModelsManage 管理聊天模型与应用类型
+	PermModelsManage = "models:manage"
+	// PermKeysManage 查看与替换服务商API Key
+	PermKeysManage = "keys:manage"
+	// PermUsageRead 查看评价报表与在线统计
+	PermUsageRead = "usage:read"
+	// PermAssistantsManage 管理公开助手
+	PermAssistantsManage = "assistants:manage"
+	// PermDataExport 导出包含用户对话内容的评价数据集
+	PermDataExport = "data:export"
+	// PermUsersManage 查看用户并修改其角色
+	PermUsersManage = "users:manage"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// roles 按权限从低到高排列
+var roles = []string{RoleUser, RoleOperator, RoleAdmin}
+
+// rolePermissions 各角色拥有的权限
+var rolePermissions = map[string][]string{
+	RoleUser:     {},
+	RoleOperator: {PermModelsManage, PermAssistantsManage, PermUsageRead},
+	RoleAdmin: {
+		PermModelsManage, PermKeysManage, PermUsageRead,
+		PermAssistantsManage, PermDataExport, PermUsersManage,
+	},
+}
+
+// RoleInfo 角色及其权限
+type RoleInfo struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest 修改用户角色的请求
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ValidRole 角色是否存在
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 角色是否拥有指定权限，未知角色没有任何权限
+func HasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles 获取全部角色及其权限
+func Roles() []RoleInfo {
+	infos := make([]RoleInfo, 0, len(roles))
+	for _, role := range roles {
+		infos = append(infos, RoleInfo{Role: role, Permissions: rolePermissions[role]})
+	}
+	return infos
+}
+
+// RequirePermission 要求当前用户的角色拥有指定权限，需在AuthMiddleware之后使用
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c.GetString("user_role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied: " + permission + " required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetAdminEmails 设置始终为管理员的邮箱，用于初始化第一个管理员；这些用户注册或登录时自动提升为admin，且不能被降级
+func (s *Service) SetAdminEmails(emails []string) {
+	s.adminEmails = make(map[string]bool, len(emails))
+	for _, email := range emails {
+		s.adminEmails[strings.ToLower(email)] = true
+	}
+}
+
+// isAdminEmail 邮箱是否配置为管理员
+func (s *Service) isAdminEmail(email string) bool {
+	return s.adminEmails[strings.ToLower(email)]
+}
+
+// ensureAdminRole 将配置为管理员的用户提升为admin
+func (s *Service) ensureAdminRole(user *User) error {
+	if !s.isAdminEmail(user.Email) || user.Role == RoleAdmin {
+		return nil
+	}
+	if err := s.repo.UpdateUserRole(user.ID, RoleAdmin); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	user.Role = RoleAdmin
+	return nil
+}
+
+// GetUsers 分页获取用户列表
+func (s *Service) GetUsers(role string, limit, offset int) ([]User, error) {
+	if role != "" && !ValidRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	if limit > maxUserPageSize {
+		limit = maxUserPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, err := s.repo.GetUsers(role, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+// SetUserRole 修改用户角色；不能修改自己的角色，因此至少保留执行操作的管理员
+func (s *Service) SetUserRole(actorID, userID, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("invalid user id: %s", userID)
+	}
+	if userID == actorID {
+		return nil, fmt.Errorf("invalid request: cannot change your own role")
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.isAdminEmail(user.Email) && role != RoleAdmin {
+		return nil, fmt.Errorf("invalid request: %s is configured in ADMIN_EMAILS", user.Email)
+	}
+
+	if err := s.repo.UpdateUserRole(userID, role); err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	user.Role = role
+	return user, nil
+}
+
+// GetUsers 获取用户列表，支持 role、limit、offset 参数
+func (h *Handler) GetUsers(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	users, err := h.service.GetUsers(c.Query("role"), limit, offset)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// UpdateUserRole 提升或降级用户
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.SetUserRole(userID.(string), c.Param("id"), req.Role)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "invalid"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// GetRoles 获取全部角色及其权限
+func (h *Handler) GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"roles": Roles()})
+}
+
+// queryInt 解析可选的整数查询参数，缺省为0
+func queryInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -15,23 +15,23 @@ func NewRepository(db *sql.DB) *Repository {
 
 func (r *Repository) CreateUser(user *User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, oauth_provider, oauth_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		INSERT INTO users (id, email, password_hash, oauth_provider, oauth_id, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
 	_, err := r.db.Exec(query, user.ID, user.Email, user.PasswordHash, 
-		user.OAuthProvider, user.OAuthID, user.CreatedAt, user.UpdatedAt)
+		user.OAuthProvider, user.OAuthID, user.Role, user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
 func (r *Repository) GetUserByEmail(email string) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT id, email, password_hash, oauth_provider, oauth_id, created_at, updated_at
+		SELECT id, email, password_hash, oauth_provider, oauth_id, COALESCE(role, 'user'), created_at, updated_at
 		FROM users WHERE email = $1`
 
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash,
-		&user.OAuthProvider, &user.OAuthID, &user.CreatedAt, &user.UpdatedAt)
+		&user.OAuthProvider, &user.OAuthID, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -46,12 +46,12 @@ func (r *Repository) GetUserByEmail(email string) (*User, error) {
 func (r *Repository) GetUserByID(id string) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT id, email, password_hash, oauth_provider, oauth_id, created_at, updated_at
+		SELECT id, email, password_hash, oauth_provider, oauth_id, COALESCE(role, 'user'), created_at, updated_at
 		FROM users WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Email, &user.PasswordHash,
-		&user.OAuthProvider, &user.OAuthID, &user.CreatedAt, &user.UpdatedAt)
+		&user.OAuthProvider, &user.OAuthID, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -66,12 +66,12 @@ func (r *Repository) GetUserByID(id string) (*User, error) {
 func (r *Repository) GetUserByOAuth(provider, oauthID string) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT id, email, password_hash, oauth_provider, oauth_id, created_at, updated_at
+		SELECT id, email, password_hash, oauth_provider, oauth_id, COALESCE(role, 'user'), created_at, updated_at
 		FROM users WHERE oauth_provider = $1 AND oauth_id = $2`
 
 	err := r.db.QueryRow(query, provider, oauthID).Scan(
 		&user.ID, &user.Email, &user.PasswordHash,
-		&user.OAuthProvider, &user.OAuthID, &user.CreatedAt, &user.UpdatedAt)
+		&user.OAuthProvider, &user.OAuthID, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -97,4 +97,48 @@ func (r *Repository) DeleteUser(id string) error {
 	query := `DELETE FROM users WHERE id = $1`
 	_, err := r.db.Exec(query, id)
 	return err
-}
\ No newline at end of file
+}
+
+// GetUsers 分页获取用户列表，role为空时不过滤
+func (r *Repository) GetUsers(role string, limit, offset int) ([]User, error) {
+	query := `
+		SELECT id, email, password_hash, oauth_provider, oauth_id, COALESCE(role, 'user'), created_at, updated_at
+		FROM users
+		WHERE $1 = '' OR COALESCE(role, 'user') = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, role, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Email, &user.PasswordHash,
+			&user.OAuthProvider, &user.OAuthID, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUserRole 修改用户角色
+func (r *Repository) UpdateUserRole(id, role string) error {
+	query := `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1`
+	result, err := r.db.Exec(query, id, role)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
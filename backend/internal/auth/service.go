@@ -10,6 +10,7 @@ type Service struct {
 	repo         *Repository
 	jwtService   *JWTService
 	oauthService *OAuthService
+	adminEmails  map[string]bool
 }
 
 func NewService(repo *Repository, jwtService *JWTService, oauthService *OAuthService) *Service {
@@ -28,6 +29,9 @@ func (s *Service) Register(req RegisterRequest) (*LoginResponse, error) {
 
 	// 创建新用户
 	user := NewUser(req.Email)
+	if s.isAdminEmail(user.Email) {
+		user.Role = RoleAdmin
+	}
 	
 	// 加密密码
 	hashedPassword, err := HashPassword(req.Password)
@@ -42,7 +46,7 @@ func (s *Service) Register(req RegisterRequest) (*LoginResponse, error) {
 	}
 
 	// 生成JWT token
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	token, err := s.jwtService.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -69,8 +73,12 @@ func (s *Service) Login(req LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("invalid credentials")
 	}
 
+	if err := s.ensureAdminRole(user); err != nil {
+		return nil, err
+	}
+
 	// 生成JWT token
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	token, err := s.jwtService.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -109,14 +117,21 @@ func (s *Service) OAuthLogin(provider, code string) (*LoginResponse, error) {
 		} else {
 			// 创建新用户
 			user = NewOAuthUser(userInfo.Email, provider, userInfo.ID)
+			if s.isAdminEmail(user.Email) {
+				user.Role = RoleAdmin
+			}
 			if err := s.repo.CreateUser(user); err != nil {
 				return nil, fmt.Errorf("failed to create user: %w", err)
 			}
 		}
 	}
 
+	if err := s.ensureAdminRole(user); err != nil {
+		return nil, err
+	}
+
 	// 生成JWT token
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	token, err := s.jwtService.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -128,19 +143,47 @@ func (s *Service) OAuthLogin(provider, code string) (*LoginResponse, error) {
 }
 
 func (s *Service) ValidateToken(tokenString string) (*User, error) {
+	user, _, err := s.ValidateTokenClaims(tokenString)
+	return user, err
+}
+
+// ValidateTokenClaims 校验令牌并返回用户及令牌声明
+func (s *Service) ValidateTokenClaims(tokenString string) (*User, *Claims, error) {
 	claims, err := s.jwtService.ValidateToken(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	user, err := s.repo.GetUserByID(claims.UserID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, nil, errors.New("user not found")
 	}
 
-	return user, nil
+	return user, claims, nil
+}
+
+// AuthenticateToken 校验令牌，返回用户ID与令牌过期时间，用于WebSocket握手与连接续期
+func (s *Service) AuthenticateToken(tokenString string) (string, time.Time, error) {
+	user, claims, err := s.ValidateTokenClaims(tokenString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return "", time.Time{}, errors.New("invalid token: missing expiry")
+	}
+
+	return user.ID, claims.ExpiresAt.Time, nil
 }
 
 func (s *Service) RefreshToken(tokenString string) (string, error) {
+	user, claims, err := s.ValidateTokenClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	// 角色变更后立即签发新令牌，使声明中的角色与数据库一致
+	if claims.Role != user.Role {
+		return s.jwtService.GenerateToken(user.ID, user.Email, user.Role)
+	}
 	return s.jwtService.RefreshToken(tokenString)
 }
\ No newline at end of file
@@ -0,0 +1,490 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qicro/qicro/backend/internal/llm"
+)
+
+const (
+	minCompareModels = 2
+	maxCompareModels = 4
+	// eloInitialRating 模型的初始Elo分
+	eloInitialRating = 1000.0
+	// eloK 每场对局的最大分数变化
+	eloK = 32.0
+)
+
+// 对比模式的生成事件类型，与SSE事件名一致
+const (
+	EventCompareMessage = "compare_message"
+	EventCompareResult  = "compare_result"
+)
+
+// Comparison 多模型对比：同一条用户消息并发发给多个模型，各模型的回答作为该消息下的同级分支保存
+type Comparison struct {
+	ID              string               `json:"id" db:"id"`
+	UserID          string               `json:"user_id" db:"user_id"`
+	ConversationID  string               `json:"conversation_id" db:"conversation_id"`
+	UserMessageID   string               `json:"user_message_id" db:"user_message_id"`
+	Models          []string             `json:"models" db:"models"`
+	Responses       []ComparisonResponse `json:"responses" db:"-"`
+	WinnerMessageID *string              `json:"winner_message_id" db:"winner_message_id"`
+	WinnerModel     *string              `json:"winner_model" db:"winner_model"`
+	CreatedAt       time.Time            `json:"created_at" db:"created_at"`
+	VotedAt         *time.Time           `json:"voted_at" db:"voted_at"`
+}
+
+// ComparisonResponse 对比中某个模型保存下来的回答
+type ComparisonResponse struct {
+	Model     string `json:"model"`
+	MessageID string `json:"message_id"`
+}
+
+// CompareChunk 对比模式的流式片段，按模型标记
+type CompareChunk struct {
+	Model string `json:"model"`
+	Index int    `json:"index"`
+	*llm.ChatResponse
+}
+
+// CompareResult 单个模型回答结束的事件，保存失败或未生成内容时message_id为空
+type CompareResult struct {
+	Model        string `json:"model"`
+	Index        int    `json:"index"`
+	MessageID    string `json:"message_id,omitempty"`
+	FinishReason string `json:"finish_reason"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ModelRating 排行榜中模型的Elo分与两两对局的胜负场次
+type ModelRating struct {
+	Model   string  `json:"model"`
+	Rating  float64 `json:"rating"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Matches int     `json:"matches"`
+}
+
+// comparisonVote 已投票的对比，用于计算排行榜
+type comparisonVote struct {
+	winner string
+	models []string
+}
+
+// CompareStream 将用户消息并发发给多个模型，各模型的流式回复合并到同一个生成任务的事件中
+// 全部结束后活动分支指向第一个成功的回答，用户投票选出胜者后切换到胜者分支
+func (s *Service) CompareStream(ctx context.Context, conversationID, userID, content string, models []string) (*Comparison, *Generation, error) {
+	models, err := s.resolveCompareModels(models)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conv, err := s.prepareConversation(conversationID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userMessage, err := s.createUserMessage(conv, conv.ActiveLeafID, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 检索只做一次，所有模型共用同一份历史与引用
+	llmMessages, references, err := s.buildHistory(ctx, conv, userMessage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comparison := &Comparison{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		ConversationID: conv.ID,
+		UserMessageID:  userMessage.ID,
+		Models:         models,
+		Responses:      []ComparisonResponse{},
+		CreatedAt:      time.Now(),
+	}
+	if err := s.repo.CreateComparison(comparison); err != nil {
+		return nil, nil, fmt.Errorf("failed to create comparison: %w", err)
+	}
+
+	generationID := uuid.New().String()
+	genCtx, done := s.generations.Register(generationID, conv.UserID)
+
+	events := make(chan GenerationEvent, 10)
+	generation := &Generation{
+		ID:          generationID,
+		UserMessage: userMessage,
+		Events:      events,
+		detached:    make(chan struct{}),
+	}
+
+	// emit 写入缓冲并推送给仍在连接的客户端，各模型的goroutine并发调用
+	// 写入缓冲与推送需在同一把锁内完成，否则客户端收到的事件顺序与缓冲中的ID顺序不一致，断线续传时会丢失或重复事件
+	var emitMu sync.Mutex
+	emit := func(event string, data interface{}) {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		id := s.generations.Append(generationID, event, data)
+		select {
+		case events <- GenerationEvent{ID: id, Event: event, Data: data}:
+		case <-generation.detached:
+		}
+	}
+
+	s.publish(conv.UserID, EventGenerationStarted, conv.ID, map[string]interface{}{
+		"generation_id":   generationID,
+		"user_message_id": userMessage.ID,
+		"comparison_id":   comparison.ID,
+		"models":          models,
+	})
+
+	go func() {
+		defer close(events)
+		defer done()
+
+		emit(EventUserMessage, userMessage)
+
+		answers := make([]*Message, len(models))
+		var wg sync.WaitGroup
+		for i, model := range models {
+			wg.Add(1)
+			go func(index int, model string) {
+				defer wg.Done()
+				request := s.newLLMRequest(conv, llmMessages, model, GenerationOptions{}, true)
+				answers[index] = s.streamCandidate(genCtx, comparison, userMessage, request, references, index, emit)
+			}(i, model)
+		}
+		wg.Wait()
+
+		// 同一对话中的artifact按标识符计算版本，并发提取会产生重复版本，因此在全部结束后按模型顺序提取
+		var first *Message
+		for _, answer := range answers {
+			if answer == nil {
+				continue
+			}
+			s.extractArtifacts(answer)
+			s.publish(conv.UserID, EventMessageCreated, conv.ID, answer)
+			comparison.Responses = append(comparison.Responses, ComparisonResponse{Model: answer.Model, MessageID: answer.ID})
+			if first == nil {
+				first = answer
+			}
+		}
+
+		if first != nil {
+			if err := s.repo.SetActiveLeaf(conv.ID, first.ID); err != nil {
+				fmt.Printf("Warning: failed to update active leaf: %v\n", err)
+			} else {
+				s.publish(conv.UserID, EventConversationUpdated, conv.ID, map[string]interface{}{"active_leaf_id": first.ID})
+			}
+			s.maybeGenerateTitle(conv, userMessage, first)
+		}
+
+		result := map[string]interface{}{
+			"status":        "completed",
+			"comparison_id": comparison.ID,
+			"responses":     comparison.Responses,
+		}
+		emit(EventDone, result)
+		s.publish(conv.UserID, EventGenerationFinished, conv.ID, generationFinished(generationID, userMessage.ID, result))
+	}()
+
+	return comparison, generation, nil
+}
+
+// streamCandidate 读取单个模型的流式回复并保存为用户消息下的一个分支，不修改活动叶子
+// 被取消或中断时仍保存已生成的部分，启动失败或未生成任何内容时返回nil
+func (s *Service) streamCandidate(ctx context.Context, comparison *Comparison, userMessage *Message, request *llm.ChatRequest, references map[string]interface{}, index int, emit func(string, interface{})) *Message {
+	result := CompareResult{Model: request.Model, Index: index}
+
+	responseStream, err := s.llmService.StreamChat(ctx, request)
+	if err != nil {
+		result.FinishReason = FinishReasonError
+		result.Error = err.Error()
+		emit(EventCompareResult, result)
+		return nil
+	}
+
+	var fullContent strings.Builder
+	var usage *llm.TokenUsage
+	for response := range responseStream {
+		fullContent.WriteString(response.Message.Content)
+		if response.FinishReason != "" {
+			result.FinishReason = response.FinishReason
+		}
+		if response.Usage != nil {
+			usage = response.Usage
+		}
+		emit(EventCompareMessage, CompareChunk{Model: request.Model, Index: index, ChatResponse: response})
+	}
+
+	// 上游未给出结束原因即关闭：被取消或异常中断
+	if result.FinishReason == "" {
+		result.FinishReason = FinishReasonError
+		if ctx.Err() != nil {
+			result.FinishReason = FinishReasonCancelled
+		}
+	}
+
+	if fullContent.Len() == 0 && result.FinishReason != FinishReasonStop {
+		emit(EventCompareResult, result)
+		return nil
+	}
+
+	answer := NewMessage(comparison.ConversationID, &userMessage.ID, "assistant", fullContent.String())
+	answer.Model = request.Model
+	answer.FinishReason = result.FinishReason
+	for key, value := range references {
+		answer.Artifacts[key] = value
+	}
+	if usage != nil {
+		answer.Artifacts["usage"] = usage
+	}
+	answer.Artifacts["comparison_id"] = comparison.ID
+
+	if err := s.repo.CreateMessage(answer); err != nil {
+		result.Error = fmt.Sprintf("failed to save assistant message: %v", err)
+		emit(EventCompareResult, result)
+		return nil
+	}
+	if err := s.repo.CreateComparisonResponse(comparison.ID, answer.Model, answer.ID); err != nil {
+		fmt.Printf("Warning: failed to save comparison response: %v\n", err)
+	}
+
+	result.MessageID = answer.ID
+	emit(EventCompareResult, result)
+	return answer
+}
+
+// resolveCompareModels 校验参与对比的模型：须为已启用的模型，按模型名称去重
+func (s *Service) resolveCompareModels(models []string) ([]string, error) {
+	resolved := make([]string, 0, len(models))
+	seen := make(map[string]bool)
+	for _, model := range models {
+		chatModel := s.llmService.FindChatModel(strings.TrimSpace(model))
+		if chatModel == nil {
+			return nil, fmt.Errorf("invalid model: %q is not an enabled chat model", model)
+		}
+		if seen[chatModel.Value] {
+			continue
+		}
+		seen[chatModel.Value] = true
+		resolved = append(resolved, chatModel.Value)
+	}
+
+	if len(resolved) < minCompareModels || len(resolved) > maxCompareModels {
+		return nil, fmt.Errorf("invalid models: compare between %d and %d different models", minCompareModels, maxCompareModels)
+	}
+	return resolved, nil
+}
+
+// GetComparison 获取对比及各模型的回答
+func (s *Service) GetComparison(comparisonID, userID string) (*Comparison, error) {
+	comparison, err := s.repo.GetComparisonByID(comparisonID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("comparison not found")
+		}
+		return nil, fmt.Errorf("failed to get comparison: %w", err)
+	}
+
+	if comparison.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to comparison")
+	}
+
+	return comparison, nil
+}
+
+// VoteComparison 选出对比的胜者并切换到胜者分支，重复投票会覆盖之前的选择
+func (s *Service) VoteComparison(comparisonID, userID, messageID string) (*Comparison, error) {
+	comparison, err := s.GetComparison(comparisonID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var winner *ComparisonResponse
+	for i := range comparison.Responses {
+		if comparison.Responses[i].MessageID == messageID {
+			winner = &comparison.Responses[i]
+			break
+		}
+	}
+	if winner == nil {
+		return nil, fmt.Errorf("invalid message_id: not a response of this comparison")
+	}
+
+	votedAt := time.Now()
+	if err := s.repo.SetComparisonWinner(comparison.ID, winner.MessageID, winner.Model, votedAt); err != nil {
+		return nil, fmt.Errorf("failed to save vote: %w", err)
+	}
+	comparison.WinnerMessageID = &winner.MessageID
+	comparison.WinnerModel = &winner.Model
+	comparison.VotedAt = &votedAt
+
+	if _, err := s.SwitchBranch(comparison.ConversationID, userID, winner.MessageID); err != nil {
+		fmt.Printf("Warning: failed to switch to winning branch: %v\n", err)
+	}
+
+	return comparison, nil
+}
+
+// GetLeaderboard 按投票时间依次回放全部投票计算各模型的Elo分
+// 每次投票中胜者与其余每个有回答的模型各进行一场对局，同一次投票的对局均基于投票前的分数
+func (s *Service) GetLeaderboard() ([]ModelRating, error) {
+	votes, err := s.repo.GetComparisonVotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comparison votes: %w", err)
+	}
+
+	ratings := make(map[string]*ModelRating)
+	get := func(model string) *ModelRating {
+		rating, ok := ratings[model]
+		if !ok {
+			rating = &ModelRating{Model: model, Rating: eloInitialRating}
+			ratings[model] = rating
+		}
+		return rating
+	}
+
+	for _, vote := range votes {
+		winner := get(vote.winner)
+		deltas := make(map[string]float64)
+		for _, model := range vote.models {
+			if model == vote.winner {
+				continue
+			}
+			loser := get(model)
+			expected := 1 / (1 + math.Pow(10, (loser.Rating-winner.Rating)/400))
+			delta := eloK * (1 - expected)
+			deltas[vote.winner] += delta
+			deltas[model] -= delta
+			loser.Losses++
+			loser.Matches++
+			winner.Wins++
+			winner.Matches++
+		}
+		for model, delta := range deltas {
+			ratings[model].Rating += delta
+		}
+	}
+
+	leaderboard := make([]ModelRating, 0, len(ratings))
+	for _, rating := range ratings {
+		rating.Rating = math.Round(rating.Rating*10) / 10
+		leaderboard = append(leaderboard, *rating)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Rating != leaderboard[j].Rating {
+			return leaderboard[i].Rating > leaderboard[j].Rating
+		}
+		return leaderboard[i].Model < leaderboard[j].Model
+	})
+
+	return leaderboard, nil
+}
+
+// CreateComparison 保存对比
+func (r *Repository) CreateComparison(comparison *Comparison) error {
+	query := `
+		INSERT INTO comparisons (id, user_id, conversation_id, user_message_id, models, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.Exec(query, comparison.ID, comparison.UserID, comparison.ConversationID,
+		comparison.UserMessageID, pq.Array(comparison.Models), comparison.CreatedAt)
+	return err
+}
+
+// CreateComparisonResponse 记录对比中某个模型的回答
+func (r *Repository) CreateComparisonResponse(comparisonID, model, messageID string) error {
+	query := `INSERT INTO comparison_responses (comparison_id, model, message_id) VALUES ($1, $2, $3)`
+	_, err := r.db.Exec(query, comparisonID, model, messageID)
+	return err
+}
+
+// GetComparisonByID 获取对比及其回答，回答按对比时的模型顺序排列
+func (r *Repository) GetComparisonByID(id string) (*Comparison, error) {
+	query := `
+		SELECT id, user_id, conversation_id, user_message_id, models, winner_message_id, winner_model, created_at, voted_at
+		FROM comparisons
+		WHERE id = $1`
+
+	var comparison Comparison
+	var winnerMessageID, winnerModel sql.NullString
+	var votedAt sql.NullTime
+
+	err := r.db.QueryRow(query, id).Scan(&comparison.ID, &comparison.UserID, &comparison.ConversationID,
+		&comparison.UserMessageID, pq.Array(&comparison.Models), &winnerMessageID, &winnerModel,
+		&comparison.CreatedAt, &votedAt)
+	if err != nil {
+		return nil, err
+	}
+	comparison.WinnerMessageID = nullStringPtr(winnerMessageID)
+	comparison.WinnerModel = nullStringPtr(winnerModel)
+	comparison.VotedAt = nullTimePtr(votedAt)
+
+	rows, err := r.db.Query(`
+		SELECT r.model, r.message_id
+		FROM comparison_responses r
+		JOIN comparisons c ON c.id = r.comparison_id
+		WHERE r.comparison_id = $1
+		ORDER BY array_position(c.models, r.model::text)`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comparison.Responses = []ComparisonResponse{}
+	for rows.Next() {
+		var response ComparisonResponse
+		if err := rows.Scan(&response.Model, &response.MessageID); err != nil {
+			return nil, err
+		}
+		comparison.Responses = append(comparison.Responses, response)
+	}
+
+	return &comparison, rows.Err()
+}
+
+// SetComparisonWinner 记录对比的胜者
+func (r *Repository) SetComparisonWinner(id, messageID, model string, votedAt time.Time) error {
+	query := `UPDATE comparisons SET winner_message_id = $2, winner_model = $3, voted_at = $4 WHERE id = $1`
+	_, err := r.db.Exec(query, id, messageID, model, votedAt)
+	return err
+}
+
+// GetComparisonVotes 按投票时间获取全部已投票的对比及其有回答的模型
+func (r *Repository) GetComparisonVotes() ([]comparisonVote, error) {
+	query := `
+		SELECT c.winner_model,
+			ARRAY(SELECT r.model FROM comparison_responses r WHERE r.comparison_id = c.id ORDER BY r.model)
+		FROM comparisons c
+		WHERE c.winner_model IS NOT NULL
+		ORDER BY c.voted_at ASC, c.id ASC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votes []comparisonVote
+	for rows.Next() {
+		var vote comparisonVote
+		if err := rows.Scan(&vote.winner, pq.Array(&vote.models)); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
@@ -0,0 +1,218 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// eventSeqKeyPrefix 用户领域事件的序号计数器，不设过期，保证序号单调递增
+	eventSeqKeyPrefix = "events:seq:"
+	// eventLogKeyPrefix 用户最近的领域事件（有序集合，分数为序号），用于客户端补齐缺失的事件
+	eventLogKeyPrefix = "events:log:"
+	// eventLogSize 每个用户保留的事件数
+	eventLogSize = 500
+	// eventLogTTL 事件日志的过期时间，用户长时间没有新事件时整体清除
+	eventLogTTL = 24 * time.Hour
+)
+
+// 领域事件类型，推送给用户的所有连接
+// conversation.updated的data为变更后的字段（通过更新接口修改时为完整的对话）
+const (
+	EventConversationCreated = "conversation.created"
+	EventConversationUpdated = "conversation.updated"
+	EventConversationDeleted = "conversation.deleted"
+	EventMessageCreated      = "message.created"
+	EventGenerationStarted   = "generation.started"
+	EventGenerationFinished  = "generation.finished"
+)
+
+// appendEventScript 原子地分配序号并写入事件日志，保证日志中的序号连续
+var appendEventScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('ZADD', KEYS[2], seq, ARGV[1])
+redis.call('ZREMRANGEBYRANK', KEYS[2], 0, -(tonumber(ARGV[2]) + 1))
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+return seq
+`)
+
+// DomainEvent 对话状态变更事件，Seq在用户范围内从1开始连续递增
+// 客户端记录收到的最大序号，发现跳号（或重连后）通过 GET /api/events?after=<seq> 补齐
+type DomainEvent struct {
+	Seq            int64       `json:"seq"`
+	ID             string      `json:"id"`
+	Type           string      `json:"type"`
+	ConversationID string      `json:"conversation_id"`
+	Data           interface{} `json:"data,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// EventPage 序号之后的事件
+// ResyncRequired 为true时日志已无法补齐（事件已被清理或序号被重置），客户端应重新加载对话列表与当前对话，并以Seq作为新的起点
+type EventPage struct {
+	Events         []DomainEvent `json:"events"`
+	Seq            int64         `json:"seq"`
+	ResyncRequired bool          `json:"resync_required"`
+}
+
+// EventLog 分配事件序号并保留最近的事件
+// 传入Redis客户端时序号与日志跨实例共享；为nil时只保存在本实例内存中，适用于单实例部署
+type EventLog struct {
+	redis *redis.Client
+
+	mu     sync.Mutex
+	seqs   map[string]int64
+	events map[string][]DomainEvent
+}
+
+// NewEventLog 创建事件日志
+func NewEventLog(redisClient *redis.Client) *EventLog {
+	return &EventLog{
+		redis:  redisClient,
+		seqs:   make(map[string]int64),
+		events: make(map[string][]DomainEvent),
+	}
+}
+
+// Append 为事件分配序号并写入日志
+func (l *EventLog) Append(ctx context.Context, userID string, event *DomainEvent) error {
+	if l.redis == nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.seqs[userID]++
+		event.Seq = l.seqs[userID]
+		events := append(l.events[userID], *event)
+		if len(events) > eventLogSize {
+			events = events[len(events)-eventLogSize:]
+		}
+		l.events[userID] = events
+		return nil
+	}
+
+	// 序号由脚本分配，日志中事件的seq为0，读取时以分数回填
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	seq, err := appendEventScript.Run(ctx, l.redis,
+		[]string{eventSeqKeyPrefix + userID, eventLogKeyPrefix + userID},
+		payload, eventLogSize, int(eventLogTTL.Seconds()),
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	event.Seq = seq
+	return nil
+}
+
+// Since 获取序号after之后的事件
+func (l *EventLog) Since(ctx context.Context, userID string, after int64) (*EventPage, error) {
+	var seq int64
+	var events []DomainEvent
+
+	if l.redis == nil {
+		l.mu.Lock()
+		seq = l.seqs[userID]
+		for _, event := range l.events[userID] {
+			if event.Seq > after {
+				events = append(events, event)
+			}
+		}
+		l.mu.Unlock()
+	} else {
+		key := eventLogKeyPrefix + userID
+		// 在同一事务中读取序号与日志，保证返回的Seq与事件一致
+		pipe := l.redis.TxPipeline()
+		seqCmd := pipe.Get(ctx, eventSeqKeyPrefix+userID)
+		logCmd := pipe.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min: "(" + strconv.FormatInt(after, 10),
+			Max: "+inf",
+		})
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to get events: %w", err)
+		}
+
+		if value, err := seqCmd.Int64(); err == nil {
+			seq = value
+		}
+		for _, z := range logCmd.Val() {
+			var event DomainEvent
+			member, _ := z.Member.(string)
+			if err := json.Unmarshal([]byte(member), &event); err != nil {
+				log.Printf("Warning: failed to decode event: %v", err)
+				continue
+			}
+			event.Seq = int64(z.Score)
+			events = append(events, event)
+		}
+	}
+
+	page := &EventPage{Events: events, Seq: seq}
+	if page.Events == nil {
+		page.Events = []DomainEvent{}
+	}
+
+	// 客户端序号超过当前序号说明计数器已重置；首个事件不紧接after说明中间的事件已被清理
+	switch {
+	case after > seq:
+		page.ResyncRequired = true
+	case after < seq:
+		if len(events) == 0 || events[0].Seq != after+1 {
+			page.ResyncRequired = true
+		}
+	}
+	return page, nil
+}
+
+// SetEventLog 替换事件日志（例如使用基于Redis的跨实例日志）
+func (s *Service) SetEventLog(eventLog *EventLog) {
+	s.events = eventLog
+}
+
+// GetEvents 获取用户序号after之后的领域事件
+func (s *Service) GetEvents(ctx context.Context, userID string, after int64) (*EventPage, error) {
+	if after < 0 {
+		return nil, fmt.Errorf("invalid after: must not be negative")
+	}
+	return s.events.Since(ctx, userID, after)
+}
+
+// generationFinished generation.finished事件的数据：生成ID、用户消息ID与done事件的结果
+func generationFinished(generationID, userMessageID string, result map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"generation_id":   generationID,
+		"user_message_id": userMessageID,
+	}
+	for key, value := range result {
+		data[key] = value
+	}
+	return data
+}
+
+// publish 记录领域事件并推送给用户的所有连接
+// 写入日志失败时仍推送，此时序号为0，客户端不应据此更新已收到的最大序号
+func (s *Service) publish(userID, eventType, conversationID string, data interface{}) {
+	event := &DomainEvent{
+		ID:             uuid.New().String(),
+		Type:           eventType,
+		ConversationID: conversationID,
+		Data:           data,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.events.Append(context.Background(), userID, event); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if s.notifier != nil {
+		s.notifier.Notify(userID, eventType, event)
+	}
+}
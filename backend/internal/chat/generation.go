@@ -0,0 +1,279 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// generationKeyPrefix 生成任务元数据（所属用户与状态），用于跨实例校验权限
+	generationKeyPrefix = "generation:"
+	// generationStreamKeyPrefix 生成事件缓冲（Redis Stream），用于断线重连后补发
+	generationStreamKeyPrefix = "generation:stream:"
+	// generationCancelChannel 跨实例广播取消请求的频道
+	generationCancelChannel = "generation:cancel"
+	// generationTTL 生成任务元数据与事件缓冲的过期时间
+	generationTTL = time.Hour
+	// generationReadBlock 重连读取事件时单次阻塞等待的时长
+	generationReadBlock = 15 * time.Second
+)
+
+// 生成任务状态
+const (
+	generationStatusRunning  = "running"
+	generationStatusFinished = "finished"
+)
+
+// 生成结束原因
+const (
+	FinishReasonStop      = "stop"
+	FinishReasonLength    = "length"
+	FinishReasonCancelled = "cancelled"
+	FinishReasonError     = "error"
+)
+
+// 生成事件类型，与SSE事件名一致
+const (
+	EventUserMessage      = "user_message"
+	EventAssistantMessage = "assistant_message"
+	EventDone             = "done"
+)
+
+// GenerationEvent 生成过程中产生的事件，ID为事件在Redis Stream中的ID（未启用Redis时为空）
+type GenerationEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+}
+
+// Generation 进行中的流式生成
+type Generation struct {
+	ID          string
+	UserMessage *Message
+	Events      <-chan GenerationEvent
+	detached    chan struct{}
+	detachOnce  sync.Once
+}
+
+// Detach 发起请求的客户端断开时调用，生成在服务端继续进行，事件仍写入缓冲供重连读取
+func (g *Generation) Detach() {
+	g.detachOnce.Do(func() {
+		close(g.detached)
+	})
+}
+
+// GenerationRegistry 进行中的生成任务注册表
+// 本实例的任务直接通过context取消；任务不在本实例时通过Redis频道广播给持有它的实例
+type GenerationRegistry struct {
+	mu          sync.Mutex
+	generations map[string]*runningGeneration
+	redis       *redis.Client
+}
+
+// runningGeneration 本实例上运行中的生成任务
+type runningGeneration struct {
+	userID string
+	cancel context.CancelFunc
+}
+
+// NewGenerationRegistry 创建生成任务注册表，redisClient为nil时只支持单实例且不支持断线重连
+func NewGenerationRegistry(redisClient *redis.Client) *GenerationRegistry {
+	registry := &GenerationRegistry{
+		generations: make(map[string]*runningGeneration),
+		redis:       redisClient,
+	}
+	if redisClient != nil {
+		go registry.listen(context.Background())
+	}
+	return registry
+}
+
+// Register 注册生成任务，返回可被取消的context与任务结束时调用的注销函数
+// 生成与发起请求的连接解耦，因此context不继承请求的context
+func (r *GenerationRegistry) Register(generationID, userID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r.mu.Lock()
+	r.generations[generationID] = &runningGeneration{userID: userID, cancel: cancel}
+	r.mu.Unlock()
+
+	if r.redis != nil {
+		key := generationKeyPrefix + generationID
+		pipe := r.redis.TxPipeline()
+		pipe.HSet(ctx, key, "user_id", userID, "status", generationStatusRunning)
+		pipe.Expire(ctx, key, generationTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Warning: failed to register generation %s: %v", generationID, err)
+		}
+	}
+
+	return ctx, func() {
+		cancel()
+
+		r.mu.Lock()
+		delete(r.generations, generationID)
+		r.mu.Unlock()
+
+		if r.redis != nil {
+			// 保留元数据到过期，供结束后的重连校验权限
+			r.redis.HSet(context.Background(), generationKeyPrefix+generationID, "status", generationStatusFinished)
+		}
+	}
+}
+
+// Cancel 取消生成任务，只有任务所属用户可以取消
+func (r *GenerationRegistry) Cancel(ctx context.Context, generationID, userID string) error {
+	r.mu.Lock()
+	generation, ok := r.generations[generationID]
+	r.mu.Unlock()
+
+	if ok {
+		if generation.userID != userID {
+			return fmt.Errorf("unauthorized access to generation")
+		}
+		generation.cancel()
+		return nil
+	}
+
+	status, err := r.authorize(ctx, generationID, userID)
+	if err != nil {
+		return err
+	}
+	if status != generationStatusRunning {
+		return fmt.Errorf("invalid generation: already finished")
+	}
+
+	if err := r.redis.Publish(ctx, generationCancelChannel, generationID).Err(); err != nil {
+		return fmt.Errorf("failed to cancel generation: %w", err)
+	}
+	return nil
+}
+
+// Append 将事件写入生成事件缓冲，返回事件ID；未启用Redis或写入失败时返回空字符串
+func (r *GenerationRegistry) Append(generationID, event string, data interface{}) string {
+	if r.redis == nil {
+		return ""
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Warning: failed to marshal generation event: %v", err)
+		return ""
+	}
+
+	ctx := context.Background()
+	key := generationStreamKeyPrefix + generationID
+	id, err := r.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		Values: map[string]interface{}{"event": event, "data": payload},
+	}).Result()
+	if err != nil {
+		log.Printf("Warning: failed to buffer generation event: %v", err)
+		return ""
+	}
+	r.redis.Expire(ctx, key, generationTTL)
+
+	return id
+}
+
+// Replay 读取lastEventID之后的事件，补发完缓冲内容后继续跟随直到生成结束
+// lastEventID为空时从头读取
+func (r *GenerationRegistry) Replay(ctx context.Context, generationID, userID, lastEventID string) (<-chan GenerationEvent, error) {
+	if r.redis == nil {
+		return nil, fmt.Errorf("generation not found")
+	}
+	if _, err := r.authorize(ctx, generationID, userID); err != nil {
+		return nil, err
+	}
+
+	if lastEventID == "" {
+		lastEventID = "0"
+	}
+
+	events := make(chan GenerationEvent, 10)
+	go func() {
+		defer close(events)
+
+		key := generationStreamKeyPrefix + generationID
+		for {
+			streams, err := r.redis.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{key, lastEventID},
+				Count:   100,
+				Block:   generationReadBlock,
+			}).Result()
+			if err == redis.Nil {
+				// 没有新事件：任务已结束（例如实例异常退出未写入done）时停止跟随
+				status, _ := r.redis.HGet(ctx, generationKeyPrefix+generationID, "status").Result()
+				if status != generationStatusRunning {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				return
+			}
+
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					lastEventID = msg.ID
+					event, _ := msg.Values["event"].(string)
+					data, _ := msg.Values["data"].(string)
+
+					select {
+					case events <- GenerationEvent{ID: msg.ID, Event: event, Data: json.RawMessage(data)}:
+					case <-ctx.Done():
+						return
+					}
+
+					if event == EventDone {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// authorize 检查Redis中记录的任务所属用户，返回任务状态
+func (r *GenerationRegistry) authorize(ctx context.Context, generationID, userID string) (string, error) {
+	if r.redis == nil {
+		return "", fmt.Errorf("generation not found")
+	}
+
+	meta, err := r.redis.HGetAll(ctx, generationKeyPrefix+generationID).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get generation: %w", err)
+	}
+	if len(meta) == 0 {
+		return "", fmt.Errorf("generation not found")
+	}
+	if meta["user_id"] != userID {
+		return "", fmt.Errorf("unauthorized access to generation")
+	}
+
+	return meta["status"], nil
+}
+
+// listen 订阅其他实例广播的取消请求
+func (r *GenerationRegistry) listen(ctx context.Context) {
+	pubsub := r.redis.Subscribe(ctx, generationCancelChannel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		r.mu.Lock()
+		generation, ok := r.generations[msg.Payload]
+		r.mu.Unlock()
+
+		if ok {
+			generation.cancel()
+		}
+	}
+}
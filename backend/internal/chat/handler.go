@@ -1,9 +1,14 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,7 +31,7 @@ func (h *Handler) CreateConversation(c *gin.Context) {
 	}
 
 	var req struct {
-		Title string `json:"title" binding:"required"`
+		Title string `json:"title"`
 		Model string `json:"model" binding:"required"`
 	}
 
@@ -44,7 +49,8 @@ func (h *Handler) CreateConversation(c *gin.Context) {
 	c.JSON(http.StatusCreated, conv)
 }
 
-// GetConversations 获取对话列表
+// GetConversations 分页获取对话列表（轻量投影）
+// 参数：limit、cursor（上一页返回的next_cursor）、sort(updated_at/created_at)、order(desc/asc)、model、assistant_id
 func (h *Handler) GetConversations(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -52,13 +58,46 @@ func (h *Handler) GetConversations(c *gin.Context) {
 		return
 	}
 
-	conversations, err := h.service.GetConversations(userID.(string))
+	limit, err := parseIntQuery(c, "limit")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	opts := ConversationListOptions{
+		Limit:       limit,
+		Cursor:      c.Query("cursor"),
+		Sort:        c.Query("sort"),
+		Model:       c.Query("model"),
+		AssistantID: c.Query("assistant_id"),
+		FolderID:    c.Query("folder_id"),
+		Tag:         c.Query("tag"),
+		Archived:    c.Query("archived"),
+	}
+	if value := c.Query("pinned"); value != "" {
+		pinned, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pinned"})
+			return
+		}
+		opts.Pinned = &pinned
+	}
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order: must be asc or desc"})
+		return
+	}
+
+	page, err := h.service.ListConversations(userID.(string), opts)
+	if err != nil {
+		writeMessageError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+	c.JSON(http.StatusOK, page)
 }
 
 // GetConversation 获取对话详情
@@ -114,7 +153,7 @@ func (h *Handler) UpdateConversation(c *gin.Context) {
 
 	conv, err := h.service.UpdateConversation(conversationID, userID.(string), updates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeMessageError(c, err)
 		return
 	}
 
@@ -185,7 +224,14 @@ func (h *Handler) SendMessage(c *gin.Context) {
 func (h *Handler) handleStreamMessage(c *gin.Context, conversationID, userID, content string) {
 	fmt.Printf("Debug: handleStreamMessage called - ConversationID: %s, UserID: %s, Content: %s\n", 
 		conversationID, userID, content)
-	
+
+	h.streamReply(c, func(ctx context.Context) (*Generation, error) {
+		return h.service.SendMessageStream(ctx, conversationID, userID, content)
+	})
+}
+
+// streamReply 以SSE格式输出用户消息与助手的流式回复
+func (h *Handler) streamReply(c *gin.Context, start func(ctx context.Context) (*Generation, error)) {
 	// 设置SSE头部
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -193,39 +239,290 @@ func (h *Handler) handleStreamMessage(c *gin.Context, conversationID, userID, co
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Headers", "Cache-Control")
 
-	userMessage, responseStream, err := h.service.SendMessageStream(
-		c.Request.Context(), conversationID, userID, content)
+	generation, err := start(c.Request.Context())
 	if err != nil {
 		fmt.Printf("Debug: SendMessageStream error: %v\n", err)
 		c.SSEvent("error", gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("Debug: SendMessageStream started - UserMessage: %+v\n", userMessage)
+	fmt.Printf("Debug: SendMessageStream started - UserMessage: %+v\n", generation.UserMessage)
+
+	// 客户端断开后生成在服务端继续，可通过 GET /generations/:id/stream 重连
+	defer generation.Detach()
 
-	// 发送用户消息
-	c.SSEvent("user_message", userMessage)
+	// 发送生成任务ID，客户端可用于取消与重连
+	c.SSEvent("generation", gin.H{"generation_id": generation.ID})
 	c.Writer.Flush()
 
-	// 发送流式响应
-	for response := range responseStream {
+	h.writeEvents(c, generation.Events)
+}
+
+// writeEvents 以SSE格式输出生成事件，事件ID可作为重连时的Last-Event-ID
+func (h *Handler) writeEvents(c *gin.Context, events <-chan GenerationEvent) {
+	for {
 		select {
 		case <-c.Request.Context().Done():
 			fmt.Printf("Debug: Stream context cancelled\n")
 			return
-		default:
-			fmt.Printf("Debug: Stream response: %+v\n", response)
-			c.SSEvent("assistant_message", response)
+		case event, ok := <-events:
+			if !ok {
+				fmt.Printf("Debug: Stream completed\n")
+				return
+			}
+			c.Render(-1, sse.Event{
+				Id:    event.ID,
+				Event: event.Event,
+				Data:  event.Data,
+			})
 			c.Writer.Flush()
 		}
 	}
+}
+
+// ResumeGeneration 断线重连后继续接收生成事件，支持Last-Event-ID
+func (h *Handler) ResumeGeneration(c *gin.Context) {
+	generationID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	events, err := h.service.ResumeGeneration(c.Request.Context(), generationID, userID.(string), lastEventID)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	// 设置SSE头部
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Cache-Control, Last-Event-ID")
+
+	h.writeEvents(c, events)
+}
+
+// EditMessage 编辑用户消息，创建新分支并重新生成回答
+func (h *Handler) EditMessage(c *gin.Context) {
+	conversationID := c.Param("id")
+	messageID := c.Param("msgId")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
 
-	// 发送结束标记
-	fmt.Printf("Debug: Stream completed\n")
-	c.SSEvent("done", gin.H{"status": "completed"})
+	var req struct {
+		Content string `json:"content" binding:"required"`
+		Stream  bool   `json:"stream"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Stream {
+		h.streamReply(c, func(ctx context.Context) (*Generation, error) {
+			return h.service.EditMessageStream(ctx, conversationID, userID.(string), messageID, req.Content)
+		})
+		return
+	}
+
+	userMessage, assistantMessage, err := h.service.EditMessage(
+		c.Request.Context(), conversationID, userID.(string), messageID, req.Content)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_message":      userMessage,
+		"assistant_message": assistantMessage,
+	})
 }
 
-// GetMessages 获取消息列表
+// Regenerate 重新生成回答，可指定其他模型或温度
+func (h *Handler) Regenerate(c *gin.Context) {
+	conversationID := c.Param("id")
+	messageID := c.Param("msgId")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		Model       string   `json:"model"`
+		Temperature *float64 `json:"temperature"`
+		Stream      bool     `json:"stream"`
+	}
+
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	opts := GenerationOptions{Model: req.Model}
+	if req.Temperature != nil {
+		if *req.Temperature < 0 || *req.Temperature > 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "temperature must be between 0 and 2"})
+			return
+		}
+		opts.Temperature = req.Temperature
+	}
+
+	if req.Stream {
+		h.streamReply(c, func(ctx context.Context) (*Generation, error) {
+			return h.service.RegenerateStream(ctx, conversationID, userID.(string), messageID, opts)
+		})
+		return
+	}
+
+	userMessage, assistantMessage, err := h.service.Regenerate(
+		c.Request.Context(), conversationID, userID.(string), messageID, opts)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_message":      userMessage,
+		"assistant_message": assistantMessage,
+	})
+}
+
+// SwitchBranch 切换到指定消息所在的分支
+func (h *Handler) SwitchBranch(c *gin.Context) {
+	conversationID := c.Param("id")
+	messageID := c.Param("msgId")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	messages, err := h.service.SwitchBranch(conversationID, userID.(string), messageID)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// CancelGeneration 取消进行中的流式生成
+func (h *Handler) CancelGeneration(c *gin.Context) {
+	generationID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.CancelGeneration(c.Request.Context(), generationID, userID.(string)); err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "generation cancelled"})
+}
+
+// Search 检索对话与消息：GET /api/search?q=&model=&role=&from=&to=&limit=&offset=
+// from/to 支持 YYYY-MM-DD 或 RFC3339，to 为日期时包含当天
+func (h *Handler) Search(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	filter := SearchFilter{
+		Query: c.Query("q"),
+		Model: c.Query("model"),
+		Role:  c.Query("role"),
+	}
+
+	var err error
+	if filter.From, err = parseSearchTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	if filter.To, err = parseSearchTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+	if filter.Limit, err = parseIntQuery(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if filter.Offset, err = parseIntQuery(c, "offset"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	results, err := h.service.Search(userID.(string), filter)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// parseSearchTime 解析日期参数；endOfDay为true时日期格式取次日零点作为开区间上界
+func parseSearchTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC3339")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseIntQuery 解析可选的整数查询参数
+func parseIntQuery(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// writeMessageError 根据错误类型返回对应状态码
+func writeMessageError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "no rows"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "invalid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetMessages 分页获取活动路径上的消息：limit、before/after（消息ID）；tree=true 返回完整消息树
 func (h *Handler) GetMessages(c *gin.Context) {
 	conversationID := c.Param("id")
 	userID, exists := c.Get("user_id")
@@ -246,11 +543,317 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	messages, err := h.service.GetMessages(conversationID)
+	// tree=true 时返回包含所有分支的完整消息树
+	if c.Query("tree") == "true" {
+		messages, err := h.service.GetMessageTree(conversationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"messages": messages})
+		return
+	}
+
+	limit, err := parseIntQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	page, err := h.service.GetMessagePage(conversationID, MessagePageOptions{
+		Limit:  limit,
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+	})
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetFolders 获取文件夹列表
+func (h *Handler) GetFolders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	folders, err := h.service.GetFolders(userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"messages": messages})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+// CreateFolder 创建文件夹
+func (h *Handler) CreateFolder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := h.service.CreateFolder(userID.(string), req)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+// UpdateFolder 更新文件夹
+func (h *Handler) UpdateFolder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := h.service.UpdateFolder(c.Param("id"), userID.(string), req)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder 删除文件夹
+func (h *Handler) DeleteFolder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.DeleteFolder(c.Param("id"), userID.(string)); err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "folder deleted successfully"})
+}
+
+// GetTags 获取标签列表
+func (h *Handler) GetTags(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	tags, err := h.service.GetTags(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// RenameTag 重命名标签
+func (h *Handler) RenameTag(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RenameTag(userID.(string), c.Param("tag"), req.Name); err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tag renamed successfully"})
+}
+
+// DeleteTag 删除标签
+func (h *Handler) DeleteTag(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.DeleteTag(userID.(string), c.Param("tag")); err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tag deleted successfully"})
+}
+
+// BulkUpdateConversations 批量移动、打标签、置顶、归档或删除对话
+func (h *Handler) BulkUpdateConversations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	affected, err := h.service.BulkUpdate(userID.(string), req)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "affected": affected})
+}
+
+// CompareMessages 将用户消息并发发给多个模型，以SSE输出按模型标记的流式回复
+func (h *Handler) CompareMessages(c *gin.Context) {
+	conversationID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		Content string   `json:"content" binding:"required"`
+		Models  []string `json:"models" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison, generation, err := h.service.CompareStream(c.Request.Context(), conversationID, userID.(string), req.Content, req.Models)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	// 设置SSE头部
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+
+	// 客户端断开后生成在服务端继续，可通过 GET /generations/:id/stream 重连
+	defer generation.Detach()
+
+	c.SSEvent("generation", gin.H{"generation_id": generation.ID, "comparison_id": comparison.ID, "models": comparison.Models})
+	c.Writer.Flush()
+
+	h.writeEvents(c, generation.Events)
+}
+
+// GetComparison 获取对比及各模型的回答
+func (h *Handler) GetComparison(c *gin.Context) {
+	comparisonID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	comparison, err := h.service.GetComparison(comparisonID, userID.(string))
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comparison": comparison})
+}
+
+// VoteComparison 选出对比的胜者
+func (h *Handler) VoteComparison(c *gin.Context) {
+	comparisonID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison, err := h.service.VoteComparison(comparisonID, userID.(string), req.MessageID)
+	if err != nil {
+		writeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comparison": comparison})
+}
+
+// GetLeaderboard 获取基于对比投票的模型Elo排行榜
+func (h *Handler) GetLeaderboard(c *gin.Context) {
+	leaderboard, err := h.service.GetLeaderboard()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboard": leaderboard})
+}
+
+// GetEvents 获取序号after之后的领域事件，客户端发现事件跳号或重连后调用以补齐
+func (h *Handler) GetEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var after int64
+	if value := c.Query("after"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+			return
+		}
+		after = parsed
+	}
+
+	page, err := h.service.GetEvents(c.Request.Context(), userID.(string), after)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ImportResult 导入单个对话的结果
+type ImportResult struct {
+	// Created 对话是首次导入；为false时对话已存在，只补充缺少的消息
+	Created       bool
+	MessagesAdded int
+}
+
+// ImportConversation 导入外部对话，对话与消息使用调用方生成的确定性ID，重复导入不会产生重复数据
+// messages需按父消息在前的顺序排列；已存在的对话保留当前活动分支，避免覆盖用户导入后的操作
+func (s *Service) ImportConversation(conv *Conversation, messages []Message) (*ImportResult, error) {
+	result, err := s.repo.ImportConversation(conv, messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import conversation: %w", err)
+	}
+
+	switch {
+	case result.Created:
+		s.publish(conv.UserID, EventConversationCreated, conv.ID, conv)
+	case result.MessagesAdded > 0:
+		s.publish(conv.UserID, EventConversationUpdated, conv.ID, map[string]interface{}{"messages_added": result.MessagesAdded})
+	}
+	return result, nil
+}
+
+// ImportConversation 在事务中写入对话与消息，已存在的记录跳过
+func (r *Repository) ImportConversation(conv *Conversation, messages []Message) (*ImportResult, error) {
+	settingsJSON, err := json.Marshal(conv.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO conversations (id, user_id, title, model, settings, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO NOTHING`
+	inserted, err := tx.Exec(query, conv.ID, conv.UserID, conv.Title, conv.Model, settingsJSON,
+		conv.CreatedAt, conv.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	created, err := inserted.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if created == 0 {
+		var ownerID string
+		if err := tx.QueryRow(`SELECT user_id FROM conversations WHERE id = $1`, conv.ID).Scan(&ownerID); err != nil {
+			return nil, err
+		}
+		if ownerID != conv.UserID {
+			return nil, fmt.Errorf("unauthorized access to conversation")
+		}
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO messages (id, conversation_id, parent_id, role, content, model, finish_reason, artifacts, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO NOTHING`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	result := &ImportResult{Created: created > 0}
+	for _, msg := range messages {
+		artifactsJSON, err := json.Marshal(msg.Artifacts)
+		if err != nil {
+			return nil, err
+		}
+		res, err := stmt.Exec(msg.ID, conv.ID, msg.ParentID, msg.Role, msg.Content, msg.Model,
+			msg.FinishReason, artifactsJSON, msg.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if affected, _ := res.RowsAffected(); affected > 0 {
+			result.MessagesAdded++
+		}
+	}
+
+	if result.Created && conv.ActiveLeafID != nil {
+		if _, err := tx.Exec(`UPDATE conversations SET active_leaf_id = $2 WHERE id = $1`, conv.ID, conv.ActiveLeafID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -3,30 +3,51 @@ package chat
 import (
 	"database/sql"
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// DefaultConversationTitle 未指定标题时的默认标题，首轮问答后会自动生成标题替换
+const DefaultConversationTitle = "New chat"
+
+// IsDefaultTitle 标题是否为默认标题，不区分大小写（兼容旧版客户端创建的 "New Chat"）
+func IsDefaultTitle(title string) bool {
+	return strings.EqualFold(strings.TrimSpace(title), DefaultConversationTitle)
+}
+
 // Conversation 对话模型
 type Conversation struct {
 	ID        string                 `json:"id" db:"id"`
 	UserID    string                 `json:"user_id" db:"user_id"`
 	Title     string                 `json:"title" db:"title"`
 	Model     string                 `json:"model" db:"model"`
-	Settings  map[string]interface{} `json:"settings" db:"settings"`
-	CreatedAt time.Time              `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time              `json:"updated_at" db:"updated_at"`
+	Settings     ConversationSettings   `json:"settings" db:"settings"`
+	ActiveLeafID *string                `json:"active_leaf_id" db:"active_leaf_id"`
+	AssistantID  *string                `json:"assistant_id" db:"assistant_id"`
+	FolderID     *string                `json:"folder_id" db:"folder_id"`
+	Pinned       bool                   `json:"pinned" db:"pinned"`
+	ArchivedAt   *time.Time             `json:"archived_at" db:"archived_at"`
+	// Tags 对话标签，存储在conversation_tags表中
+	Tags []string `json:"tags" db:"-"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at" db:"updated_at"`
 }
 
 // Message 消息模型
 type Message struct {
 	ID             string                 `json:"id" db:"id"`
 	ConversationID string                 `json:"conversation_id" db:"conversation_id"`
+	ParentID       *string                `json:"parent_id" db:"parent_id"`
 	Role           string                 `json:"role" db:"role"`
 	Content        string                 `json:"content" db:"content"`
+	Model          string                 `json:"model,omitempty" db:"model"`
+	FinishReason   string                 `json:"finish_reason,omitempty" db:"finish_reason"`
 	Artifacts      map[string]interface{} `json:"artifacts" db:"artifacts"`
 	CreatedAt      time.Time              `json:"created_at" db:"created_at"`
+	// Siblings 同一父消息下的所有分支（含自身），按创建时间排序，仅在返回活动路径时填充
+	Siblings []string `json:"siblings,omitempty" db:"-"`
 }
 
 // Repository 聊天仓库接口
@@ -47,18 +68,18 @@ func (r *Repository) CreateConversation(conv *Conversation) error {
 	}
 
 	query := `
-		INSERT INTO conversations (id, user_id, title, model, settings, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		INSERT INTO conversations (id, user_id, title, model, settings, assistant_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
 	_, err = r.db.Exec(query, conv.ID, conv.UserID, conv.Title, conv.Model, 
-		settingsJSON, conv.CreatedAt, conv.UpdatedAt)
+		settingsJSON, conv.AssistantID, conv.CreatedAt, conv.UpdatedAt)
 	return err
 }
 
 // GetConversationsByUserID 获取用户的对话列表
 func (r *Repository) GetConversationsByUserID(userID string) ([]Conversation, error) {
 	query := `
-		SELECT id, user_id, title, model, settings, created_at, updated_at
+		SELECT id, user_id, title, model, settings, active_leaf_id, assistant_id, folder_id, pinned, archived_at, created_at, updated_at
 		FROM conversations 
 		WHERE user_id = $1 
 		ORDER BY updated_at DESC`
@@ -73,15 +94,22 @@ func (r *Repository) GetConversationsByUserID(userID string) ([]Conversation, er
 	for rows.Next() {
 		var conv Conversation
 		var settingsJSON []byte
+		var activeLeafID, assistantID, folderID sql.NullString
+		var archivedAt sql.NullTime
 
 		err := rows.Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.Model,
-			&settingsJSON, &conv.CreatedAt, &conv.UpdatedAt)
+			&settingsJSON, &activeLeafID, &assistantID, &folderID, &conv.Pinned, &archivedAt,
+			&conv.CreatedAt, &conv.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
+		conv.ActiveLeafID = nullStringPtr(activeLeafID)
+		conv.AssistantID = nullStringPtr(assistantID)
+		conv.FolderID = nullStringPtr(folderID)
+		conv.ArchivedAt = nullTimePtr(archivedAt)
 
 		if err := json.Unmarshal(settingsJSON, &conv.Settings); err != nil {
-			conv.Settings = make(map[string]interface{})
+			conv.Settings = ConversationSettings{}
 		}
 
 		conversations = append(conversations, conv)
@@ -93,21 +121,28 @@ func (r *Repository) GetConversationsByUserID(userID string) ([]Conversation, er
 // GetConversationByID 获取对话详情
 func (r *Repository) GetConversationByID(id string) (*Conversation, error) {
 	query := `
-		SELECT id, user_id, title, model, settings, created_at, updated_at
+		SELECT id, user_id, title, model, settings, active_leaf_id, assistant_id, folder_id, pinned, archived_at, created_at, updated_at
 		FROM conversations 
 		WHERE id = $1`
 
 	var conv Conversation
 	var settingsJSON []byte
+	var activeLeafID, assistantID, folderID sql.NullString
+	var archivedAt sql.NullTime
 
 	err := r.db.QueryRow(query, id).Scan(&conv.ID, &conv.UserID, &conv.Title, 
-		&conv.Model, &settingsJSON, &conv.CreatedAt, &conv.UpdatedAt)
+		&conv.Model, &settingsJSON, &activeLeafID, &assistantID, &folderID, &conv.Pinned, &archivedAt,
+		&conv.CreatedAt, &conv.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	conv.ActiveLeafID = nullStringPtr(activeLeafID)
+	conv.AssistantID = nullStringPtr(assistantID)
+	conv.FolderID = nullStringPtr(folderID)
+	conv.ArchivedAt = nullTimePtr(archivedAt)
 
 	if err := json.Unmarshal(settingsJSON, &conv.Settings); err != nil {
-		conv.Settings = make(map[string]interface{})
+		conv.Settings = ConversationSettings{}
 	}
 
 	return &conv, nil
@@ -122,14 +157,32 @@ func (r *Repository) UpdateConversation(conv *Conversation) error {
 
 	query := `
 		UPDATE conversations 
-		SET title = $2, model = $3, settings = $4, updated_at = $5
+		SET title = $2, model = $3, settings = $4, folder_id = $5, pinned = $6, archived_at = $7, updated_at = $8
 		WHERE id = $1`
 
 	_, err = r.db.Exec(query, conv.ID, conv.Title, conv.Model, 
-		settingsJSON, conv.UpdatedAt)
+		settingsJSON, conv.FolderID, conv.Pinned, conv.ArchivedAt, conv.UpdatedAt)
+	return err
+}
+
+// SetActiveLeaf 设置对话当前分支的叶子消息，并更新对话的最后更新时间
+func (r *Repository) SetActiveLeaf(conversationID, messageID string) error {
+	query := `UPDATE conversations SET active_leaf_id = $2, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(query, conversationID, messageID)
 	return err
 }
 
+// UpdateConversationTitleIfUnchanged 仅当标题仍为expected（不区分大小写）时更新，避免覆盖用户在此期间手动修改的标题
+func (r *Repository) UpdateConversationTitleIfUnchanged(conversationID, expected, title string) (bool, error) {
+	query := `UPDATE conversations SET title = $3, updated_at = NOW() WHERE id = $1 AND LOWER(TRIM(title)) = LOWER($2)`
+	result, err := r.db.Exec(query, conversationID, expected, title)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
 // DeleteConversation 删除对话
 func (r *Repository) DeleteConversation(id string) error {
 	query := `DELETE FROM conversations WHERE id = $1`
@@ -145,21 +198,21 @@ func (r *Repository) CreateMessage(msg *Message) error {
 	}
 
 	query := `
-		INSERT INTO messages (id, conversation_id, role, content, artifacts, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO messages (id, conversation_id, parent_id, role, content, model, finish_reason, artifacts, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
-	_, err = r.db.Exec(query, msg.ID, msg.ConversationID, msg.Role, 
-		msg.Content, metadataJSON, msg.CreatedAt)
+	_, err = r.db.Exec(query, msg.ID, msg.ConversationID, msg.ParentID, msg.Role, 
+		msg.Content, msg.Model, msg.FinishReason, metadataJSON, msg.CreatedAt)
 	return err
 }
 
 // GetMessagesByConversationID 获取对话的消息列表
 func (r *Repository) GetMessagesByConversationID(conversationID string) ([]Message, error) {
 	query := `
-		SELECT id, conversation_id, role, content, artifacts, created_at
+		SELECT id, conversation_id, parent_id, role, content, COALESCE(model, ''), COALESCE(finish_reason, ''), artifacts, created_at
 		FROM messages 
 		WHERE conversation_id = $1 
-		ORDER BY created_at ASC`
+		ORDER BY created_at ASC, id ASC`
 
 	rows, err := r.db.Query(query, conversationID)
 	if err != nil {
@@ -171,12 +224,14 @@ func (r *Repository) GetMessagesByConversationID(conversationID string) ([]Messa
 	for rows.Next() {
 		var msg Message
 		var metadataJSON []byte
+		var parentID sql.NullString
 
-		err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, 
-			&msg.Content, &metadataJSON, &msg.CreatedAt)
+		err := rows.Scan(&msg.ID, &msg.ConversationID, &parentID, &msg.Role, 
+			&msg.Content, &msg.Model, &msg.FinishReason, &metadataJSON, &msg.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		msg.ParentID = nullStringPtr(parentID)
 
 		if err := json.Unmarshal(metadataJSON, &msg.Artifacts); err != nil {
 			msg.Artifacts = make(map[string]interface{})
@@ -188,6 +243,43 @@ func (r *Repository) GetMessagesByConversationID(conversationID string) ([]Messa
 	return messages, nil
 }
 
+// GetMessageByID 获取单条消息
+func (r *Repository) GetMessageByID(id string) (*Message, error) {
+	query := `
+		SELECT id, conversation_id, parent_id, role, content, COALESCE(model, ''), COALESCE(finish_reason, ''), artifacts, created_at
+		FROM messages 
+		WHERE id = $1`
+
+	var msg Message
+	var metadataJSON []byte
+	var parentID sql.NullString
+
+	err := r.db.QueryRow(query, id).Scan(&msg.ID, &msg.ConversationID, &parentID, &msg.Role,
+		&msg.Content, &msg.Model, &msg.FinishReason, &metadataJSON, &msg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.ParentID = nullStringPtr(parentID)
+
+	if err := json.Unmarshal(metadataJSON, &msg.Artifacts); err != nil {
+		msg.Artifacts = make(map[string]interface{})
+	}
+
+	return &msg, nil
+}
+
+// UpdateMessageArtifacts 更新消息的artifacts字段
+func (r *Repository) UpdateMessageArtifacts(msg *Message) error {
+	artifactsJSON, err := json.Marshal(msg.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE messages SET artifacts = $2 WHERE id = $1`
+	_, err = r.db.Exec(query, msg.ID, artifactsJSON)
+	return err
+}
+
 // DeleteMessage 删除消息
 func (r *Repository) DeleteMessage(id string) error {
 	query := `DELETE FROM messages WHERE id = $1`
@@ -203,20 +295,36 @@ func NewConversation(userID, title, model string) *Conversation {
 		UserID:    userID,
 		Title:     title,
 		Model:     model,
-		Settings:  make(map[string]interface{}),
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 }
 
-// NewMessage 创建新消息实例
-func NewMessage(conversationID, role, content string) *Message {
+// NewMessage 创建新消息实例，parentID为nil表示对话的根消息
+func NewMessage(conversationID string, parentID *string, role, content string) *Message {
 	return &Message{
 		ID:             uuid.New().String(),
 		ConversationID: conversationID,
+		ParentID:       parentID,
 		Role:           role,
 		Content:        content,
 		Artifacts:      make(map[string]interface{}),
 		CreatedAt:      time.Now(),
 	}
-}
\ No newline at end of file
+}
+
+// nullTimePtr 将可空时间转换为指针
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// nullStringPtr 将可空字符串转换为指针
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,630 @@
+package chat
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxFolderNameRunes    = 100
+	maxFolderDepth        = 5
+	maxTagRunes           = 50
+	maxTagsPerRequest     = 20
+	maxBulkConversations  = 500
+	folderFilterUnfiled   = "none"
+	archivedFilterAll     = "all"
+	archivedFilterOnly    = "true"
+	archivedFilterExclude = "false"
+)
+
+// 批量操作类型
+const (
+	BulkMove      = "move"
+	BulkTag       = "tag"
+	BulkUntag     = "untag"
+	BulkPin       = "pin"
+	BulkUnpin     = "unpin"
+	BulkArchive   = "archive"
+	BulkUnarchive = "unarchive"
+	BulkDelete    = "delete"
+)
+
+// Folder 对话文件夹，parent_id为空表示顶层文件夹，删除文件夹会级联删除子文件夹，其中的对话移出文件夹
+type Folder struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	ParentID  *string   `json:"parent_id" db:"parent_id"`
+	Name      string    `json:"name" db:"name"`
+	SortNum   int       `json:"sort_num" db:"sort_num"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FolderRequest 创建或更新文件夹的请求，更新时未提供的字段保持不变
+type FolderRequest struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parent_id"`
+	SortNum  *int    `json:"sort_num"`
+}
+
+// TagCount 标签及使用次数
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// BulkRequest 批量操作请求
+type BulkRequest struct {
+	ConversationIDs []string `json:"conversation_ids" binding:"required"`
+	Action          string   `json:"action" binding:"required"`
+	FolderID        *string  `json:"folder_id"`
+	Tags            []string `json:"tags"`
+}
+
+// GetFolders 获取用户的全部文件夹（扁平列表，客户端按parent_id组装树）
+func (s *Service) GetFolders(userID string) ([]Folder, error) {
+	folders, err := s.repo.GetFoldersByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders: %w", err)
+	}
+	return folders, nil
+}
+
+// CreateFolder 创建文件夹
+func (s *Service) CreateFolder(userID string, req FolderRequest) (*Folder, error) {
+	if req.Name == nil {
+		return nil, fmt.Errorf("invalid folder: name is required")
+	}
+
+	now := time.Now()
+	folder := &Folder{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		ParentID:  emptyToNil(req.ParentID),
+		Name:      strings.TrimSpace(*req.Name),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.SortNum != nil {
+		folder.SortNum = *req.SortNum
+	}
+	if err := s.validateFolder(folder); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateFolder(folder); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+	return folder, nil
+}
+
+// UpdateFolder 重命名、移动或调整文件夹顺序
+func (s *Service) UpdateFolder(folderID, userID string, req FolderRequest) (*Folder, error) {
+	folder, err := s.getOwnedFolder(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		folder.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ParentID != nil {
+		folder.ParentID = emptyToNil(req.ParentID)
+	}
+	if req.SortNum != nil {
+		folder.SortNum = *req.SortNum
+	}
+	if err := s.validateFolder(folder); err != nil {
+		return nil, err
+	}
+
+	folder.UpdatedAt = time.Now()
+	if err := s.repo.UpdateFolder(folder); err != nil {
+		return nil, fmt.Errorf("failed to update folder: %w", err)
+	}
+	return folder, nil
+}
+
+// DeleteFolder 删除文件夹及其子文件夹，其中的对话移出文件夹
+func (s *Service) DeleteFolder(folderID, userID string) error {
+	if _, err := s.getOwnedFolder(folderID, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteFolder(folderID); err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return nil
+}
+
+// GetTags 获取用户使用过的标签及对话数量
+func (s *Service) GetTags(userID string) ([]TagCount, error) {
+	tags, err := s.repo.GetTagCounts(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	return tags, nil
+}
+
+// RenameTag 重命名标签（与已有标签同名时合并）
+func (s *Service) RenameTag(userID, tag, newTag string) error {
+	tags, err := normalizeTags([]string{newTag})
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("invalid tag: name is required")
+	}
+	if err := s.repo.RenameTag(userID, tag, tags[0]); err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	return nil
+}
+
+// DeleteTag 从用户的所有对话中移除标签
+func (s *Service) DeleteTag(userID, tag string) error {
+	if err := s.repo.DeleteTag(userID, tag); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return nil
+}
+
+// BulkUpdate 对多个对话执行同一操作，只作用于属于该用户的对话，返回受影响的对话数
+func (s *Service) BulkUpdate(userID string, req BulkRequest) (int64, error) {
+	if len(req.ConversationIDs) == 0 {
+		return 0, fmt.Errorf("invalid request: conversation_ids is required")
+	}
+	if len(req.ConversationIDs) > maxBulkConversations {
+		return 0, fmt.Errorf("invalid request: at most %d conversations per request", maxBulkConversations)
+	}
+	for _, id := range req.ConversationIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return 0, fmt.Errorf("invalid conversation id: %s", id)
+		}
+	}
+
+	var affected int64
+	var err error
+	switch req.Action {
+	case BulkMove:
+		folderID := emptyToNil(req.FolderID)
+		if folderID != nil {
+			if _, err := s.getOwnedFolder(*folderID, userID); err != nil {
+				return 0, err
+			}
+		}
+		affected, err = s.repo.BulkSetFolder(userID, req.ConversationIDs, folderID)
+	case BulkTag, BulkUntag:
+		tags, tagErr := normalizeTags(req.Tags)
+		if tagErr != nil {
+			return 0, tagErr
+		}
+		if len(tags) == 0 {
+			return 0, fmt.Errorf("invalid request: tags is required")
+		}
+		if req.Action == BulkTag {
+			affected, err = s.repo.BulkAddTags(userID, req.ConversationIDs, tags)
+		} else {
+			affected, err = s.repo.BulkRemoveTags(userID, req.ConversationIDs, tags)
+		}
+	case BulkPin, BulkUnpin:
+		affected, err = s.repo.BulkSetPinned(userID, req.ConversationIDs, req.Action == BulkPin)
+	case BulkArchive, BulkUnarchive:
+		affected, err = s.repo.BulkSetArchived(userID, req.ConversationIDs, req.Action == BulkArchive)
+	case BulkDelete:
+		affected, err = s.repo.BulkDelete(userID, req.ConversationIDs)
+	default:
+		return 0, fmt.Errorf("invalid action: %s", req.Action)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s conversations: %w", req.Action, err)
+	}
+	if affected > 0 {
+		s.publishBulk(userID, req)
+	}
+	return affected, nil
+}
+
+// publishBulk 为批量操作涉及的每个对话推送事件，客户端据action刷新对应对话
+// 请求中不属于该用户的ID未被修改，事件只推送给该用户本人，客户端忽略未知的对话即可
+func (s *Service) publishBulk(userID string, req BulkRequest) {
+	for _, id := range req.ConversationIDs {
+		if req.Action == BulkDelete {
+			s.publish(userID, EventConversationDeleted, id, nil)
+			continue
+		}
+		s.publish(userID, EventConversationUpdated, id, map[string]interface{}{"action": req.Action})
+	}
+}
+
+// getOwnedFolder 获取文件夹并检查归属
+func (s *Service) getOwnedFolder(folderID, userID string) (*Folder, error) {
+	if _, err := uuid.Parse(folderID); err != nil {
+		return nil, fmt.Errorf("invalid folder id: %s", folderID)
+	}
+	folder, err := s.repo.GetFolderByID(folderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("folder not found")
+		}
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	if folder.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to folder")
+	}
+	return folder, nil
+}
+
+// validateFolder 校验名称、父文件夹归属，并防止移动到自身子树下或超过最大层级
+func (s *Service) validateFolder(folder *Folder) error {
+	if folder.Name == "" || utf8.RuneCountInString(folder.Name) > maxFolderNameRunes {
+		return fmt.Errorf("invalid folder: name must be 1-%d characters", maxFolderNameRunes)
+	}
+	if folder.ParentID == nil {
+		return nil
+	}
+	if _, err := s.getOwnedFolder(*folder.ParentID, folder.UserID); err != nil {
+		return err
+	}
+
+	folders, err := s.repo.GetFoldersByUserID(folder.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get folders: %w", err)
+	}
+	parents := make(map[string]*string, len(folders))
+	for _, f := range folders {
+		parents[f.ID] = f.ParentID
+	}
+	parents[folder.ID] = folder.ParentID
+
+	// 从新位置向上回溯，检查环路并计算所在层级
+	depth := 1
+	for id := folder.ParentID; id != nil; id = parents[*id] {
+		if *id == folder.ID {
+			return fmt.Errorf("invalid folder: cannot move a folder into itself")
+		}
+		depth++
+	}
+
+	// 移动已有文件夹时，子树随之下移，需要加上子树高度
+	height := 0
+	for _, f := range folders {
+		steps := 0
+		for id := &f.ID; id != nil; id = parents[*id] {
+			if *id == folder.ID {
+				if steps > height {
+					height = steps
+				}
+				break
+			}
+			steps++
+		}
+	}
+	if depth+height > maxFolderDepth {
+		return fmt.Errorf("invalid folder: folders can be nested at most %d levels", maxFolderDepth)
+	}
+	return nil
+}
+
+// parseTags 解析更新请求中的标签数组
+func parseTags(value interface{}) ([]string, error) {
+	if value == nil {
+		return []string{}, nil
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid tags")
+	}
+	raw := make([]string, 0, len(values))
+	for _, v := range values {
+		tag, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid tags")
+		}
+		raw = append(raw, tag)
+	}
+	return normalizeTags(raw)
+}
+
+// normalizeTags 去除首尾空白、去重并校验长度
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTagsPerRequest {
+		return nil, fmt.Errorf("invalid tags: at most %d tags are allowed", maxTagsPerRequest)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagRunes {
+			return nil, fmt.Errorf("invalid tag: must be at most %d characters", maxTagRunes)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// emptyToNil 空字符串视为未设置
+func emptyToNil(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
+
+// CreateFolder 保存文件夹
+func (r *Repository) CreateFolder(folder *Folder) error {
+	query := `
+		INSERT INTO folders (id, user_id, parent_id, name, sort_num, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.Exec(query, folder.ID, folder.UserID, folder.ParentID, folder.Name,
+		folder.SortNum, folder.CreatedAt, folder.UpdatedAt)
+	return err
+}
+
+// GetFolderByID 获取文件夹
+func (r *Repository) GetFolderByID(id string) (*Folder, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, sort_num, created_at, updated_at
+		FROM folders
+		WHERE id = $1`
+
+	var folder Folder
+	var parentID sql.NullString
+	err := r.db.QueryRow(query, id).Scan(&folder.ID, &folder.UserID, &parentID, &folder.Name,
+		&folder.SortNum, &folder.CreatedAt, &folder.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	folder.ParentID = nullStringPtr(parentID)
+	return &folder, nil
+}
+
+// GetFoldersByUserID 获取用户的文件夹
+func (r *Repository) GetFoldersByUserID(userID string) ([]Folder, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, sort_num, created_at, updated_at
+		FROM folders
+		WHERE user_id = $1
+		ORDER BY sort_num ASC, name ASC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []Folder{}
+	for rows.Next() {
+		var folder Folder
+		var parentID sql.NullString
+		err := rows.Scan(&folder.ID, &folder.UserID, &parentID, &folder.Name,
+			&folder.SortNum, &folder.CreatedAt, &folder.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		folder.ParentID = nullStringPtr(parentID)
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+// UpdateFolder 更新文件夹
+func (r *Repository) UpdateFolder(folder *Folder) error {
+	query := `UPDATE folders SET parent_id = $2, name = $3, sort_num = $4, updated_at = $5 WHERE id = $1`
+	_, err := r.db.Exec(query, folder.ID, folder.ParentID, folder.Name, folder.SortNum, folder.UpdatedAt)
+	return err
+}
+
+// DeleteFolder 删除文件夹
+func (r *Repository) DeleteFolder(id string) error {
+	query := `DELETE FROM folders WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// GetConversationTags 获取对话的标签
+func (r *Repository) GetConversationTags(conversationID string) ([]string, error) {
+	query := `SELECT tag FROM conversation_tags WHERE conversation_id = $1 ORDER BY tag`
+
+	rows, err := r.db.Query(query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetConversationTags 替换对话的标签
+func (r *Repository) SetConversationTags(conversationID string, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM conversation_tags WHERE conversation_id = $1`, conversationID); err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		query := `
+			INSERT INTO conversation_tags (conversation_id, tag)
+			SELECT $1, UNNEST($2::text[])
+			ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, conversationID, pq.Array(tags)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTagCounts 获取用户的标签及对话数量
+func (r *Repository) GetTagCounts(userID string) ([]TagCount, error) {
+	query := `
+		SELECT t.tag, COUNT(*)
+		FROM conversation_tags t
+		JOIN conversations c ON c.id = t.conversation_id
+		WHERE c.user_id = $1
+		GROUP BY t.tag
+		ORDER BY t.tag`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// RenameTag 重命名用户的标签，目标标签已存在的对话直接合并
+func (r *Repository) RenameTag(userID, tag, newTag string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO conversation_tags (conversation_id, tag)
+		SELECT t.conversation_id, $3
+		FROM conversation_tags t
+		JOIN conversations c ON c.id = t.conversation_id
+		WHERE c.user_id = $1 AND t.tag = $2
+		ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(insert, userID, tag, newTag); err != nil {
+		return err
+	}
+	if tag != newTag {
+		if err := deleteUserTag(tx, userID, tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTag 删除用户的标签
+func (r *Repository) DeleteTag(userID, tag string) error {
+	return deleteUserTag(r.db, userID, tag)
+}
+
+// execer 兼容 *sql.DB 与 *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// deleteUserTag 删除用户所有对话上的指定标签
+func deleteUserTag(db execer, userID, tag string) error {
+	query := `
+		DELETE FROM conversation_tags t
+		USING conversations c
+		WHERE c.id = t.conversation_id AND c.user_id = $1 AND t.tag = $2`
+	_, err := db.Exec(query, userID, tag)
+	return err
+}
+
+// BulkSetFolder 批量移动对话到文件夹，folderID为nil表示移出文件夹
+func (r *Repository) BulkSetFolder(userID string, conversationIDs []string, folderID *string) (int64, error) {
+	query := `UPDATE conversations SET folder_id = $3 WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	return execAffected(r.db, query, userID, pq.Array(conversationIDs), folderID)
+}
+
+// BulkSetPinned 批量置顶或取消置顶
+func (r *Repository) BulkSetPinned(userID string, conversationIDs []string, pinned bool) (int64, error) {
+	query := `UPDATE conversations SET pinned = $3 WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	return execAffected(r.db, query, userID, pq.Array(conversationIDs), pinned)
+}
+
+// BulkSetArchived 批量归档或取消归档，已归档的对话保留原归档时间
+func (r *Repository) BulkSetArchived(userID string, conversationIDs []string, archived bool) (int64, error) {
+	query := `UPDATE conversations SET archived_at = NULL WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	if archived {
+		query = `UPDATE conversations SET archived_at = COALESCE(archived_at, NOW()) WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	}
+	return execAffected(r.db, query, userID, pq.Array(conversationIDs))
+}
+
+// BulkDelete 批量删除对话
+func (r *Repository) BulkDelete(userID string, conversationIDs []string) (int64, error) {
+	query := `DELETE FROM conversations WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	return execAffected(r.db, query, userID, pq.Array(conversationIDs))
+}
+
+// BulkAddTags 批量添加标签，返回涉及的对话数
+func (r *Repository) BulkAddTags(userID string, conversationIDs []string, tags []string) (int64, error) {
+	query := `
+		WITH owned AS (
+			SELECT id FROM conversations WHERE user_id = $1 AND id = ANY($2::uuid[])
+		), inserted AS (
+			INSERT INTO conversation_tags (conversation_id, tag)
+			SELECT owned.id, tag FROM owned, UNNEST($3::text[]) AS tag
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM owned`
+
+	var affected int64
+	err := r.db.QueryRow(query, userID, pq.Array(conversationIDs), pq.Array(tags)).Scan(&affected)
+	return affected, err
+}
+
+// BulkRemoveTags 批量移除标签，返回涉及的对话数
+func (r *Repository) BulkRemoveTags(userID string, conversationIDs []string, tags []string) (int64, error) {
+	query := `
+		DELETE FROM conversation_tags t
+		USING conversations c
+		WHERE c.id = t.conversation_id AND c.user_id = $1 AND c.id = ANY($2::uuid[]) AND t.tag = ANY($3::text[])`
+
+	result, err := r.db.Exec(query, userID, pq.Array(conversationIDs), pq.Array(tags))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil || deleted == 0 {
+		return 0, err
+	}
+
+	var affected int64
+	countQuery := `SELECT COUNT(*) FROM conversations WHERE user_id = $1 AND id = ANY($2::uuid[])`
+	err = r.db.QueryRow(countQuery, userID, pq.Array(conversationIDs)).Scan(&affected)
+	return affected, err
+}
+
+// execAffected 执行语句并返回受影响的行数
+func execAffected(db execer, query string, args ...interface{}) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
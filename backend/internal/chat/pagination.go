@@ -0,0 +1,474 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultConversationPageSize = 20
+	maxConversationPageSize     = 100
+	defaultMessagePageSize      = 50
+	maxMessagePageSize          = 200
+	previewRunes                = 120
+)
+
+// 对话列表排序字段
+const (
+	SortUpdatedAt = "updated_at"
+	SortCreatedAt = "created_at"
+)
+
+// ConversationListOptions 对话列表的分页、排序与过滤参数
+type ConversationListOptions struct {
+	Limit       int
+	Cursor      string
+	Sort        string
+	Ascending   bool
+	Model       string
+	AssistantID string
+	// FolderID 为"none"时只返回不在任何文件夹中的对话
+	FolderID string
+	Tag      string
+	Pinned   *bool
+	// Archived 默认（空或"false"）排除已归档对话，"true"只返回已归档，"all"返回全部
+	Archived string
+}
+
+// ConversationSummary 对话列表的轻量投影，不包含设置，附带最后一条消息的预览与消息数
+type ConversationSummary struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Model        string          `json:"model"`
+	AssistantID  *string         `json:"assistant_id"`
+	FolderID     *string         `json:"folder_id"`
+	Pinned       bool            `json:"pinned"`
+	ArchivedAt   *time.Time      `json:"archived_at"`
+	Tags         []string        `json:"tags"`
+	ActiveLeafID *string         `json:"active_leaf_id"`
+	MessageCount int             `json:"message_count"`
+	LastMessage  *MessagePreview `json:"last_message"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// MessagePreview 消息预览
+type MessagePreview struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationPage 对话列表分页结果，NextCursor为空表示没有更多
+type ConversationPage struct {
+	Conversations []ConversationSummary `json:"conversations"`
+	NextCursor    string                `json:"next_cursor,omitempty"`
+	HasMore       bool                  `json:"has_more"`
+}
+
+// MessagePageOptions 活动路径消息分页参数，Before与After为消息ID，最多指定一个
+type MessagePageOptions struct {
+	Limit  int
+	Before string
+	After  string
+}
+
+// MessagePage 消息分页结果，消息按对话顺序排列
+// HasMore表示翻页方向上（Before/默认为更早，After为更新）还有更多消息
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"`
+}
+
+// conversationCursor 对话列表游标，记录上一页最后一条的排序键
+type conversationCursor struct {
+	Sort string    `json:"s"`
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// encodeConversationCursor 编码游标
+func encodeConversationCursor(cursor conversationCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeConversationCursor 解码游标，并校验排序字段与当前请求一致
+func decodeConversationCursor(value, sort string) (*conversationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor conversationCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("invalid cursor: sort order changed")
+	}
+	return &cursor, nil
+}
+
+// ListConversations 分页获取用户的对话列表，按排序字段与ID保证顺序稳定
+func (s *Service) ListConversations(userID string, opts ConversationListOptions) (*ConversationPage, error) {
+	switch opts.Sort {
+	case "":
+		opts.Sort = SortUpdatedAt
+	case SortUpdatedAt, SortCreatedAt:
+	default:
+		return nil, fmt.Errorf("invalid sort: %s", opts.Sort)
+	}
+	switch opts.Archived {
+	case "", archivedFilterExclude, archivedFilterOnly, archivedFilterAll:
+	default:
+		return nil, fmt.Errorf("invalid archived: must be true, false or all")
+	}
+	if opts.FolderID != "" && opts.FolderID != folderFilterUnfiled {
+		if _, err := uuid.Parse(opts.FolderID); err != nil {
+			return nil, fmt.Errorf("invalid folder id: %s", opts.FolderID)
+		}
+	}
+	opts.Tag = strings.TrimSpace(opts.Tag)
+	opts.Limit = clampPageSize(opts.Limit, defaultConversationPageSize, maxConversationPageSize)
+
+	var cursor *conversationCursor
+	if opts.Cursor != "" {
+		var err error
+		if cursor, err = decodeConversationCursor(opts.Cursor, opts.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	summaries, err := s.repo.ListConversationSummaries(userID, opts, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	page := &ConversationPage{Conversations: summaries}
+	if len(summaries) > opts.Limit {
+		page.Conversations = summaries[:opts.Limit]
+		page.HasMore = true
+
+		last := page.Conversations[opts.Limit-1]
+		next := conversationCursor{Sort: opts.Sort, Time: last.UpdatedAt, ID: last.ID}
+		if opts.Sort == SortCreatedAt {
+			next.Time = last.CreatedAt
+		}
+		page.NextCursor = encodeConversationCursor(next)
+	}
+	return page, nil
+}
+
+// GetMessagePage 分页获取对话活动路径上的消息
+// 默认返回最新的一页；before返回该消息之前的消息，after返回该消息之后的消息
+func (s *Service) GetMessagePage(conversationID string, opts MessagePageOptions) (*MessagePage, error) {
+	if opts.Before != "" && opts.After != "" {
+		return nil, fmt.Errorf("invalid cursor: before and after are mutually exclusive")
+	}
+	opts.Limit = clampPageSize(opts.Limit, defaultMessagePageSize, maxMessagePageSize)
+
+	conv, err := s.repo.GetConversationByID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	var path []Message
+	hasMore := false
+	switch {
+	case opts.Before != "":
+		anchor, err := s.getConversationMessage(conversationID, opts.Before)
+		if err != nil {
+			return nil, err
+		}
+		if anchor.ParentID == nil {
+			return &MessagePage{Messages: []Message{}}, nil
+		}
+		// 从锚点的父消息向上多取一条，用于判断是否还有更早的消息
+		path, err = s.repo.GetAncestors(conversationID, *anchor.ParentID, opts.Limit+1, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(path) > opts.Limit {
+			path = path[1:]
+			hasMore = true
+		}
+
+	case opts.After != "":
+		if _, err := s.getConversationMessage(conversationID, opts.After); err != nil {
+			return nil, err
+		}
+		if conv.ActiveLeafID == nil || *conv.ActiveLeafID == opts.After {
+			return &MessagePage{Messages: []Message{}}, nil
+		}
+		// 从活动叶子回溯到锚点（不含锚点），取靠近锚点的一页
+		path, err = s.repo.GetAncestors(conversationID, *conv.ActiveLeafID, 0, opts.After)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(path) == 0 || path[0].ParentID == nil || *path[0].ParentID != opts.After {
+			// 回溯到根都没有遇到锚点：锚点不在活动路径上
+			return nil, fmt.Errorf("invalid cursor: message is not on the active branch")
+		}
+		if len(path) > opts.Limit {
+			path = path[:opts.Limit]
+			hasMore = true
+		}
+
+	default:
+		if conv.ActiveLeafID == nil {
+			return &MessagePage{Messages: []Message{}}, nil
+		}
+		path, err = s.repo.GetAncestors(conversationID, *conv.ActiveLeafID, opts.Limit+1, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(path) > opts.Limit {
+			path = path[1:]
+			hasMore = true
+		}
+	}
+
+	if err := s.attachPageSiblings(conversationID, path); err != nil {
+		return nil, err
+	}
+	return &MessagePage{Messages: path, HasMore: hasMore}, nil
+}
+
+// getConversationMessage 获取属于指定对话的消息
+func (s *Service) getConversationMessage(conversationID, messageID string) (*Message, error) {
+	msg, err := s.repo.GetMessageByID(messageID)
+	if err != nil || msg.ConversationID != conversationID {
+		return nil, fmt.Errorf("invalid cursor: message does not belong to this conversation")
+	}
+	return msg, nil
+}
+
+// attachPageSiblings 为分页返回的消息填充同级分支ID
+func (s *Service) attachPageSiblings(conversationID string, path []Message) error {
+	if len(path) == 0 {
+		return nil
+	}
+
+	var parentIDs []string
+	includeRoots := false
+	for _, msg := range path {
+		if msg.ParentID == nil {
+			includeRoots = true
+		} else {
+			parentIDs = append(parentIDs, *msg.ParentID)
+		}
+	}
+
+	siblings, err := s.repo.GetSiblingRefs(conversationID, parentIDs, includeRoots)
+	if err != nil {
+		return fmt.Errorf("failed to get message branches: %w", err)
+	}
+	attachSiblings(path, siblings)
+	return nil
+}
+
+// clampPageSize 规范分页大小
+func clampPageSize(limit, defaultSize, maxSize int) int {
+	if limit <= 0 {
+		return defaultSize
+	}
+	if limit > maxSize {
+		return maxSize
+	}
+	return limit
+}
+
+// ListConversationSummaries 按游标查询对话摘要，多取一条用于判断是否还有下一页
+func (r *Repository) ListConversationSummaries(userID string, opts ConversationListOptions, cursor *conversationCursor) ([]ConversationSummary, error) {
+	// 排序字段来自白名单，可以直接拼接
+	sortColumn := "c." + opts.Sort
+	direction, comparator := "DESC", "<"
+	if opts.Ascending {
+		direction, comparator = "ASC", ">"
+	}
+
+	args := []interface{}{userID}
+	conditions := []string{"c.user_id = $1"}
+	if opts.Model != "" {
+		args = append(args, opts.Model)
+		conditions = append(conditions, fmt.Sprintf("c.model = $%d", len(args)))
+	}
+	if opts.AssistantID != "" {
+		args = append(args, opts.AssistantID)
+		conditions = append(conditions, fmt.Sprintf("c.assistant_id = $%d", len(args)))
+	}
+	switch opts.FolderID {
+	case "":
+	case folderFilterUnfiled:
+		conditions = append(conditions, "c.folder_id IS NULL")
+	default:
+		args = append(args, opts.FolderID)
+		conditions = append(conditions, fmt.Sprintf("c.folder_id = $%d", len(args)))
+	}
+	if opts.Tag != "" {
+		args = append(args, opts.Tag)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM conversation_tags t WHERE t.conversation_id = c.id AND t.tag = $%d)", len(args)))
+	}
+	if opts.Pinned != nil {
+		args = append(args, *opts.Pinned)
+		conditions = append(conditions, fmt.Sprintf("c.pinned = $%d", len(args)))
+	}
+	switch opts.Archived {
+	case archivedFilterAll:
+	case archivedFilterOnly:
+		conditions = append(conditions, "c.archived_at IS NOT NULL")
+	default:
+		conditions = append(conditions, "c.archived_at IS NULL")
+	}
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, c.id) %s ($%d, $%d)", sortColumn, comparator, len(args)-1, len(args)))
+	}
+	args = append(args, opts.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT c.id, COALESCE(c.title, ''), COALESCE(c.model, ''), c.assistant_id, c.active_leaf_id,
+			c.folder_id, COALESCE(c.pinned, false), c.archived_at,
+			ARRAY(SELECT t.tag FROM conversation_tags t WHERE t.conversation_id = c.id ORDER BY t.tag),
+			c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id),
+			lm.id, lm.role, LEFT(lm.content, %d), lm.created_at
+		FROM conversations c
+		LEFT JOIN messages lm ON lm.id = c.active_leaf_id
+		WHERE %s
+		ORDER BY %s %s, c.id %s
+		LIMIT $%d`,
+		previewRunes+1, strings.Join(conditions, " AND "), sortColumn, direction, direction, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []ConversationSummary{}
+	for rows.Next() {
+		var summary ConversationSummary
+		var assistantID, activeLeafID, folderID, lastID, lastRole, lastContent sql.NullString
+		var archivedAt, lastCreatedAt sql.NullTime
+
+		summary.Tags = []string{}
+		err := rows.Scan(&summary.ID, &summary.Title, &summary.Model, &assistantID, &activeLeafID,
+			&folderID, &summary.Pinned, &archivedAt, pq.Array(&summary.Tags),
+			&summary.CreatedAt, &summary.UpdatedAt, &summary.MessageCount,
+			&lastID, &lastRole, &lastContent, &lastCreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		summary.AssistantID = nullStringPtr(assistantID)
+		summary.ActiveLeafID = nullStringPtr(activeLeafID)
+		summary.FolderID = nullStringPtr(folderID)
+		summary.ArchivedAt = nullTimePtr(archivedAt)
+		if lastID.Valid {
+			summary.LastMessage = &MessagePreview{
+				ID:        lastID.String,
+				Role:      lastRole.String,
+				Content:   previewText(lastContent.String),
+				CreatedAt: lastCreatedAt.Time,
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetAncestors 从指定消息沿parent_id向上回溯，按对话顺序返回
+// limit>0时最多返回limit条；stopID非空时遇到该消息即停止（不包含该消息）
+func (r *Repository) GetAncestors(conversationID, fromID string, limit int, stopID string) ([]Message, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT id, parent_id, 1 AS depth
+			FROM messages
+			WHERE id = $1 AND conversation_id = $2 AND id::text <> $4
+			UNION ALL
+			SELECT m.id, m.parent_id, p.depth + 1
+			FROM messages m
+			JOIN path p ON m.id = p.parent_id
+			WHERE ($3 = 0 OR p.depth < $3) AND m.id::text <> $4
+		)
+		SELECT m.id, m.conversation_id, m.parent_id, m.role, m.content, COALESCE(m.model, ''), COALESCE(m.finish_reason, ''), m.artifacts, m.created_at
+		FROM path p
+		JOIN messages m ON m.id = p.id
+		ORDER BY p.depth DESC`
+
+	rows, err := r.db.Query(query, fromID, conversationID, limit, stopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var msg Message
+		var metadataJSON []byte
+		var parentID sql.NullString
+
+		err := rows.Scan(&msg.ID, &msg.ConversationID, &parentID, &msg.Role,
+			&msg.Content, &msg.Model, &msg.FinishReason, &metadataJSON, &msg.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		msg.ParentID = nullStringPtr(parentID)
+
+		if err := json.Unmarshal(metadataJSON, &msg.Artifacts); err != nil {
+			msg.Artifacts = make(map[string]interface{})
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// GetSiblingRefs 获取指定父消息下所有子消息的ID与父ID（按创建时间排序），用于计算分支
+func (r *Repository) GetSiblingRefs(conversationID string, parentIDs []string, includeRoots bool) ([]Message, error) {
+	query := `
+		SELECT id, parent_id
+		FROM messages
+		WHERE conversation_id = $1 AND (parent_id = ANY($2::uuid[]) OR ($3 AND parent_id IS NULL))
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.Query(query, conversationID, pq.Array(parentIDs), includeRoots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []Message
+	for rows.Next() {
+		var msg Message
+		var parentID sql.NullString
+		if err := rows.Scan(&msg.ID, &parentID); err != nil {
+			return nil, err
+		}
+		msg.ParentID = nullStringPtr(parentID)
+		refs = append(refs, msg)
+	}
+
+	return refs, rows.Err()
+}
+
+// previewText 截取预览文本
+func previewText(content string) string {
+	content = strings.TrimSpace(content)
+	if utf8.RuneCountInString(content) <= previewRunes {
+		return content
+	}
+	return string([]rune(content)[:previewRunes]) + "…"
+}
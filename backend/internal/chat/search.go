@@ -0,0 +1,319 @@
+package chat
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 100
+	maxTitleResults      = 10
+	maxSearchQueryRunes  = 200
+	snippetContextRunes  = 40
+	defaultTextSearchCfg = "simple"
+
+	// 高亮标记先用控制字符占位，转义HTML后再替换为<mark>，避免消息内容中的HTML被原样返回
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// textSearchConfigPattern 合法的text search配置名（与数据库索引保持一致）
+var textSearchConfigPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SearchFilter 对话检索条件
+type SearchFilter struct {
+	Query  string
+	Model  string
+	Role   string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// MessageSearchResult 消息检索结果，Snippet为HTML转义后的片段，命中词用<mark>包裹
+type MessageSearchResult struct {
+	MessageID         string    `json:"message_id"`
+	ConversationID    string    `json:"conversation_id"`
+	ConversationTitle string    `json:"conversation_title"`
+	Role              string    `json:"role"`
+	Model             string    `json:"model"`
+	Snippet           string    `json:"snippet"`
+	Rank              float64   `json:"rank"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// ConversationSearchResult 标题命中的对话
+type ConversationSearchResult struct {
+	ConversationID string    `json:"conversation_id"`
+	Title          string    `json:"title"`
+	Model          string    `json:"model"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SearchResults 检索结果
+type SearchResults struct {
+	Query         string                     `json:"query"`
+	Total         int                        `json:"total"`
+	Messages      []MessageSearchResult      `json:"messages"`
+	Conversations []ConversationSearchResult `json:"conversations"`
+}
+
+// SetTextSearchConfig 设置全文检索使用的text search配置，需与启动时创建的索引一致
+func (s *Service) SetTextSearchConfig(textSearchConfig string) {
+	if !textSearchConfigPattern.MatchString(textSearchConfig) {
+		fmt.Printf("Warning: invalid text search config %q, using %s\n", textSearchConfig, defaultTextSearchCfg)
+		textSearchConfig = defaultTextSearchCfg
+	}
+	s.textSearchConfig = textSearchConfig
+}
+
+// Search 在用户的所有对话中检索消息内容与对话标题
+// 分词检索（websearch语法）与子串匹配取并集：前者按相关度排序，后者覆盖中文等不以空格分词的语言
+func (s *Service) Search(userID string, filter SearchFilter) (*SearchResults, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" {
+		return nil, fmt.Errorf("invalid query: q is required")
+	}
+	if utf8.RuneCountInString(filter.Query) > maxSearchQueryRunes {
+		return nil, fmt.Errorf("invalid query: must be at most %d characters", maxSearchQueryRunes)
+	}
+	switch filter.Role {
+	case "", "user", "assistant":
+	default:
+		return nil, fmt.Errorf("invalid role: %s", filter.Role)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	textSearchConfig := s.textSearchConfig
+	if textSearchConfig == "" {
+		textSearchConfig = defaultTextSearchCfg
+	}
+
+	messages, total, err := s.repo.SearchMessages(userID, textSearchConfig, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	for i := range messages {
+		messages[i].Snippet = renderSnippet(messages[i].Snippet, filter.Query)
+	}
+
+	conversations := []ConversationSearchResult{}
+	// 标题命中只在第一页返回，且不适用于按角色过滤
+	if filter.Offset == 0 && filter.Role == "" {
+		conversations, err = s.repo.SearchConversationTitles(userID, textSearchConfig, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search conversations: %w", err)
+		}
+		for i := range conversations {
+			conversations[i].Title = renderSnippet(conversations[i].Title, filter.Query)
+		}
+	}
+
+	return &SearchResults{
+		Query:         filter.Query,
+		Total:         total,
+		Messages:      messages,
+		Conversations: conversations,
+	}, nil
+}
+
+// SearchMessages 检索用户对话中的消息，返回当前页结果与总数
+// Snippet返回ts_headline生成的片段；未命中分词时返回原文，由调用方按子串高亮
+func (r *Repository) SearchMessages(userID, textSearchConfig string, filter SearchFilter) ([]MessageSearchResult, int, error) {
+	args := []interface{}{userID, filter.Query, likePattern(filter.Query), headlineOptions}
+	conditions := []string{
+		"c.user_id = $1",
+		fmt.Sprintf("(to_tsvector('%s', m.content) @@ query OR m.content ILIKE $3)", textSearchConfig),
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("m.role = $%d", len(args)))
+	}
+	if filter.Model != "" {
+		args = append(args, filter.Model)
+		conditions = append(conditions, fmt.Sprintf("(m.model = $%[1]d OR (COALESCE(m.model, '') = '' AND c.model = $%[1]d))", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("m.created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("m.created_at < $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT m.id, m.conversation_id, COALESCE(c.title, ''), m.role, COALESCE(NULLIF(m.model, ''), c.model, ''),
+			CASE WHEN to_tsvector('%[1]s', m.content) @@ query
+				THEN ts_headline('%[1]s', m.content, query, $4)
+				ELSE m.content END,
+			ts_rank(to_tsvector('%[1]s', m.content), query) AS rank,
+			m.created_at,
+			COUNT(*) OVER() AS total
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id,
+			websearch_to_tsquery('%[1]s', $2) query
+		WHERE %[2]s
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT $%[3]d OFFSET $%[4]d`,
+		textSearchConfig, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []MessageSearchResult{}
+	total := 0
+	for rows.Next() {
+		var result MessageSearchResult
+		err := rows.Scan(&result.MessageID, &result.ConversationID, &result.ConversationTitle,
+			&result.Role, &result.Model, &result.Snippet, &result.Rank, &result.CreatedAt, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+
+	return results, total, rows.Err()
+}
+
+// SearchConversationTitles 按标题检索用户的对话
+func (r *Repository) SearchConversationTitles(userID, textSearchConfig string, filter SearchFilter) ([]ConversationSearchResult, error) {
+	args := []interface{}{userID, filter.Query, likePattern(filter.Query), headlineOptions}
+	conditions := []string{
+		"user_id = $1",
+		fmt.Sprintf("(to_tsvector('%s', COALESCE(title, '')) @@ query OR title ILIKE $3)", textSearchConfig),
+	}
+	if filter.Model != "" {
+		args = append(args, filter.Model)
+		conditions = append(conditions, fmt.Sprintf("model = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("updated_at < $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id,
+			CASE WHEN to_tsvector('%[1]s', COALESCE(title, '')) @@ query
+				THEN ts_headline('%[1]s', COALESCE(title, ''), query, $4)
+				ELSE COALESCE(title, '') END,
+			COALESCE(model, ''), updated_at
+		FROM conversations, websearch_to_tsquery('%[1]s', $2) query
+		WHERE %[2]s
+		ORDER BY ts_rank(to_tsvector('%[1]s', COALESCE(title, '')), query) DESC, updated_at DESC
+		LIMIT %[3]d`,
+		textSearchConfig, strings.Join(conditions, " AND "), maxTitleResults)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []ConversationSearchResult{}
+	for rows.Next() {
+		var result ConversationSearchResult
+		if err := rows.Scan(&result.ConversationID, &result.Title, &result.Model, &result.UpdatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// headlineOptions ts_headline选项，命中词用占位控制字符包裹
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \"",
+	highlightStart, highlightStop)
+
+// likePattern 构造子串匹配模式，转义LIKE通配符
+func likePattern(query string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(query) + "%"
+}
+
+// renderSnippet 生成可直接渲染的HTML片段
+// 已有ts_headline高亮时直接转义；否则在原文中按子串（忽略大小写）截取上下文并高亮
+func renderSnippet(text, query string) string {
+	if !strings.Contains(text, highlightStart) {
+		text = substringSnippet(text, query)
+	}
+
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+// substringSnippet 以第一次出现的查询串为中心截取片段并插入高亮占位符
+func substringSnippet(text, query string) string {
+	runes := []rune(text)
+	needle := []rune(query)
+
+	index := indexFoldRunes(runes, needle)
+	if index < 0 {
+		if len(runes) > snippetContextRunes*2 {
+			return string(runes[:snippetContextRunes*2]) + "…"
+		}
+		return text
+	}
+
+	start := index - snippetContextRunes
+	prefix := "…"
+	if start <= 0 {
+		start = 0
+		prefix = ""
+	}
+	end := index + len(needle) + snippetContextRunes
+	suffix := "…"
+	if end >= len(runes) {
+		end = len(runes)
+		suffix = ""
+	}
+
+	return prefix + string(runes[start:index]) +
+		highlightStart + string(runes[index:index+len(needle)]) + highlightStop +
+		string(runes[index+len(needle):end]) + suffix
+}
+
+// indexFoldRunes 忽略大小写查找子串，返回rune下标
+func indexFoldRunes(runes, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := 0; i+len(needle) <= len(runes); i++ {
+		matched := true
+		for j, r := range needle {
+			if unicode.ToLower(runes[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
@@ -3,34 +3,133 @@ package chat
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/qicro/qicro/backend/internal/artifact"
+	"github.com/qicro/qicro/backend/internal/knowledge"
 	"github.com/qicro/qicro/backend/internal/llm"
+	"github.com/qicro/qicro/backend/internal/search"
 )
 
+// GenerationOptions 单次生成的覆盖参数，为空时使用对话的设置
+type GenerationOptions struct {
+	Model       string
+	Temperature *float64
+}
+
 // Service 聊天服务
 type Service struct {
-	repo       *Repository
-	llmService *llm.Service
+	repo             *Repository
+	llmService       *llm.Service
+	searchService    *search.Service
+	knowledgeService *knowledge.Service
+	artifactService  *artifact.Service
+	generations      *GenerationRegistry
+	events           *EventLog
+	titleGenerator   TitleGenerator
+	titleModel       string
+	notifier         Notifier
+	textSearchConfig string
 }
 
 // NewService 创建聊天服务
 func NewService(repo *Repository, llmService *llm.Service) *Service {
 	return &Service{
-		repo:       repo,
-		llmService: llmService,
+		repo:        repo,
+		llmService:  llmService,
+		generations: NewGenerationRegistry(nil),
+		events:      NewEventLog(nil),
 	}
 }
 
+// SetSearchService 注入网络搜索服务，未注入时忽略对话的web_search开关
+func (s *Service) SetSearchService(searchService *search.Service) {
+	s.searchService = searchService
+}
+
+// SetKnowledgeService 注入知识库服务，未注入时忽略对话设置中的知识库
+func (s *Service) SetKnowledgeService(knowledgeService *knowledge.Service) {
+	s.knowledgeService = knowledgeService
+}
+
+// SetArtifactService 注入artifact服务，未注入时不解析助手回复中的artifact
+func (s *Service) SetArtifactService(artifactService *artifact.Service) {
+	s.artifactService = artifactService
+}
+
+// SetGenerationRegistry 替换生成任务注册表（例如使用基于Redis的跨实例注册表）
+func (s *Service) SetGenerationRegistry(registry *GenerationRegistry) {
+	s.generations = registry
+}
+
+// CancelGeneration 取消进行中的流式生成，已生成的部分会以cancelled状态保存
+func (s *Service) CancelGeneration(ctx context.Context, generationID, userID string) error {
+	return s.generations.Cancel(ctx, generationID, userID)
+}
+
 // CreateConversation 创建对话
 func (s *Service) CreateConversation(userID, title, model string) (*Conversation, error) {
+	if strings.TrimSpace(title) == "" {
+		title = DefaultConversationTitle
+	}
 	conv := NewConversation(userID, title, model)
 	if err := s.repo.CreateConversation(conv); err != nil {
 		return nil, fmt.Errorf("failed to create conversation: %w", err)
 	}
+	s.publish(userID, EventConversationCreated, conv.ID, conv)
 	return conv, nil
 }
 
+// ConversationPreset 基于预设（如助手）创建对话的参数
+type ConversationPreset struct {
+	AssistantID    string
+	Title          string
+	Model          string
+	Settings       ConversationSettings
+	OpeningMessage string
+}
+
+// StartConversation 按预设创建对话；预设包含开场白时，作为根节点的助手消息保存
+func (s *Service) StartConversation(userID string, preset ConversationPreset) (*Conversation, *Message, error) {
+	if err := preset.Settings.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	title := preset.Title
+	if title == "" {
+		title = DefaultConversationTitle
+	}
+	conv := NewConversation(userID, title, preset.Model)
+	conv.Settings = preset.Settings
+	if preset.AssistantID != "" {
+		conv.AssistantID = &preset.AssistantID
+	}
+	if err := s.repo.CreateConversation(conv); err != nil {
+		return nil, nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	if preset.OpeningMessage == "" {
+		s.publish(userID, EventConversationCreated, conv.ID, conv)
+		return conv, nil, nil
+	}
+
+	opening := NewMessage(conv.ID, nil, "assistant", preset.OpeningMessage)
+	opening.FinishReason = FinishReasonStop
+	if err := s.repo.CreateMessage(opening); err != nil {
+		return nil, nil, fmt.Errorf("failed to save opening message: %w", err)
+	}
+	if err := s.repo.SetActiveLeaf(conv.ID, opening.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to update conversation: %w", err)
+	}
+	conv.ActiveLeafID = &opening.ID
+	s.publish(userID, EventConversationCreated, conv.ID, conv)
+	s.publish(userID, EventMessageCreated, conv.ID, opening)
+
+	return conv, opening, nil
+}
+
 // GetConversations 获取用户的对话列表
 func (s *Service) GetConversations(userID string) ([]Conversation, error) {
 	conversations, err := s.repo.GetConversationsByUserID(userID)
@@ -46,11 +145,47 @@ func (s *Service) GetConversation(conversationID string) (*Conversation, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
+	conv.Tags, err = s.repo.GetConversationTags(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation tags: %w", err)
+	}
 	return conv, nil
 }
 
-// GetMessages 获取对话的消息列表
+// GetMessages 获取对话当前分支（活动路径）上的消息
 func (s *Service) GetMessages(conversationID string) ([]Message, error) {
+	conv, err := s.repo.GetConversationByID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	messages, err := s.repo.GetMessagesByConversationID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	return activePath(messages, conv.ActiveLeafID), nil
+}
+
+// GetMessage 获取单条消息
+func (s *Service) GetMessage(messageID string) (*Message, error) {
+	msg, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return msg, nil
+}
+
+// GetMessageHistory 获取从根消息到指定消息的路径（包含该消息）
+func (s *Service) GetMessageHistory(conversationID, messageID string) ([]Message, error) {
+	messages, err := s.repo.GetAncestors(conversationID, messageID, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message history: %w", err)
+	}
+	return messages, nil
+}
+
+// GetMessageTree 获取对话的全部消息（包含所有分支）
+func (s *Service) GetMessageTree(conversationID string) ([]Message, error) {
 	messages, err := s.repo.GetMessagesByConversationID(conversationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages: %w", err)
@@ -60,140 +195,457 @@ func (s *Service) GetMessages(conversationID string) ([]Message, error) {
 
 // SendMessage 发送消息
 func (s *Service) SendMessage(ctx context.Context, conversationID, userID, content string) (*Message, *Message, error) {
-	// 检查是否有有效的API提供商
-	if !s.llmService.HasValidProviders() {
-		return nil, nil, fmt.Errorf("no valid API keys configured. Please configure valid API keys in the admin panel to use AI chat functionality")
+	conv, err := s.prepareConversation(conversationID, userID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 获取对话信息
-	conv, err := s.repo.GetConversationByID(conversationID)
+	// 创建用户消息，挂在当前分支的叶子之后
+	userMessage, err := s.createUserMessage(conv, conv.ActiveLeafID, content)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get conversation: %w", err)
+		return nil, nil, err
 	}
 
-	// 检查权限
-	if conv.UserID != userID {
-		return nil, nil, fmt.Errorf("unauthorized access to conversation")
+	assistantMessage, err := s.generate(ctx, conv, userMessage, GenerationOptions{})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 创建用户消息
-	userMessage := NewMessage(conversationID, "user", content)
-	if err := s.repo.CreateMessage(userMessage); err != nil {
-		return nil, nil, fmt.Errorf("failed to save user message: %w", err)
+	return userMessage, assistantMessage, nil
+}
+
+// SendMessageStream 发送消息（流式）
+func (s *Service) SendMessageStream(ctx context.Context, conversationID, userID, content string) (*Generation, error) {
+	conv, err := s.prepareConversation(conversationID, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 获取历史消息
-	messages, err := s.repo.GetMessagesByConversationID(conversationID)
+	// 创建用户消息，挂在当前分支的叶子之后
+	userMessage, err := s.createUserMessage(conv, conv.ActiveLeafID, content)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get message history: %w", err)
+		return nil, err
 	}
 
-	// 转换为LLM消息格式
-	llmMessages := s.convertToLLMMessages(messages)
+	return s.generateStream(ctx, conv, userMessage, GenerationOptions{})
+}
 
-	// 调用LLM服务
-	llmRequest := &llm.ChatRequest{
-		ConversationID: conversationID,
-		Messages:       llmMessages,
-		Model:          conv.Model,
-		Stream:         false,
+// EditMessage 编辑用户消息：在原消息旁创建一个同级分支并重新生成回答，原分支保留
+func (s *Service) EditMessage(ctx context.Context, conversationID, userID, messageID, content string) (*Message, *Message, error) {
+	conv, original, err := s.prepareEdit(conversationID, userID, messageID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	llmResponse, err := s.llmService.Chat(ctx, llmRequest)
+	userMessage, err := s.createUserMessage(conv, original.ParentID, content)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get LLM response: %w", err)
+		return nil, nil, err
 	}
 
-	// 创建助手消息
-	assistantMessage := NewMessage(conversationID, "assistant", llmResponse.Message.Content)
-	if err := s.repo.CreateMessage(assistantMessage); err != nil {
-		return nil, nil, fmt.Errorf("failed to save assistant message: %w", err)
+	assistantMessage, err := s.generate(ctx, conv, userMessage, GenerationOptions{})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 更新对话的最后更新时间
-	conv.UpdatedAt = time.Now()
-	if err := s.repo.UpdateConversation(conv); err != nil {
-		// 这里失败不影响主要流程，只记录错误
-		fmt.Printf("Warning: failed to update conversation: %v\n", err)
+	return userMessage, assistantMessage, nil
+}
+
+// EditMessageStream 编辑用户消息（流式）
+func (s *Service) EditMessageStream(ctx context.Context, conversationID, userID, messageID, content string) (*Generation, error) {
+	conv, original, err := s.prepareEdit(conversationID, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	userMessage, err := s.createUserMessage(conv, original.ParentID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.generateStream(ctx, conv, userMessage, GenerationOptions{})
+}
+
+// Regenerate 重新生成回答：基于指定消息之前的历史再次调用模型，新回答作为同级分支保存，旧回答保留
+// messageID可以是助手消息（为其父用户消息重新生成）或用户消息
+func (s *Service) Regenerate(ctx context.Context, conversationID, userID, messageID string, opts GenerationOptions) (*Message, *Message, error) {
+	conv, userMessage, err := s.prepareRegenerate(conversationID, userID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assistantMessage, err := s.generate(ctx, conv, userMessage, opts)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return userMessage, assistantMessage, nil
 }
 
-// SendMessageStream 发送消息（流式）
-func (s *Service) SendMessageStream(ctx context.Context, conversationID, userID, content string) (*Message, <-chan *llm.ChatResponse, error) {
-	// 检查是否有有效的API提供商
-	if !s.llmService.HasValidProviders() {
-		return nil, nil, fmt.Errorf("no valid API keys configured. Please configure valid API keys in the admin panel to use AI chat functionality")
+// RegenerateStream 重新生成回答（流式）
+func (s *Service) RegenerateStream(ctx context.Context, conversationID, userID, messageID string, opts GenerationOptions) (*Generation, error) {
+	conv, userMessage, err := s.prepareRegenerate(conversationID, userID, messageID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 获取对话信息
+	return s.generateStream(ctx, conv, userMessage, opts)
+}
+
+// SwitchBranch 切换到指定消息所在的分支，活动叶子为该分支上最新的消息
+func (s *Service) SwitchBranch(conversationID, userID, messageID string) ([]Message, error) {
+	conv, err := s.getOwnedConversation(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.GetMessagesByConversationID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	found := false
+	for _, msg := range messages {
+		if msg.ID == messageID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("message not found")
+	}
+
+	leafID := latestLeaf(messages, messageID)
+	if err := s.repo.SetActiveLeaf(conv.ID, leafID); err != nil {
+		return nil, fmt.Errorf("failed to switch branch: %w", err)
+	}
+	s.publish(userID, EventConversationUpdated, conv.ID, map[string]interface{}{"active_leaf_id": leafID})
+
+	return activePath(messages, &leafID), nil
+}
+
+// getOwnedConversation 获取对话并检查权限
+func (s *Service) getOwnedConversation(conversationID, userID string) (*Conversation, error) {
 	conv, err := s.repo.GetConversationByID(conversationID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get conversation: %w", err)
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 
 	// 检查权限
 	if conv.UserID != userID {
-		return nil, nil, fmt.Errorf("unauthorized access to conversation")
+		return nil, fmt.Errorf("unauthorized access to conversation")
+	}
+
+	return conv, nil
+}
+
+// prepareConversation 检查API提供商与对话权限
+func (s *Service) prepareConversation(conversationID, userID string) (*Conversation, error) {
+	// 检查是否有有效的API提供商
+	if !s.llmService.HasValidProviders() {
+		return nil, fmt.Errorf("no valid API keys configured. Please configure valid API keys in the admin panel to use AI chat functionality")
+	}
+
+	return s.getOwnedConversation(conversationID, userID)
+}
+
+// prepareEdit 检查编辑权限并返回被编辑的用户消息
+func (s *Service) prepareEdit(conversationID, userID, messageID string) (*Conversation, *Message, error) {
+	conv, err := s.prepareConversation(conversationID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	original, err := s.repo.GetMessageByID(messageID)
+	if err != nil || original.ConversationID != conversationID {
+		return nil, nil, fmt.Errorf("message not found")
+	}
+	if original.Role != "user" {
+		return nil, nil, fmt.Errorf("invalid message: only user messages can be edited")
+	}
+
+	return conv, original, nil
+}
+
+// prepareRegenerate 检查权限并返回重新生成所基于的用户消息
+func (s *Service) prepareRegenerate(conversationID, userID, messageID string) (*Conversation, *Message, error) {
+	conv, err := s.prepareConversation(conversationID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := s.repo.GetMessageByID(messageID)
+	if err != nil || target.ConversationID != conversationID {
+		return nil, nil, fmt.Errorf("message not found")
+	}
+
+	switch target.Role {
+	case "user":
+		return conv, target, nil
+	case "assistant":
+		if target.ParentID == nil {
+			return nil, nil, fmt.Errorf("invalid message: assistant message has no parent")
+		}
+		userMessage, err := s.repo.GetMessageByID(*target.ParentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("message not found")
+		}
+		return conv, userMessage, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid message: cannot regenerate %s message", target.Role)
 	}
+}
 
-	// 创建用户消息
-	userMessage := NewMessage(conversationID, "user", content)
+// createUserMessage 保存用户消息并将其设为活动叶子
+func (s *Service) createUserMessage(conv *Conversation, parentID *string, content string) (*Message, error) {
+	userMessage := NewMessage(conv.ID, parentID, "user", content)
 	if err := s.repo.CreateMessage(userMessage); err != nil {
-		return nil, nil, fmt.Errorf("failed to save user message: %w", err)
+		return nil, fmt.Errorf("failed to save user message: %w", err)
 	}
 
-	// 获取历史消息
-	messages, err := s.repo.GetMessagesByConversationID(conversationID)
+	if err := s.repo.SetActiveLeaf(conv.ID, userMessage.ID); err != nil {
+		fmt.Printf("Warning: failed to update active leaf: %v\n", err)
+	}
+	conv.ActiveLeafID = &userMessage.ID
+	s.publish(conv.UserID, EventMessageCreated, conv.ID, userMessage)
+
+	return userMessage, nil
+}
+
+// buildLLMRequest 构建到指定消息为止的历史，并合并对话设置与模型默认参数
+// 返回的references为检索到的引用来源，需写入助手消息的artifacts
+func (s *Service) buildLLMRequest(ctx context.Context, conv *Conversation, leaf *Message, opts GenerationOptions, stream bool) (*llm.ChatRequest, map[string]interface{}, error) {
+	llmMessages, references, err := s.buildHistory(ctx, conv, leaf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	model := conv.Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	return s.newLLMRequest(conv, llmMessages, model, opts, stream), references, nil
+}
+
+// buildHistory 沿活动路径构建到指定消息为止的LLM消息，并按需注入知识库与联网搜索结果
+func (s *Service) buildHistory(ctx context.Context, conv *Conversation, leaf *Message) ([]llm.ChatMessage, map[string]interface{}, error) {
+	messages, err := s.repo.GetMessagesByConversationID(conv.ID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get message history: %w", err)
 	}
 
 	// 转换为LLM消息格式
-	llmMessages := s.convertToLLMMessages(messages)
+	llmMessages := s.convertToLLMMessages(activePath(messages, &leaf.ID))
 
-	// 调用LLM流式服务
-	llmRequest := &llm.ChatRequest{
-		ConversationID: conversationID,
+	references := make(map[string]interface{})
+
+	// 知识库检索
+	llmMessages, knowledgeCitations := s.applyKnowledge(ctx, conv, leaf.Content, llmMessages)
+	if len(knowledgeCitations) > 0 {
+		references["knowledge_citations"] = knowledgeCitations
+	}
+
+	// 联网搜索
+	llmMessages, citations := s.applyWebSearch(ctx, conv, leaf.Content, llmMessages)
+	if len(citations) > 0 {
+		references["citations"] = citations
+	}
+
+	return llmMessages, references, nil
+}
+
+// newLLMRequest 为指定模型创建LLM请求，合并对话设置与模型默认参数
+func (s *Service) newLLMRequest(conv *Conversation, llmMessages []llm.ChatMessage, model string, opts GenerationOptions, stream bool) *llm.ChatRequest {
+	request := &llm.ChatRequest{
+		ConversationID: conv.ID,
 		Messages:       llmMessages,
-		Model:          conv.Model,
-		Stream:         true,
+		Model:          model,
+		Stream:         stream,
+	}
+	conv.Settings.applyTo(request, s.llmService.FindChatModel(model), opts)
+	return request
+}
+
+// generate 针对用户消息生成助手回复
+func (s *Service) generate(ctx context.Context, conv *Conversation, userMessage *Message, opts GenerationOptions) (*Message, error) {
+	llmRequest, references, err := s.buildLLMRequest(ctx, conv, userMessage, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	llmResponse, err := s.llmService.Chat(ctx, llmRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM response: %w", err)
+	}
+
+	// 创建助手消息
+	assistantMessage := NewMessage(conv.ID, &userMessage.ID, "assistant", llmResponse.Message.Content)
+	assistantMessage.Model = llmRequest.Model
+	assistantMessage.FinishReason = llmResponse.FinishReason
+	if assistantMessage.FinishReason == "" {
+		assistantMessage.FinishReason = FinishReasonStop
+	}
+	for key, value := range references {
+		assistantMessage.Artifacts[key] = value
 	}
+	if llmResponse.Usage != nil {
+		assistantMessage.Artifacts["usage"] = llmResponse.Usage
+	}
+	if err := s.saveAssistantMessage(conv, assistantMessage); err != nil {
+		return nil, err
+	}
+	s.maybeGenerateTitle(conv, userMessage, assistantMessage)
+
+	return assistantMessage, nil
+}
+
+// generateStream 针对用户消息流式生成助手回复
+// 生成在后台运行，不随请求断开而中止；事件按顺序写入缓冲以便断线重连，可通过注册表取消
+// 流结束、被取消或中断时都会保存已生成的内容及结束原因
+func (s *Service) generateStream(ctx context.Context, conv *Conversation, userMessage *Message, opts GenerationOptions) (*Generation, error) {
+	generationID := uuid.New().String()
+	genCtx, done := s.generations.Register(generationID, conv.UserID)
 
-	responseStream, err := s.llmService.StreamChat(ctx, llmRequest)
+	llmRequest, references, err := s.buildLLMRequest(ctx, conv, userMessage, opts, true)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get LLM stream response: %w", err)
+		done()
+		return nil, err
+	}
+
+	responseStream, err := s.llmService.StreamChat(genCtx, llmRequest)
+	if err != nil {
+		done()
+		return nil, fmt.Errorf("failed to get LLM stream response: %w", err)
 	}
 
 	// 创建一个新的channel来处理流式响应
-	processedStream := make(chan *llm.ChatResponse, 10)
-	
+	events := make(chan GenerationEvent, 10)
+	generation := &Generation{
+		ID:          generationID,
+		UserMessage: userMessage,
+		Events:      events,
+		detached:    make(chan struct{}),
+	}
+
+	// emit 写入缓冲并推送给仍在连接的客户端
+	emit := func(event string, data interface{}) {
+		id := s.generations.Append(generationID, event, data)
+		select {
+		case events <- GenerationEvent{ID: id, Event: event, Data: data}:
+		case <-generation.detached:
+		}
+	}
+
+	s.publish(conv.UserID, EventGenerationStarted, conv.ID, map[string]interface{}{
+		"generation_id":   generationID,
+		"user_message_id": userMessage.ID,
+		"model":           llmRequest.Model,
+	})
+
 	go func() {
-		defer close(processedStream)
-		var fullContent string
-		
+		defer close(events)
+		defer done()
+
+		emit(EventUserMessage, userMessage)
+
+		var fullContent strings.Builder
+		var usage *llm.TokenUsage
+		finishReason := ""
+
 		for response := range responseStream {
-			fullContent += response.Message.Content
-			processedStream <- response
-			
-			// 如果是最后一个响应，保存助手消息
-			if response.FinishReason == "stop" {
-				assistantMessage := NewMessage(conversationID, "assistant", fullContent)
-				if err := s.repo.CreateMessage(assistantMessage); err != nil {
-					fmt.Printf("Warning: failed to save assistant message: %v\n", err)
-				}
-				
-				// 更新对话的最后更新时间
-				conv.UpdatedAt = time.Now()
-				if err := s.repo.UpdateConversation(conv); err != nil {
-					fmt.Printf("Warning: failed to update conversation: %v\n", err)
-				}
+			fullContent.WriteString(response.Message.Content)
+			if response.FinishReason != "" {
+				finishReason = response.FinishReason
+			}
+			if response.Usage != nil {
+				usage = response.Usage
 			}
+			emit(EventAssistantMessage, response)
 		}
+
+		// 上游未给出结束原因即关闭：被取消或异常中断
+		if finishReason == "" {
+			finishReason = FinishReasonError
+			if genCtx.Err() != nil {
+				finishReason = FinishReasonCancelled
+			}
+
+			emit(EventAssistantMessage, &llm.ChatResponse{
+				ConversationID: conv.ID,
+				Message:        llm.ChatMessage{Role: "assistant", CreatedAt: time.Now()},
+				FinishReason:   finishReason,
+			})
+		}
+
+		// 保存助手消息（包括被取消或截断的部分回答）
+		result := map[string]interface{}{"status": "completed", "finish_reason": finishReason}
+		if fullContent.Len() > 0 || finishReason == FinishReasonStop {
+			assistantMessage := NewMessage(conv.ID, &userMessage.ID, "assistant", fullContent.String())
+			assistantMessage.Model = llmRequest.Model
+			assistantMessage.FinishReason = finishReason
+			for key, value := range references {
+				assistantMessage.Artifacts[key] = value
+			}
+			if usage != nil {
+				assistantMessage.Artifacts["usage"] = usage
+			}
+			if err := s.saveAssistantMessage(conv, assistantMessage); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			} else {
+				result["message_id"] = assistantMessage.ID
+				s.maybeGenerateTitle(conv, userMessage, assistantMessage)
+			}
+		}
+
+		emit(EventDone, result)
+		s.publish(conv.UserID, EventGenerationFinished, conv.ID, generationFinished(generationID, userMessage.ID, result))
 	}()
 
-	return userMessage, processedStream, nil
+	return generation, nil
+}
+
+// ResumeGeneration 断线重连：从lastEventID之后继续读取生成事件
+func (s *Service) ResumeGeneration(ctx context.Context, generationID, userID, lastEventID string) (<-chan GenerationEvent, error) {
+	return s.generations.Replay(ctx, generationID, userID, lastEventID)
+}
+
+// saveAssistantMessage 保存助手消息并设为活动叶子，同时提取回复中的artifact写入消息的artifacts字段
+// artifact记录引用消息ID，因此需要在消息保存之后提取
+func (s *Service) saveAssistantMessage(conv *Conversation, msg *Message) error {
+	if err := s.repo.CreateMessage(msg); err != nil {
+		return fmt.Errorf("failed to save assistant message: %w", err)
+	}
+
+	// 设为活动叶子，同时更新对话的最后更新时间
+	if err := s.repo.SetActiveLeaf(msg.ConversationID, msg.ID); err != nil {
+		// 这里失败不影响主要流程，只记录错误
+		fmt.Printf("Warning: failed to update conversation: %v\n", err)
+	}
+
+	s.extractArtifacts(msg)
+	s.publish(conv.UserID, EventMessageCreated, conv.ID, msg)
+	return nil
+}
+
+// extractArtifacts 提取回复中的artifact并写入消息的artifacts字段，失败时只记录警告
+func (s *Service) extractArtifacts(msg *Message) {
+	if s.artifactService == nil {
+		return
+	}
+
+	refs, err := s.artifactService.ExtractAndSave(msg.ConversationID, msg.ID, msg.Content)
+	if err != nil {
+		// artifact提取失败不影响消息本身
+		fmt.Printf("Warning: failed to extract artifacts: %v\n", err)
+	}
+	if len(refs) == 0 {
+		return
+	}
+
+	msg.Artifacts["artifacts"] = refs
+	if err := s.repo.UpdateMessageArtifacts(msg); err != nil {
+		fmt.Printf("Warning: failed to update message artifacts: %v\n", err)
+	}
 }
 
 // UpdateConversation 更新对话
@@ -209,14 +661,75 @@ func (s *Service) UpdateConversation(conversationID, userID string, updates map[
 	}
 
 	// 更新字段
-	if title, ok := updates["title"]; ok {
-		conv.Title = title.(string)
+	if value, ok := updates["title"]; ok {
+		title, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid title")
+		}
+		conv.Title = title
 	}
-	if model, ok := updates["model"]; ok {
-		conv.Model = model.(string)
+	if value, ok := updates["model"]; ok {
+		model, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid model")
+		}
+		conv.Model = model
 	}
-	if settings, ok := updates["settings"]; ok {
-		conv.Settings = settings.(map[string]interface{})
+	if value, ok := updates["settings"]; ok {
+		settings, err := ParseConversationSettings(value)
+		if err != nil {
+			return nil, err
+		}
+		// 对话中保存的可能是模型ID，按ID或名称解析出实际模型后再校验
+		if err := settings.validateForModel(s.llmService.FindChatModel(conv.Model)); err != nil {
+			return nil, err
+		}
+		if err := s.checkKnowledgeAccess(conv, userID, settings.KnowledgeBaseIDs); err != nil {
+			return nil, err
+		}
+		conv.Settings = settings
+	}
+	if value, ok := updates["folder_id"]; ok {
+		switch folderID := value.(type) {
+		case nil:
+			conv.FolderID = nil
+		case string:
+			conv.FolderID = emptyToNil(&folderID)
+			if conv.FolderID != nil {
+				if _, err := s.getOwnedFolder(folderID, userID); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid folder_id")
+		}
+	}
+	if value, ok := updates["pinned"]; ok {
+		pinned, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid pinned")
+		}
+		conv.Pinned = pinned
+	}
+	if value, ok := updates["archived"]; ok {
+		archived, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid archived")
+		}
+		if !archived {
+			conv.ArchivedAt = nil
+		} else if conv.ArchivedAt == nil {
+			now := time.Now()
+			conv.ArchivedAt = &now
+		}
+	}
+	_, updateTags := updates["tags"]
+	if updateTags {
+		if conv.Tags, err = parseTags(updates["tags"]); err != nil {
+			return nil, err
+		}
+	} else if conv.Tags, err = s.repo.GetConversationTags(conversationID); err != nil {
+		return nil, fmt.Errorf("failed to get conversation tags: %w", err)
 	}
 
 	conv.UpdatedAt = time.Now()
@@ -224,6 +737,12 @@ func (s *Service) UpdateConversation(conversationID, userID string, updates map[
 	if err := s.repo.UpdateConversation(conv); err != nil {
 		return nil, fmt.Errorf("failed to update conversation: %w", err)
 	}
+	if updateTags {
+		if err := s.repo.SetConversationTags(conversationID, conv.Tags); err != nil {
+			return nil, fmt.Errorf("failed to update tags: %w", err)
+		}
+	}
+	s.publish(userID, EventConversationUpdated, conv.ID, conv)
 
 	return conv, nil
 }
@@ -243,6 +762,7 @@ func (s *Service) DeleteConversation(conversationID, userID string) error {
 	if err := s.repo.DeleteConversation(conversationID); err != nil {
 		return fmt.Errorf("failed to delete conversation: %w", err)
 	}
+	s.publish(userID, EventConversationDeleted, conversationID, nil)
 
 	return nil
 }
@@ -259,4 +779,91 @@ func (s *Service) convertToLLMMessages(messages []Message) []llm.ChatMessage {
 		}
 	}
 	return llmMessages
-}
\ No newline at end of file
+}
+
+// webSearchEnabled 检查对话是否开启了联网搜索
+func webSearchEnabled(conv *Conversation) bool {
+	return conv.Settings.WebSearch
+}
+
+// checkKnowledgeAccess 对话只能引用用户自己的知识库，或对话已有的（例如从公开助手继承的）知识库
+func (s *Service) checkKnowledgeAccess(conv *Conversation, userID string, knowledgeBaseIDs []string) error {
+	inherited := make(map[string]bool, len(conv.Settings.KnowledgeBaseIDs))
+	for _, id := range conv.Settings.KnowledgeBaseIDs {
+		inherited[id] = true
+	}
+
+	for _, id := range knowledgeBaseIDs {
+		if inherited[id] {
+			continue
+		}
+		if s.knowledgeService == nil {
+			return fmt.Errorf("invalid settings: knowledge bases are not available")
+		}
+		if _, err := s.knowledgeService.GetKnowledgeBase(id, userID); err != nil {
+			return fmt.Errorf("invalid settings: knowledge base %s is not accessible", id)
+		}
+	}
+	return nil
+}
+
+// applyKnowledge 对话关联了知识库时，检索用户问题并将命中的片段注入到最后一条用户消息中
+// 知识库在写入设置时已做权限检查；检索失败不影响正常回答
+func (s *Service) applyKnowledge(ctx context.Context, conv *Conversation, query string, llmMessages []llm.ChatMessage) ([]llm.ChatMessage, []knowledge.Citation) {
+	if s.knowledgeService == nil || len(conv.Settings.KnowledgeBaseIDs) == 0 || len(llmMessages) == 0 || query == "" {
+		return llmMessages, nil
+	}
+
+	var citations []knowledge.Citation
+	for _, id := range conv.Settings.KnowledgeBaseIDs {
+		results, err := s.knowledgeService.SearchByID(ctx, id, knowledge.SearchRequest{Query: query})
+		if err != nil {
+			fmt.Printf("Warning: knowledge search failed for %s: %v\n", id, err)
+			continue
+		}
+		for _, result := range results {
+			citations = append(citations, result.Citation)
+		}
+	}
+	if len(citations) == 0 {
+		return llmMessages, nil
+	}
+
+	var sources strings.Builder
+	for i, citation := range citations {
+		fmt.Fprintf(&sources, "[资料%d] %s\n%s\n\n", i+1, citation.Title, citation.Content)
+	}
+
+	last := len(llmMessages) - 1
+	llmMessages[last].Content = fmt.Sprintf(`以下是从知识库中检索到的参考资料。请优先依据这些资料回答，引用时使用方括号编号（如[资料1]），资料中没有的信息请明确说明。
+
+%s
+
+用户问题：%s`, strings.TrimSpace(sources.String()), llmMessages[last].Content)
+
+	return llmMessages, citations
+}
+
+// applyWebSearch 对话开启联网搜索时，检索用户问题并将整理后的结果注入到最后一条用户消息中
+// 返回注入后的消息列表与引用来源；搜索失败不影响正常回答
+func (s *Service) applyWebSearch(ctx context.Context, conv *Conversation, query string, llmMessages []llm.ChatMessage) ([]llm.ChatMessage, []search.Citation) {
+	if s.searchService == nil || !webSearchEnabled(conv) || len(llmMessages) == 0 {
+		return llmMessages, nil
+	}
+
+	citations, err := s.searchService.Search(ctx, query)
+	if err != nil {
+		fmt.Printf("Warning: web search failed: %v\n", err)
+		return llmMessages, nil
+	}
+	if len(citations) == 0 {
+		return llmMessages, nil
+	}
+
+	searchContext := s.searchService.BuildContext(ctx, query, conv.Model, citations)
+
+	last := len(llmMessages) - 1
+	llmMessages[last].Content = fmt.Sprintf("%s\n\n用户问题：%s", searchContext, llmMessages[last].Content)
+
+	return llmMessages, citations
+}
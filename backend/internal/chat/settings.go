@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qicro/qicro/backend/internal/config"
+	"github.com/qicro/qicro/backend/internal/llm"
+)
+
+const (
+	maxSystemPromptRunes = 20000
+	maxSettingsMaxTokens = 200000
+	maxStopSequences     = 4
+	maxKnowledgeBases    = 5
+)
+
+// ConversationSettings 对话级别的生成设置，未设置的字段使用模型默认值
+type ConversationSettings struct {
+	SystemPrompt    string   `json:"system_prompt,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	WebSearch       bool     `json:"web_search,omitempty"`
+	// KnowledgeBaseIDs 回答前检索的知识库，通常继承自助手
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"`
+}
+
+// ParseConversationSettings 严格解析设置，拒绝未知字段并校验取值范围
+func ParseConversationSettings(raw interface{}) (ConversationSettings, error) {
+	var settings ConversationSettings
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return settings, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		return settings, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// Validate 校验设置取值范围
+func (s *ConversationSettings) Validate() error {
+	if utf8.RuneCountInString(s.SystemPrompt) > maxSystemPromptRunes {
+		return fmt.Errorf("invalid settings: system_prompt must be at most %d characters", maxSystemPromptRunes)
+	}
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("invalid settings: temperature must be between 0 and 2")
+	}
+	if s.TopP != nil && (*s.TopP < 0 || *s.TopP > 1) {
+		return fmt.Errorf("invalid settings: top_p must be between 0 and 1")
+	}
+	if s.MaxTokens < 0 || s.MaxTokens > maxSettingsMaxTokens {
+		return fmt.Errorf("invalid settings: max_tokens must be between 0 and %d", maxSettingsMaxTokens)
+	}
+	if len(s.Stop) > maxStopSequences {
+		return fmt.Errorf("invalid settings: at most %d stop sequences are allowed", maxStopSequences)
+	}
+	for _, stop := range s.Stop {
+		if stop == "" {
+			return fmt.Errorf("invalid settings: stop sequences must not be empty")
+		}
+	}
+	if len(s.KnowledgeBaseIDs) > maxKnowledgeBases {
+		return fmt.Errorf("invalid settings: at most %d knowledge bases are allowed", maxKnowledgeBases)
+	}
+	switch s.ReasoningEffort {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("invalid settings: reasoning_effort must be one of low, medium, high")
+	}
+	return nil
+}
+
+// validateForModel 校验设置是否被模型支持，模型未知（nil）时跳过
+func (s *ConversationSettings) validateForModel(model *config.ChatModel) error {
+	if model == nil {
+		return nil
+	}
+	if s.ReasoningEffort != "" && !llm.SupportsReasoning(model.Value) {
+		return fmt.Errorf("invalid settings: reasoning_effort is not supported by model %s", model.Value)
+	}
+	return nil
+}
+
+// applyTo 将对话设置与模型默认值合并写入LLM请求
+// 优先级：单次生成的覆盖参数 > 对话设置 > 模型默认值
+func (s ConversationSettings) applyTo(req *llm.ChatRequest, model *config.ChatModel, opts GenerationOptions) {
+	if model != nil {
+		// 模型的temperature创建时缺省为1.0，为0时是管理员显式设置的值
+		temperature := model.Temperature
+		req.Temperature = &temperature
+		req.MaxTokens = model.MaxTokens
+	}
+
+	req.System = strings.TrimSpace(s.SystemPrompt)
+	if s.Temperature != nil {
+		req.Temperature = s.Temperature
+	}
+	if s.MaxTokens > 0 {
+		req.MaxTokens = s.MaxTokens
+	}
+	req.TopP = s.TopP
+	req.Stop = s.Stop
+	req.ReasoningEffort = s.ReasoningEffort
+
+	if opts.Temperature != nil {
+		req.Temperature = opts.Temperature
+	}
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// titleTimeout 生成标题的超时时间
+const titleTimeout = 30 * time.Second
+
+// TitleGenerator 对话标题生成器，由 llm.EinoService 实现
+type TitleGenerator interface {
+	GenerateTitle(ctx context.Context, question, answer, model string) (string, error)
+}
+
+// Notifier 向用户已连接的客户端推送事件，由 websocket.Hub 实现
+type Notifier interface {
+	Notify(userID, eventType string, data interface{})
+}
+
+// SetTitleGenerator 注入标题生成器，model为空时使用对话的模型
+func (s *Service) SetTitleGenerator(generator TitleGenerator, model string) {
+	s.titleGenerator = generator
+	s.titleModel = model
+}
+
+// SetNotifier 注入客户端推送
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// maybeGenerateTitle 首轮问答完成且对话仍使用默认标题时，异步生成标题
+func (s *Service) maybeGenerateTitle(conv *Conversation, userMessage, assistantMessage *Message) {
+	if s.titleGenerator == nil || !IsDefaultTitle(conv.Title) || !s.isFirstQuestion(userMessage) {
+		return
+	}
+	if assistantMessage.Content == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		if err := s.generateTitle(ctx, conv, userMessage.Content, assistantMessage.Content); err != nil {
+			fmt.Printf("Warning: failed to generate title for conversation %s: %v\n", conv.ID, err)
+		}
+	}()
+}
+
+// isFirstQuestion 用户消息是否为首轮提问：根消息，或直接回复助手开场白
+func (s *Service) isFirstQuestion(userMessage *Message) bool {
+	if userMessage.ParentID == nil {
+		return true
+	}
+	parent, err := s.repo.GetMessageByID(*userMessage.ParentID)
+	if err != nil {
+		return false
+	}
+	return parent.Role == "assistant" && parent.ParentID == nil
+}
+
+// generateTitle 生成标题、保存并推送给客户端
+func (s *Service) generateTitle(ctx context.Context, conv *Conversation, question, answer string) error {
+	model := s.titleModel
+	if model == "" {
+		model = conv.Model
+	}
+
+	title, err := s.titleGenerator.GenerateTitle(ctx, question, answer, model)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.repo.UpdateConversationTitleIfUnchanged(conv.ID, DefaultConversationTitle, title)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation title: %w", err)
+	}
+	if !updated {
+		// 用户已手动修改标题
+		return nil
+	}
+
+	s.publish(conv.UserID, EventConversationUpdated, conv.ID, map[string]interface{}{"title": title})
+	return nil
+}
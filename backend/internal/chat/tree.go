@@ -0,0 +1,83 @@
+package chat
+
+// activePath 从叶子消息沿parent_id回溯到根，返回按对话顺序排列的活动路径
+// 叶子不存在时（例如旧数据或刚删除的分支）退化为最近创建的消息
+func activePath(messages []Message, leafID *string) []Message {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*Message, len(messages))
+	for i := range messages {
+		byID[messages[i].ID] = &messages[i]
+	}
+
+	leaf := &messages[len(messages)-1]
+	if leafID != nil {
+		if msg, ok := byID[*leafID]; ok {
+			leaf = msg
+		}
+	}
+
+	var reversed []Message
+	visited := make(map[string]bool)
+	for msg := leaf; msg != nil && !visited[msg.ID]; {
+		visited[msg.ID] = true
+		reversed = append(reversed, *msg)
+		if msg.ParentID == nil {
+			break
+		}
+		msg = byID[*msg.ParentID]
+	}
+
+	path := make([]Message, len(reversed))
+	for i, msg := range reversed {
+		path[len(reversed)-1-i] = msg
+	}
+
+	attachSiblings(path, messages)
+	return path
+}
+
+// attachSiblings 为路径上的消息填充同级分支ID
+func attachSiblings(path, messages []Message) {
+	children := make(map[string][]string)
+	for _, msg := range messages {
+		children[parentKey(msg.ParentID)] = append(children[parentKey(msg.ParentID)], msg.ID)
+	}
+
+	for i := range path {
+		if siblings := children[parentKey(path[i].ParentID)]; len(siblings) > 1 {
+			path[i].Siblings = siblings
+		}
+	}
+}
+
+// latestLeaf 从指定消息开始，每一层选择最新创建的子消息，返回到达的叶子
+// 切换分支时用它恢复该分支上次生成到的位置
+func latestLeaf(messages []Message, fromID string) string {
+	latestChild := make(map[string]string)
+	for _, msg := range messages {
+		// messages按创建时间升序，后出现的覆盖先出现的
+		latestChild[parentKey(msg.ParentID)] = msg.ID
+	}
+
+	leafID := fromID
+	visited := map[string]bool{leafID: true}
+	for {
+		child, ok := latestChild[leafID]
+		if !ok || visited[child] {
+			return leafID
+		}
+		visited[child] = true
+		leafID = child
+	}
+}
+
+// parentKey 根消息使用空字符串作为父节点键
+func parentKey(parentID *string) string {
+	if parentID == nil {
+		return ""
+	}
+	return *parentID
+}
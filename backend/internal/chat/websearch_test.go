@@ -0,0 +1,270 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qicro/qicro/backend/internal/config"
+	"github.com/qicro/qicro/backend/internal/llm"
+	"github.com/qicro/qicro/backend/internal/search"
+)
+
+var searchResults = []search.Result{
+	{Title: "Result A", URL: "https://a.example", Snippet: "alpha"},
+	{Title: "Result B", URL: "https://b.example", Snippet: "beta"},
+}
+
+func newWebSearchService() *Service {
+	service := &Service{}
+	service.SetSearchService(search.NewService(search.NewFakeBackend(searchResults), nil, 5, false, ""))
+	return service
+}
+
+func TestApplyWebSearchInjectsContextAndReturnsCitations(t *testing.T) {
+	conv := &Conversation{Settings: ConversationSettings{WebSearch: true}}
+	messages := []llm.ChatMessage{
+		{Role: "user", Content: "earlier"},
+		{Role: "assistant", Content: "answer"},
+		{Role: "user", Content: "what is alpha"},
+	}
+
+	messages, citations := newWebSearchService().applyWebSearch(context.Background(), conv, "what is alpha", messages)
+
+	if len(citations) != 2 || citations[0].Index != 1 || citations[1].URL != "https://b.example" {
+		t.Fatalf("unexpected citations %+v", citations)
+	}
+	last := messages[len(messages)-1].Content
+	if !strings.Contains(last, "[1] Result A (https://a.example)") || !strings.HasSuffix(last, "用户问题：what is alpha") {
+		t.Errorf("unexpected injected message:\n%s", last)
+	}
+	if messages[0].Content != "earlier" {
+		t.Errorf("earlier messages should not change, got %q", messages[0].Content)
+	}
+}
+
+func TestApplyWebSearchDisabled(t *testing.T) {
+	conv := &Conversation{}
+	messages := []llm.ChatMessage{{Role: "user", Content: "q"}}
+
+	messages, citations := newWebSearchService().applyWebSearch(context.Background(), conv, "q", messages)
+	if citations != nil || messages[0].Content != "q" {
+		t.Errorf("web search should be skipped, got %+v %q", citations, messages[0].Content)
+	}
+}
+
+func TestGenerateSummarizesSearchResultsAndSavesCitations(t *testing.T) {
+	db := newFakeDB()
+	provider := &stubProvider{summary: "Alpha comes first [1], beta second [2]."}
+	llmService := llm.NewService(config.NewService(config.NewRepository(db.DB)))
+	llmService.AddProvider(provider)
+
+	service := NewService(NewRepository(db.DB), llmService)
+	service.SetSearchService(search.NewService(search.NewFakeBackend(searchResults), llmService, 5, true, ""))
+
+	conv := &Conversation{ID: "conv-1", UserID: "user-1", Model: "stub-model", Settings: ConversationSettings{WebSearch: true}}
+	userMessage := NewMessage(conv.ID, nil, "user", "what is alpha")
+	db.addMessage(userMessage)
+
+	assistantMessage, err := service.generate(context.Background(), conv, userMessage, GenerationOptions{})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// 先总结搜索结果，再用注入总结后的消息回答
+	requests := provider.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected summarize and answer requests, got %d", len(requests))
+	}
+	if sources := requests[0].Messages[len(requests[0].Messages)-1].Content; !strings.Contains(sources, "[2] Result B (https://b.example)") {
+		t.Errorf("summarize request missing sources:\n%s", sources)
+	}
+	question := requests[1].Messages[len(requests[1].Messages)-1].Content
+	if !strings.Contains(question, provider.summary) || !strings.HasSuffix(question, "用户问题：what is alpha") {
+		t.Errorf("answer request missing summary:\n%s", question)
+	}
+
+	saved := db.message(assistantMessage.ID)
+	if saved == nil {
+		t.Fatalf("assistant message %s was not saved", assistantMessage.ID)
+	}
+	var artifacts struct {
+		Citations []search.Citation `json:"citations"`
+	}
+	if err := json.Unmarshal(saved.artifacts, &artifacts); err != nil {
+		t.Fatalf("unmarshal saved artifacts: %v", err)
+	}
+	want := []search.Citation{
+		{Index: 1, Title: "Result A", URL: "https://a.example", Snippet: "alpha"},
+		{Index: 2, Title: "Result B", URL: "https://b.example", Snippet: "beta"},
+	}
+	if len(artifacts.Citations) != len(want) || artifacts.Citations[0] != want[0] || artifacts.Citations[1] != want[1] {
+		t.Errorf("unexpected saved citations %+v", artifacts.Citations)
+	}
+}
+
+// stubProvider 记录请求的LLM提供商，总结请求返回summary，其他请求返回固定回答
+type stubProvider struct {
+	summary string
+
+	mu       sync.Mutex
+	requests []llm.ChatRequest
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+func (p *stubProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, *req)
+	content := "Alpha is the first letter [1]."
+	if len(p.requests) == 1 {
+		content = p.summary
+	}
+	p.mu.Unlock()
+
+	return &llm.ChatResponse{
+		Message:      llm.ChatMessage{Role: "assistant", Content: content},
+		FinishReason: FinishReasonStop,
+	}, nil
+}
+
+func (p *stubProvider) StreamChat(ctx context.Context, req *llm.ChatRequest) (<-chan *llm.ChatResponse, error) {
+	return nil, fmt.Errorf("stream not supported")
+}
+
+func (p *stubProvider) GetModels() []llm.Model { return nil }
+
+func (p *stubProvider) Requests() []llm.ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]llm.ChatRequest(nil), p.requests...)
+}
+
+// fakeDB 内存中的database/sql驱动，只支持生成回复用到的消息读写，其他查询返回空结果
+type fakeDB struct {
+	*sql.DB
+
+	mu       sync.Mutex
+	messages []fakeMessage
+}
+
+// fakeMessage messages表中的一行
+type fakeMessage struct {
+	id, conversationID, role, content, model, finishReason string
+	parentID                                               interface{}
+	artifacts                                              []byte
+	createdAt                                              time.Time
+}
+
+func newFakeDB() *fakeDB {
+	db := &fakeDB{}
+	db.DB = sql.OpenDB(db)
+	return db
+}
+
+func (db *fakeDB) addMessage(msg *Message) {
+	artifacts, _ := json.Marshal(msg.Artifacts)
+	var parentID interface{}
+	if msg.ParentID != nil {
+		parentID = *msg.ParentID
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.messages = append(db.messages, fakeMessage{
+		id: msg.ID, conversationID: msg.ConversationID, role: msg.Role, content: msg.Content,
+		model: msg.Model, finishReason: msg.FinishReason, parentID: parentID,
+		artifacts: artifacts, createdAt: msg.CreatedAt,
+	})
+}
+
+func (db *fakeDB) message(id string) *fakeMessage {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for i := range db.messages {
+		if db.messages[i].id == id {
+			return &db.messages[i]
+		}
+	}
+	return nil
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+
+func (db *fakeDB) Driver() driver.Driver { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("transactions not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error { return nil }
+
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "INSERT INTO messages") {
+		s.db.mu.Lock()
+		s.db.messages = append(s.db.messages, fakeMessage{
+			id: args[0].(string), conversationID: args[1].(string), parentID: args[2],
+			role: args[3].(string), content: args[4].(string), model: args[5].(string),
+			finishReason: args[6].(string), artifacts: args[7].([]byte), createdAt: args[8].(time.Time),
+		})
+		s.db.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if !strings.Contains(s.query, "FROM messages") {
+		return rows, nil
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	rows.columns = []string{"id", "conversation_id", "parent_id", "role", "content", "model", "finish_reason", "artifacts", "created_at"}
+	for _, msg := range s.db.messages {
+		if msg.conversationID != args[0] && msg.id != args[0] {
+			continue
+		}
+		rows.values = append(rows.values, []driver.Value{
+			msg.id, msg.conversationID, msg.parentID, msg.role, msg.content,
+			msg.model, msg.finishReason, msg.artifacts, msg.createdAt,
+		})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
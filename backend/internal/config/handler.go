@@ -199,19 +199,3 @@ func (h *Handler) DeleteChatModel(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Chat model deleted successfully"})
 }
-
-// Admin middleware to check if user is admin
-func (h *Handler) AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// For now, allow all authenticated users
-		// In production, you should check user role from database
-		userRole := c.GetString("user_role")
-		if userRole != "admin" {
-			// Temporary: allow all users for development
-			// c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			// c.Abort()
-			// return
-		}
-		c.Next()
-	}
-}
\ No newline at end of file
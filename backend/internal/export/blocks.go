@@ -0,0 +1,93 @@
+package export
+
+import "strings"
+
+// 内容块类型
+const (
+	blockParagraph = "paragraph"
+	blockHeading   = "heading"
+	blockCode      = "code"
+)
+
+// block 消息内容按Markdown拆分出的块，HTML与PDF导出共用
+type block struct {
+	Kind     string
+	Text     string
+	Language string
+	Level    int
+}
+
+// parseBlocks 将Markdown内容拆分为段落、标题与围栏代码块
+// 只处理排版所需的结构，列表、引用等保留原文作为段落
+func parseBlocks(content string) []block {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var blocks []block
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, block{Kind: blockParagraph, Text: strings.Join(paragraph, "\n")})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence := codeFence(trimmed); fence != "" {
+			flush()
+			language := strings.TrimSpace(strings.TrimPrefix(trimmed, fence))
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, block{Kind: blockCode, Text: strings.Join(code, "\n"), Language: language})
+			continue
+		}
+
+		if level := headingLevel(trimmed); level > 0 {
+			flush()
+			blocks = append(blocks, block{Kind: blockHeading, Text: strings.TrimSpace(trimmed[level:]), Level: level})
+			continue
+		}
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+
+	return blocks
+}
+
+// codeFence 返回行首的代码围栏标记（``` 或 ~~~，可以更长）
+func codeFence(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			end := len(marker)
+			for end < len(line) && line[end] == marker[0] {
+				end++
+			}
+			return line[:end]
+		}
+	}
+	return ""
+}
+
+// headingLevel 返回ATX标题的级别，不是标题时返回0
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
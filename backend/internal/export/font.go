@@ -0,0 +1,480 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+var errInvalidFont = fmt.Errorf("invalid font: not a TrueType font")
+
+// subsetTables 子集字体保留的表：PDF嵌入TrueType字体只需要这些表，字形通过CIDToGIDMap定位，不需要cmap
+var subsetTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// trueTypeFont 导出PDF时嵌入的TrueType字体，加载后只读，可被并发的导出共享
+type trueTypeFont struct {
+	name       string
+	unitsPerEm float64
+	numGlyphs  int
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+	capHeight  int16
+	cmap       map[rune]uint16
+	advances   []uint16
+	glyphs     [][]byte
+	tables     map[string][]byte
+}
+
+// loadTrueTypeFont 读取TrueType字体文件（.ttf，或.ttc中的第一个字体）
+func loadTrueTypeFont(path string) (*trueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+	return parseTrueType(data)
+}
+
+// parseTrueType 解析字体的度量、字符映射与字形数据
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	offset := 0
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		offset = int(binary.BigEndian.Uint32(data[12:]))
+	}
+	if offset < 0 || len(data) < offset+12 {
+		return nil, errInvalidFont
+	}
+	switch string(data[offset : offset+4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, fmt.Errorf("invalid font: CFF-based OpenType fonts are not supported, use a TrueType font")
+	default:
+		return nil, errInvalidFont
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	for i := 0; i < numTables; i++ {
+		record := offset + 12 + 16*i
+		if len(data) < record+16 {
+			return nil, errInvalidFont
+		}
+		start := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if start < 0 || length < 0 || start+length > len(data) {
+			return nil, errInvalidFont
+		}
+		tables[string(data[record:record+4])] = data[start : start+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("invalid font: missing %s table", tag)
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errInvalidFont
+	}
+	font := &trueTypeFont{
+		name:       postScriptName(tables["name"]),
+		unitsPerEm: float64(binary.BigEndian.Uint16(head[18:])),
+		numGlyphs:  int(binary.BigEndian.Uint16(maxp[4:])),
+		ascent:     int16(binary.BigEndian.Uint16(hhea[4:])),
+		descent:    int16(binary.BigEndian.Uint16(hhea[6:])),
+		tables:     tables,
+	}
+	if font.unitsPerEm == 0 || font.numGlyphs == 0 {
+		return nil, errInvalidFont
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+	font.capHeight = font.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		font.capHeight = int16(binary.BigEndian.Uint16(os2[88:]))
+	}
+
+	// hmtx只列出前numberOfHMetrics个字形的宽度，其余字形与最后一个相同
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < 4*numMetrics {
+		return nil, errInvalidFont
+	}
+	font.advances = make([]uint16, font.numGlyphs)
+	for g := range font.advances {
+		if g < numMetrics {
+			font.advances[g] = binary.BigEndian.Uint16(hmtx[4*g:])
+		} else {
+			font.advances[g] = font.advances[numMetrics-1]
+		}
+	}
+
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	loca, glyf := tables["loca"], tables["glyf"]
+	font.glyphs = make([][]byte, font.numGlyphs)
+	for g := range font.glyphs {
+		var start, end int
+		if longLoca {
+			if len(loca) < 4*(g+2) {
+				return nil, errInvalidFont
+			}
+			start = int(binary.BigEndian.Uint32(loca[4*g:]))
+			end = int(binary.BigEndian.Uint32(loca[4*g+4:]))
+		} else {
+			if len(loca) < 2*(g+2) {
+				return nil, errInvalidFont
+			}
+			start = 2 * int(binary.BigEndian.Uint16(loca[2*g:]))
+			end = 2 * int(binary.BigEndian.Uint16(loca[2*g+2:]))
+		}
+		if start < 0 || start > end || end > len(glyf) {
+			return nil, errInvalidFont
+		}
+		font.glyphs[g] = glyf[start:end]
+	}
+
+	cmap, err := parseCmap(tables["cmap"], font.numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	font.cmap = cmap
+	return font, nil
+}
+
+// parseCmap 解析Unicode字符映射，优先使用支持完整Unicode的format 12子表
+func parseCmap(table []byte, numGlyphs int) (map[rune]uint16, error) {
+	if len(table) < 4 {
+		return nil, errInvalidFont
+	}
+
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + 8*i
+		if len(table) < record+8 {
+			return nil, errInvalidFont
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		encoding := binary.BigEndian.Uint16(table[record+2:])
+		offset := int(binary.BigEndian.Uint32(table[record+4:]))
+		if offset < 0 || offset+4 > len(table) {
+			continue
+		}
+		unicode := platform == 0 || platform == 3 && (encoding == 1 || encoding == 10)
+		switch sub := table[offset:]; {
+		case unicode && binary.BigEndian.Uint16(sub) == 12:
+			format12 = sub
+		case unicode && binary.BigEndian.Uint16(sub) == 4:
+			format4 = sub
+		}
+	}
+
+	cmap := make(map[rune]uint16)
+	add := func(r rune, g uint32) {
+		if g != 0 && int(g) < numGlyphs {
+			cmap[r] = uint16(g)
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		if len(format12) < 16 {
+			return nil, errInvalidFont
+		}
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for i := 0; i < groups; i++ {
+			group := 16 + 12*i
+			if len(format12) < group+12 {
+				return nil, errInvalidFont
+			}
+			start := binary.BigEndian.Uint32(format12[group:])
+			end := binary.BigEndian.Uint32(format12[group+4:])
+			glyph := binary.BigEndian.Uint32(format12[group+8:])
+			if start > end || end > 0x10ffff {
+				continue
+			}
+			for c := start; c <= end; c++ {
+				add(rune(c), glyph+c-start)
+			}
+		}
+	case format4 != nil:
+		if len(format4) < 14 {
+			return nil, errInvalidFont
+		}
+		segments := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		if len(format4) < 16+8*segments {
+			return nil, errInvalidFont
+		}
+		for i := 0; i < segments; i++ {
+			end := int(binary.BigEndian.Uint16(format4[14+2*i:]))
+			start := int(binary.BigEndian.Uint16(format4[16+2*segments+2*i:]))
+			delta := int(binary.BigEndian.Uint16(format4[16+4*segments+2*i:]))
+			rangeOffsetPos := 16 + 6*segments + 2*i
+			rangeOffset := int(binary.BigEndian.Uint16(format4[rangeOffsetPos:]))
+			for c := start; c <= end && c != 0xffff; c++ {
+				if rangeOffset == 0 {
+					add(rune(c), uint32((c+delta)&0xffff))
+					continue
+				}
+				pos := rangeOffsetPos + rangeOffset + 2*(c-start)
+				if pos+2 > len(format4) {
+					continue
+				}
+				if g := int(binary.BigEndian.Uint16(format4[pos:])); g != 0 {
+					add(rune(c), uint32((g+delta)&0xffff))
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid font: no Unicode character map")
+	}
+	return cmap, nil
+}
+
+// postScriptName 获取字体的PostScript名称（name表中的6号名称），只保留PDF名称中可用的字符
+func postScriptName(table []byte) string {
+	if len(table) >= 6 {
+		count := int(binary.BigEndian.Uint16(table[2:]))
+		storage := int(binary.BigEndian.Uint16(table[4:]))
+		for i := 0; i < count; i++ {
+			record := 6 + 12*i
+			if len(table) < record+12 {
+				break
+			}
+			platform := binary.BigEndian.Uint16(table[record:])
+			length := int(binary.BigEndian.Uint16(table[record+8:]))
+			offset := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+			if binary.BigEndian.Uint16(table[record+6:]) != 6 || offset+length > len(table) {
+				continue
+			}
+
+			raw := table[offset : offset+length]
+			name := string(raw)
+			if platform == 0 || platform == 3 {
+				units := make([]uint16, len(raw)/2)
+				for j := range units {
+					units[j] = binary.BigEndian.Uint16(raw[2*j:])
+				}
+				name = string(utf16.Decode(units))
+			}
+			name = strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+					return r
+				}
+				return -1
+			}, name)
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return "EmbeddedFont"
+}
+
+// lookup 获取字符的字形，字体中没有的字符使用?的字形并返回?
+func (f *trueTypeFont) lookup(r rune) (uint16, rune) {
+	if g, ok := f.cmap[r]; ok {
+		return g, r
+	}
+	return f.cmap['?'], '?'
+}
+
+// width 字形宽度（千分之一字号）
+func (f *trueTypeFont) width(g uint16) float64 {
+	return float64(f.advances[g]) * 1000 / f.unitsPerEm
+}
+
+// scale 将字体单位换算为千分之一字号
+func (f *trueTypeFont) scale(value int16) int {
+	return int(float64(value) * 1000 / f.unitsPerEm)
+}
+
+// subsetName 子集字体名：6个大写字母的标签加原字体名，标签由用到的字形决定
+func (f *trueTypeFont) subsetName(used map[uint16]rune) string {
+	hash := crc32.NewIEEE()
+	for _, g := range sortedGlyphs(used) {
+		hash.Write([]byte{byte(g >> 8), byte(g)})
+	}
+	sum := hash.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag) + "+" + f.name
+}
+
+// subset 生成只包含用到字形的字体文件
+// 字形编号保持不变，未用到的字形置为空，因此CIDToGIDMap可以使用Identity
+func (f *trueTypeFont) subset(used map[uint16]rune) []byte {
+	keep := make(map[uint16]bool)
+	queue := append([]uint16{0}, sortedGlyphs(used)...)
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[g] || int(g) >= f.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		queue = append(queue, compositeComponents(f.glyphs[g])...)
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(glyf.Len()))
+		if keep[uint16(g)] {
+			glyf.Write(f.glyphs[g])
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(glyf.Len()))
+
+	// 使用长格式loca，校验和调整值在写出整个文件后计算
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{"glyf": glyf.Bytes(), "loca": loca, "head": head}
+	for _, tag := range subsetTables {
+		if tables[tag] == nil && f.tables[tag] != nil {
+			tables[tag] = f.tables[tag]
+		}
+	}
+	return writeFontFile(tables)
+}
+
+// compositeComponents 组合字形引用的组件字形
+func compositeComponents(glyph []byte) []uint16 {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	var components []uint16
+	for pos := 10; pos+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[pos:])
+		components = append(components, binary.BigEndian.Uint16(glyph[pos+2:]))
+		pos += 4
+		if flags&0x0001 != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			pos += 2
+		case flags&0x0040 != 0:
+			pos += 4
+		case flags&0x0080 != 0:
+			pos += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// writeFontFile 按表名排序写出字体文件，并回填head表的checkSumAdjustment
+func writeFontFile(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	var out bytes.Buffer
+	header := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(16*len(tags)-searchRange))
+
+	offset := len(header)
+	headOffset := 0
+	for i, tag := range tags {
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], fontChecksum(tables[tag]))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(tables[tag])))
+		if tag == "head" {
+			headOffset = offset
+		}
+		offset += (len(tables[tag]) + 3) &^ 3
+	}
+
+	out.Write(header)
+	for _, tag := range tags {
+		out.Write(tables[tag])
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+
+	data := out.Bytes()
+	binary.BigEndian.PutUint32(data[headOffset+8:], 0xb1b0afba-fontChecksum(data))
+	return data
+}
+
+// fontChecksum 按大端uint32累加，不足4字节补0
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// toUnicodeCMap 字形到Unicode的映射，使复制与搜索PDF中的文字得到原字符
+func toUnicodeCMap(used map[uint16]rune) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// 每个bfchar段最多100项
+	glyphs := sortedGlyphs(used)
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, g := range glyphs[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", g, encodeUTF16(string(used[g])))
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// sortedGlyphs 按编号排序的字形
+func sortedGlyphs(used map[uint16]rune) []uint16 {
+	glyphs := make([]uint16, 0, len(used))
+	for g := range used {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qicro/qicro/backend/internal/jobs"
+)
+
+// Handler 导出处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建导出处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ExportConversation 导出单个对话，format 为 md / json / html / pdf
+func (h *Handler) ExportConversation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	file, err := h.service.ExportConversation(c.Param("id"), userID.(string), c.Query("format"))
+	if err != nil {
+		jobs.WriteError(c, err)
+		return
+	}
+
+	jobs.Attachment(c, file.Name, file.ContentType, file.Data)
+}
+
+// StartBulkExport 创建批量导出任务，通过 /api/jobs/:id 查询进度并下载zip
+func (h *Handler) StartBulkExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req BulkExportRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Format == "" {
+		req.Format = c.Query("format")
+	}
+
+	job, err := h.service.StartBulkExport(userID.(string), req.Format)
+	if err != nil {
+		jobs.WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
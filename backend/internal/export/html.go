@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// inlineCodePattern 行内代码
+var inlineCodePattern = regexp.MustCompile("`([^`\n]+)`")
+
+// boldPattern 粗体
+var boldPattern = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+
+// htmlStyle 独立HTML文件的内联样式，不依赖任何外部资源
+const htmlStyle = `
+body { max-width: 820px; margin: 40px auto; padding: 0 20px; font: 15px/1.6 -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2328; }
+h1 { font-size: 24px; margin-bottom: 4px; }
+.meta { color: #656d76; font-size: 13px; margin-bottom: 24px; }
+.message { border-top: 1px solid #d0d7de; padding: 16px 0; }
+.role { font-weight: 600; margin-bottom: 8px; }
+.role time { font-weight: normal; color: #656d76; font-size: 12px; margin-left: 8px; }
+.user .role { color: #0969da; }
+.assistant .role { color: #1a7f37; }
+pre { background: #f6f8fa; border-radius: 6px; padding: 12px; overflow-x: auto; font-size: 13px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+p code { background: #f6f8fa; padding: 1px 4px; border-radius: 4px; }
+.sources { font-size: 13px; color: #656d76; }
+`
+
+// renderHTML 导出为独立的HTML文件，所有内容均经过转义
+func renderHTML(doc *Document) []byte {
+	var b strings.Builder
+	conv := doc.Conversation
+	title := html.EscapeString(conv.Title)
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", title, htmlStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", title)
+	fmt.Fprintf(&b, "<div class=\"meta\">%s · %s · exported %s</div>\n",
+		html.EscapeString(conv.Model), conv.CreatedAt.Format(timeLayout), doc.ExportedAt.Format(timeLayout))
+
+	for _, msg := range doc.Messages {
+		fmt.Fprintf(&b, "<section class=\"message %s\">\n", html.EscapeString(msg.Role))
+		fmt.Fprintf(&b, "<div class=\"role\">%s<time>%s</time></div>\n",
+			html.EscapeString(roleLabel(msg)), msg.CreatedAt.Format(timeLayout))
+
+		for _, blk := range parseBlocks(msg.Content) {
+			switch blk.Kind {
+			case blockCode:
+				class := ""
+				if blk.Language != "" {
+					class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(blk.Language))
+				}
+				fmt.Fprintf(&b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(blk.Text))
+			case blockHeading:
+				level := blk.Level + 1
+				if level > 6 {
+					level = 6
+				}
+				fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, renderInline(blk.Text), level)
+			default:
+				fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(renderInline(blk.Text), "\n", "<br>\n"))
+			}
+		}
+
+		if citations := messageCitations(msg); len(citations) > 0 {
+			b.WriteString("<ol class=\"sources\">\n")
+			for _, c := range citations {
+				fmt.Fprintf(&b, "<li><a href=\"%s\" rel=\"noopener noreferrer\">%s</a></li>\n",
+					html.EscapeString(safeURL(c.URL)), html.EscapeString(c.Title))
+			}
+			b.WriteString("</ol>\n")
+		}
+		b.WriteString("</section>\n")
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+// renderInline 转义文本并处理行内代码与粗体
+func renderInline(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = inlineCodePattern.ReplaceAllString(escaped, "<code>$1</code>")
+	return boldPattern.ReplaceAllString(escaped, "<strong>$1</strong>")
+}
+
+// safeURL 只保留http(s)链接，防止javascript:等协议
+func safeURL(url string) string {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return url
+	}
+	return "#"
+}
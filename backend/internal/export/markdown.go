@@ -0,0 +1,40 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+// renderMarkdown 导出为Markdown，消息内容原样保留（包括代码块）
+func renderMarkdown(doc *Document) []byte {
+	var b strings.Builder
+	conv := doc.Conversation
+
+	fmt.Fprintf(&b, "# %s\n\n", conv.Title)
+	fmt.Fprintf(&b, "- Model: %s\n", conv.Model)
+	fmt.Fprintf(&b, "- Created: %s\n", conv.CreatedAt.Format(timeLayout))
+	fmt.Fprintf(&b, "- Exported: %s\n", doc.ExportedAt.Format(timeLayout))
+	if len(conv.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(conv.Tags, ", "))
+	}
+
+	for _, msg := range doc.Messages {
+		fmt.Fprintf(&b, "\n---\n\n## %s\n\n", roleLabel(msg))
+		fmt.Fprintf(&b, "_%s_\n\n", msg.CreatedAt.Format(timeLayout))
+		b.WriteString(msg.Content)
+		if !strings.HasSuffix(msg.Content, "\n") {
+			b.WriteString("\n")
+		}
+
+		if citations := messageCitations(msg); len(citations) > 0 {
+			b.WriteString("\n**Sources**\n\n")
+			for _, c := range citations {
+				fmt.Fprintf(&b, "%d. [%s](%s)\n", c.Index, c.Title, c.URL)
+			}
+		}
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/qicro/qicro/backend/internal/artifact"
+	"github.com/qicro/qicro/backend/internal/chat"
+)
+
+// 导出格式
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// JobTypeExport 批量导出任务类型
+const JobTypeExport = "export"
+
+// formatInfo 导出格式对应的扩展名与Content-Type
+var formatInfo = map[string]struct {
+	Extension   string
+	ContentType string
+}{
+	FormatMarkdown: {".md", "text/markdown; charset=utf-8"},
+	FormatJSON:     {".json", "application/json; charset=utf-8"},
+	FormatHTML:     {".html", "text/html; charset=utf-8"},
+	FormatPDF:      {".pdf", "application/pdf"},
+}
+
+// Document 待导出的对话：Messages为当前活动分支，Tree为全部分支（仅JSON导出使用）
+type Document struct {
+	Conversation *chat.Conversation
+	Messages     []chat.Message
+	Tree         []chat.Message
+	Artifacts    []artifact.Artifact
+	ExportedAt   time.Time
+}
+
+// JSONExport JSON导出结构，包含全部分支、消息artifacts（引用、token用量等）与artifact正文，可用于无损导入
+type JSONExport struct {
+	Version      int                 `json:"version"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	Conversation *chat.Conversation  `json:"conversation"`
+	Messages     []chat.Message      `json:"messages"`
+	Artifacts    []artifact.Artifact `json:"artifacts"`
+}
+
+// File 导出生成的文件
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// BulkExportRequest 批量导出请求
+type BulkExportRequest struct {
+	Format string `json:"format"`
+}
+
+// citation 联网搜索引用
+type citation struct {
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// messageCitations 读取消息中保存的联网搜索引用
+// 从数据库读出的artifacts是通用map，这里经JSON转换为具体结构
+func messageCitations(msg chat.Message) []citation {
+	value, ok := msg.Artifacts["citations"]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var citations []citation
+	if err := json.Unmarshal(data, &citations); err != nil {
+		return nil
+	}
+	return citations
+}
+
+// roleLabel 消息角色的显示名称
+func roleLabel(msg chat.Message) string {
+	switch msg.Role {
+	case "user":
+		return "User"
+	case "assistant":
+		if msg.Model != "" {
+			return "Assistant · " + msg.Model
+		}
+		return "Assistant"
+	default:
+		return msg.Role
+	}
+}
@@ -0,0 +1,360 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PDF版式（单位：point，A4）
+const (
+	pdfPageWidth   = 595.28
+	pdfPageHeight  = 841.89
+	pdfMargin      = 56.0
+	pdfTitleSize   = 18.0
+	pdfHeadingSize = 12.5
+	pdfBodySize    = 10.5
+	pdfCodeSize    = 9.0
+	pdfMetaSize    = 8.5
+	pdfLeading     = 1.45
+	pdfCodeIndent  = 8.0
+	pdfTabWidth    = 4
+)
+
+// pdfFont 未配置嵌入字体时引用PDF阅读器内置的Adobe中文字体（STSong-Light + UniGB-UCS2-H）
+// 该字体不随文件分发，没有安装Adobe亚洲字体包的阅读器无法显示，生产环境应通过EXPORT_PDF_FONT配置嵌入字体
+// Adobe-GB1中CID 1-95为ASCII字符，这里统一设为半角宽度，其余字符按全角计算
+const pdfFont = "STSong-Light"
+
+// pdfDocument 纯Go实现的简单PDF生成器，只支持文本、矩形与直线
+// font非空时嵌入该字体中用到的字形，used记录用到的字形及其对应的字符
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+	font  *trueTypeFont
+	used  map[uint16]rune
+}
+
+// renderPDF 导出为PDF：标题、每条消息的角色与时间、正文段落与代码块
+func renderPDF(doc *Document, font *trueTypeFont) ([]byte, error) {
+	pdf := &pdfDocument{font: font, used: make(map[uint16]rune)}
+	pdf.newPage()
+	conv := doc.Conversation
+
+	pdf.paragraph(conv.Title, pdfTitleSize, 0, 0)
+	pdf.paragraph(fmt.Sprintf("%s · %s · exported %s", conv.Model, conv.CreatedAt.Format(timeLayout),
+		doc.ExportedAt.Format(timeLayout)), pdfMetaSize, 0, 0.4)
+	pdf.space(pdfBodySize)
+
+	for _, msg := range doc.Messages {
+		pdf.rule()
+		gray := 0.1
+		if msg.Role == "user" {
+			gray = 0.25
+		}
+		pdf.paragraph(roleLabel(msg)+"  "+msg.CreatedAt.Format(timeLayout), pdfHeadingSize-1, 0, gray)
+		pdf.space(pdfBodySize * 0.3)
+
+		for _, blk := range parseBlocks(msg.Content) {
+			switch blk.Kind {
+			case blockCode:
+				pdf.code(blk.Text)
+			case blockHeading:
+				pdf.paragraph(blk.Text, pdfHeadingSize, 0, 0)
+			default:
+				pdf.paragraph(blk.Text, pdfBodySize, 0, 0)
+			}
+			pdf.space(pdfBodySize * 0.5)
+		}
+
+		if citations := messageCitations(msg); len(citations) > 0 {
+			pdf.paragraph("Sources", pdfMetaSize, 0, 0.4)
+			for _, c := range citations {
+				pdf.paragraph(fmt.Sprintf("[%d] %s  %s", c.Index, c.Title, c.URL), pdfMetaSize, 0, 0.4)
+			}
+			pdf.space(pdfBodySize * 0.5)
+		}
+	}
+
+	return pdf.bytes(conv.Title)
+}
+
+// newPage 开始新的一页
+func (p *pdfDocument) newPage() {
+	p.page = &bytes.Buffer{}
+	p.pages = append(p.pages, p.page)
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// ensure 剩余空间不足时换页
+func (p *pdfDocument) ensure(height float64) {
+	if p.y-height < pdfMargin {
+		p.newPage()
+	}
+}
+
+// space 垂直留白
+func (p *pdfDocument) space(height float64) {
+	p.y -= height
+}
+
+// rule 绘制消息之间的分隔线
+func (p *pdfDocument) rule() {
+	p.ensure(pdfBodySize * 3)
+	fmt.Fprintf(p.page, "0.8 G 0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		pdfMargin, p.y, pdfPageWidth-pdfMargin, p.y)
+	p.y -= pdfBodySize
+}
+
+// paragraph 按可用宽度自动换行输出文本，gray为灰度（0为黑色）
+func (p *pdfDocument) paragraph(text string, size, indent, gray float64) {
+	width := pdfPageWidth - 2*pdfMargin - indent
+	for _, line := range p.wrapText(text, width, size) {
+		p.ensure(size * pdfLeading)
+		p.y -= size * pdfLeading
+		p.text(line, pdfMargin+indent, p.y, size, gray)
+	}
+}
+
+// code 输出代码块：逐行绘制浅灰背景，长行折行
+func (p *pdfDocument) code(text string) {
+	width := pdfPageWidth - 2*pdfMargin - 2*pdfCodeIndent
+	lineHeight := pdfCodeSize * pdfLeading
+	for _, line := range p.wrapText(text, width, pdfCodeSize) {
+		p.ensure(lineHeight)
+		p.y -= lineHeight
+		fmt.Fprintf(p.page, "0.95 g %.2f %.2f %.2f %.2f re f\n",
+			pdfMargin, p.y-pdfCodeSize*0.35, pdfPageWidth-2*pdfMargin, lineHeight)
+		p.text(line, pdfMargin+pdfCodeIndent, p.y, pdfCodeSize, 0.15)
+	}
+}
+
+// text 在指定位置绘制一行文本
+func (p *pdfDocument) text(line string, x, y, size, gray float64) {
+	if line == "" {
+		return
+	}
+	fmt.Fprintf(p.page, "BT %.2f g /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", gray, size, x, y, p.encode(line))
+}
+
+// encode 将一行文本编码为十六进制串：内置字体为UCS-2编码，嵌入字体为字形编号并记录用到的字形
+func (p *pdfDocument) encode(line string) string {
+	if p.font == nil {
+		return encodeUCS2(line)
+	}
+	var b strings.Builder
+	for _, r := range line {
+		g, r := p.font.lookup(printableRune(r))
+		if _, ok := p.used[g]; !ok {
+			p.used[g] = r
+		}
+		fmt.Fprintf(&b, "%04X", g)
+	}
+	return b.String()
+}
+
+// runeWidth 字符宽度（以字号为单位），嵌入字体时使用字形的实际宽度
+func (p *pdfDocument) runeWidth(r rune) float64 {
+	if p.font == nil {
+		return runeWidth(r)
+	}
+	g, _ := p.font.lookup(printableRune(r))
+	return p.font.width(g) / 1000
+}
+
+// bytes 组装PDF对象、交叉引用表与文件尾
+func (p *pdfDocument) bytes(title string) ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+
+	addObject := func(body string) int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", id, body)
+		return id
+	}
+	addStream := func(dict string, data []byte) int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+		out.Write(data)
+		out.WriteString("\nendstream\nendobj\n")
+		return id
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 对象1-6：目录、页面树、信息字典与字体；页面树与页面互相引用，先确定编号
+	// 嵌入字体时字体文件与ToUnicode映射在全部页面之后
+	pageCount := len(p.pages)
+	pagesID := 2
+	fontID := 4
+	firstPageID := 7
+	fontFileID := firstPageID + 2*pageCount
+	toUnicodeID := fontFileID + 1
+	kids := make([]string, pageCount)
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageID+2*i)
+	}
+
+	addObject(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	infoID := addObject(fmt.Sprintf("<< /Title <FEFF%s> /Producer (Qicro) >>", encodeUTF16(title)))
+	if p.font == nil {
+		addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /UniGB-UCS2-H /DescendantFonts [5 0 R] >>", pdfFont))
+		addObject(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> "+
+			"/FontDescriptor 6 0 R /DW 1000 /W [1 95 500] >>", pdfFont))
+		addObject(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [-25 -254 1000 880] "+
+			"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", pdfFont))
+	} else {
+		// 文本按字形编号编码（Identity-H），字形编号即CID
+		baseFont := p.font.subsetName(p.used)
+		var widths strings.Builder
+		for _, g := range sortedGlyphs(p.used) {
+			fmt.Fprintf(&widths, "%d [%d] ", g, int(p.font.width(g)))
+		}
+		addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+			"/DescendantFonts [5 0 R] /ToUnicode %d 0 R >>", baseFont, toUnicodeID))
+		addObject(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor 6 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", baseFont, strings.TrimSpace(widths.String())))
+		addObject(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] "+
+			"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			baseFont, p.font.scale(p.font.bbox[0]), p.font.scale(p.font.bbox[1]), p.font.scale(p.font.bbox[2]),
+			p.font.scale(p.font.bbox[3]), p.font.scale(p.font.ascent), p.font.scale(p.font.descent),
+			p.font.scale(p.font.capHeight), fontFileID))
+	}
+
+	for i, page := range p.pages {
+		compressed, err := deflate(page.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress page: %w", err)
+		}
+
+		pageID := addObject(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pdfPageWidth, pdfPageHeight, fontID, firstPageID+2*i+1))
+		if pageID != firstPageID+2*i {
+			return nil, fmt.Errorf("unexpected pdf object layout")
+		}
+		addStream("/Filter /FlateDecode", compressed)
+	}
+
+	if p.font != nil {
+		fontFile := p.font.subset(p.used)
+		compressed, err := deflate(fontFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress font: %w", err)
+		}
+		if id := addStream(fmt.Sprintf("/Filter /FlateDecode /Length1 %d", len(fontFile)), compressed); id != fontFileID {
+			return nil, fmt.Errorf("unexpected pdf object layout")
+		}
+		addStream("", toUnicodeCMap(p.used))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, infoID, xref)
+
+	return out.Bytes(), nil
+}
+
+// wrapText 按宽度折行：优先在空格处断行，没有空格时（如中文）可在任意位置断行
+func (p *pdfDocument) wrapText(text string, width, size float64) []string {
+	var lines []string
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		runes := []rune(strings.ReplaceAll(raw, "\t", strings.Repeat(" ", pdfTabWidth)))
+		if len(runes) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		start, lastSpace := 0, -1
+		lineWidth := 0.0
+		for i := 0; i < len(runes); i++ {
+			w := p.runeWidth(runes[i]) * size
+			if lineWidth+w > width && i > start {
+				end := i
+				if lastSpace > start {
+					end = lastSpace + 1
+				}
+				lines = append(lines, strings.TrimRight(string(runes[start:end]), " "))
+				start, lastSpace = end, -1
+				lineWidth = 0
+				for j := start; j < i; j++ {
+					lineWidth += p.runeWidth(runes[j]) * size
+				}
+			}
+			if runes[i] == ' ' {
+				lastSpace = i
+			}
+			lineWidth += w
+		}
+		lines = append(lines, string(runes[start:]))
+	}
+	return lines
+}
+
+// runeWidth 内置字体的字符宽度（以字号为单位）：ASCII按半角计算，其余字符按全角计算
+func runeWidth(r rune) float64 {
+	if r >= 0x20 && r <= 0x7e {
+		return 0.5
+	}
+	return 1
+}
+
+// encodeUCS2 将文本编码为UCS-2大端十六进制串；超出基本平面的字符（如emoji）与控制字符替换为?
+func encodeUCS2(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r = printableRune(r); r > 0xffff {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// printableRune 制表符替换为空格，控制字符与不可打印字符替换为?
+func printableRune(r rune) rune {
+	switch {
+	case r == '\t':
+		return ' '
+	case r == ' ':
+		return r
+	case r < 0x20 || (r >= 0xd800 && r <= 0xdfff) || !unicode.IsPrint(r):
+		return '?'
+	}
+	return r
+}
+
+// deflate 使用zlib压缩流数据
+func deflate(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// encodeUTF16 将文本编码为UTF-16大端十六进制串（用于文档信息字典）
+func encodeUTF16(text string) string {
+	var b strings.Builder
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	return b.String()
+}
@@ -0,0 +1,271 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/qicro/qicro/backend/internal/artifact"
+	"github.com/qicro/qicro/backend/internal/chat"
+	"github.com/qicro/qicro/backend/internal/jobs"
+)
+
+const (
+	jsonExportVersion = 1
+	maxFileNameRunes  = 80
+	bulkPageSize      = 100
+)
+
+// Service 对话导出服务
+type Service struct {
+	chatService     *chat.Service
+	artifactService *artifact.Service
+	jobService      *jobs.Service
+	pdfFont         *trueTypeFont
+}
+
+// NewService 创建导出服务
+func NewService(chatService *chat.Service, artifactService *artifact.Service, jobService *jobs.Service) *Service {
+	return &Service{
+		chatService:     chatService,
+		artifactService: artifactService,
+		jobService:      jobService,
+	}
+}
+
+// SetPDFFont 设置PDF导出嵌入的TrueType字体（.ttf或.ttc），导出时只嵌入用到的字形
+func (s *Service) SetPDFFont(path string) error {
+	font, err := loadTrueTypeFont(path)
+	if err != nil {
+		return err
+	}
+	s.pdfFont = font
+	return nil
+}
+
+// ExportConversation 按指定格式导出单个对话
+func (s *Service) ExportConversation(conversationID, userID, format string) (*File, error) {
+	format, err := normalizeFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.chatService.GetConversation(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found")
+	}
+	if conv.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to conversation")
+	}
+
+	doc, err := s.loadDocument(conv, format)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.render(doc, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Name:        fileName(conv) + formatInfo[format].Extension,
+		ContentType: formatInfo[format].ContentType,
+		Data:        data,
+	}, nil
+}
+
+// StartBulkExport 以后台任务导出用户的全部对话（包括已归档），结果为zip文件
+func (s *Service) StartBulkExport(userID, format string) (*jobs.Job, error) {
+	format, err := normalizeFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.jobService.Submit(userID, JobTypeExport, func(ctx context.Context, report jobs.ReportFunc) (*jobs.Output, error) {
+		return s.bulkExport(ctx, userID, format, report)
+	})
+}
+
+// bulkExport 逐个导出对话并打包，单个对话失败时记录在结果中而不中止整个任务
+func (s *Service) bulkExport(ctx context.Context, userID, format string, report jobs.ReportFunc) (*jobs.Output, error) {
+	summaries, err := s.listAllConversations(userID)
+	if err != nil {
+		return nil, err
+	}
+	report(0, len(summaries))
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	usedNames := make(map[string]bool, len(summaries))
+	var failed []string
+
+	for i, summary := range summaries {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("export cancelled: %w", err)
+		}
+
+		if err := s.addToArchive(archive, summary.ID, format, usedNames); err != nil {
+			failed = append(failed, summary.ID)
+		}
+		report(i+1, len(summaries))
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return &jobs.Output{
+		Result: map[string]interface{}{
+			"format":   format,
+			"exported": len(summaries) - len(failed),
+			"failed":   failed,
+		},
+		FileName:    fmt.Sprintf("qicro-export-%s.zip", time.Now().Format("20060102-150405")),
+		ContentType: "application/zip",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// addToArchive 导出单个对话并写入zip，重名时追加序号
+func (s *Service) addToArchive(archive *zip.Writer, conversationID, format string, usedNames map[string]bool) error {
+	conv, err := s.chatService.GetConversation(conversationID)
+	if err != nil {
+		return err
+	}
+	doc, err := s.loadDocument(conv, format)
+	if err != nil {
+		return err
+	}
+	data, err := s.render(doc, format)
+	if err != nil {
+		return err
+	}
+
+	base := conv.CreatedAt.Format("2006-01-02") + " " + fileName(conv)
+	name := base + formatInfo[format].Extension
+	for n := 2; usedNames[name]; n++ {
+		name = fmt.Sprintf("%s (%d)%s", base, n, formatInfo[format].Extension)
+	}
+	usedNames[name] = true
+
+	writer, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: conv.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// listAllConversations 分页读取用户的全部对话
+func (s *Service) listAllConversations(userID string) ([]chat.ConversationSummary, error) {
+	var summaries []chat.ConversationSummary
+	opts := chat.ConversationListOptions{
+		Limit:     bulkPageSize,
+		Sort:      chat.SortCreatedAt,
+		Ascending: true,
+		Archived:  "all",
+	}
+	for {
+		page, err := s.chatService.ListConversations(userID, opts)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, page.Conversations...)
+		if !page.HasMore {
+			return summaries, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// loadDocument 读取导出所需的消息；JSON导出额外读取全部分支与artifact正文
+func (s *Service) loadDocument(conv *chat.Conversation, format string) (*Document, error) {
+	doc := &Document{Conversation: conv, ExportedAt: time.Now()}
+
+	if format == FormatJSON {
+		tree, err := s.chatService.GetMessageTree(conv.ID)
+		if err != nil {
+			return nil, err
+		}
+		doc.Tree = tree
+		doc.Artifacts = []artifact.Artifact{}
+		if s.artifactService != nil {
+			if doc.Artifacts, err = s.artifactService.GetConversationArtifacts(conv.ID); err != nil {
+				return nil, err
+			}
+		}
+		return doc, nil
+	}
+
+	messages, err := s.chatService.GetMessages(conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	doc.Messages = messages
+	return doc, nil
+}
+
+// render 按格式生成文件内容
+func (s *Service) render(doc *Document, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(JSONExport{
+			Version:      jsonExportVersion,
+			ExportedAt:   doc.ExportedAt,
+			Conversation: doc.Conversation,
+			Messages:     doc.Tree,
+			Artifacts:    doc.Artifacts,
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal conversation: %w", err)
+		}
+		return data, nil
+	case FormatHTML:
+		return renderHTML(doc), nil
+	case FormatPDF:
+		return renderPDF(doc, s.pdfFont)
+	default:
+		return renderMarkdown(doc), nil
+	}
+}
+
+// normalizeFormat 校验导出格式，默认Markdown
+func normalizeFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || format == "markdown" {
+		return FormatMarkdown, nil
+	}
+	if _, ok := formatInfo[format]; !ok {
+		return "", fmt.Errorf("invalid format: must be md, json, html or pdf")
+	}
+	return format, nil
+}
+
+// fileName 由对话标题生成安全的文件名
+func fileName(conv *chat.Conversation) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(conv.Title))
+	name = strings.Trim(name, ". ")
+
+	if utf8.RuneCountInString(name) > maxFileNameRunes {
+		name = string([]rune(name)[:maxFileNameRunes])
+	}
+	if name == "" {
+		name = "conversation-" + conv.ID[:8]
+	}
+	return name
+}
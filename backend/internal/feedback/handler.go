@@ -0,0 +1,191 @@
+package feedback
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qicro/qicro/backend/internal/auth"
+)
+
+// Handler 评价处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建评价处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SubmitFeedback 提交或更新对助手消息的评价
+func (h *Handler) SubmitFeedback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req SubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feedback, err := h.service.SubmitFeedback(c.Param("id"), userID.(string), req)
+	if err != nil {
+		writeFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// GetFeedback 获取自己对消息的评价
+func (h *Handler) GetFeedback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	feedback, err := h.service.GetFeedback(c.Param("id"), userID.(string))
+	if err != nil {
+		writeFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// DeleteFeedback 撤销评价
+func (h *Handler) DeleteFeedback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.DeleteFeedback(c.Param("id"), userID.(string)); err != nil {
+		writeFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "feedback deleted successfully"})
+}
+
+// GetReasons 获取可选的评价原因
+func (h *Handler) GetReasons(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"reasons": h.service.GetReasons()})
+}
+
+// GetReport 管理员查看按模型与助手聚合的评价报表
+func (h *Handler) GetReport(c *gin.Context) {
+	if !requirePermission(c, auth.PermUsageRead) {
+		return
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.GetReport(filter)
+	if err != nil {
+		writeFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportDataset 管理员导出评价数据集（JSONL）
+func (h *Handler) ExportDataset(c *gin.Context) {
+	if !requirePermission(c, auth.PermDataExport) {
+		return
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateFilter(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"feedback-%s.jsonl\"", time.Now().Format("20060102")))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，导出中途失败只能记录日志
+	if _, err := h.service.ExportDataset(c.Writer, filter); err != nil {
+		fmt.Printf("Warning: feedback export interrupted: %v\n", err)
+	}
+}
+
+// parseFilter 解析 rating、model、from、to（YYYY-MM-DD 或 RFC3339）与 limit
+func parseFilter(c *gin.Context) (Filter, error) {
+	filter := Filter{
+		Rating: c.Query("rating"),
+		Model:  c.Query("model"),
+	}
+
+	var err error
+	if filter.From, err = parseTime(c.Query("from"), false); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseTime(c.Query("to"), true); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil {
+			return filter, fmt.Errorf("invalid limit")
+		}
+	}
+	return filter, nil
+}
+
+// parseTime 解析时间参数，仅日期的结束时间取次日零点（不含）
+func parseTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC3339")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// writeFeedbackError 根据错误类型返回对应状态码
+func writeFeedbackError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(err.Error(), "invalid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// requirePermission 报表与导出包含全部用户的数据，处理器自身也校验权限，不依赖路由是否挂载了权限中间件
+func requirePermission(c *gin.Context, permission string) bool {
+	if !auth.HasPermission(c.GetString("user_role"), permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied: " + permission + " required"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package feedback
+
+import "time"
+
+// 评分
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// reasonsByRating 各评分可选的原因分类
+var reasonsByRating = map[string][]string{
+	RatingUp:   {"accurate", "helpful", "well_written", "creative", "followed_instructions"},
+	RatingDown: {"inaccurate", "unhelpful", "incomplete", "too_long", "too_short", "off_topic", "bad_formatting", "unsafe", "ignored_instructions", "other"},
+}
+
+// Feedback 用户对助手消息的评价，每个用户对每条消息只保留一条
+type Feedback struct {
+	ID        string    `json:"id" db:"id"`
+	MessageID string    `json:"message_id" db:"message_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Rating    string    `json:"rating" db:"rating"`
+	Reasons   []string  `json:"reasons" db:"reasons"`
+	Comment   string    `json:"comment" db:"comment"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SubmitRequest 提交评价请求
+type SubmitRequest struct {
+	Rating  string   `json:"rating" binding:"required"`
+	Reasons []string `json:"reasons"`
+	Comment string   `json:"comment"`
+}
+
+// Filter 报表与导出的过滤条件
+type Filter struct {
+	Rating string
+	Model  string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+}
+
+// ReportRow 按模型或助手聚合的评价统计
+type ReportRow struct {
+	Key          string         `json:"key"`
+	Name         string         `json:"name"`
+	Total        int            `json:"total"`
+	Up           int            `json:"up"`
+	Down         int            `json:"down"`
+	Satisfaction float64        `json:"satisfaction"`
+	Reasons      map[string]int `json:"reasons"`
+}
+
+// Report 评价报表
+type Report struct {
+	Total       int         `json:"total"`
+	Up          int         `json:"up"`
+	Down        int         `json:"down"`
+	ByModel     []ReportRow `json:"by_model"`
+	ByAssistant []ReportRow `json:"by_assistant"`
+}
+
+// Record 导出用的评价记录
+type Record struct {
+	Feedback
+	ConversationID string
+	Model          string
+	AssistantID    *string
+	SystemPrompt   string
+}
+
+// DatasetMessage 数据集中的消息（OpenAI chat格式）
+type DatasetMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// DatasetLine JSONL数据集中的一行：评价消息及其之前的对话上下文
+type DatasetLine struct {
+	Messages []DatasetMessage `json:"messages"`
+	Rating   string           `json:"rating"`
+	Reasons  []string         `json:"reasons"`
+	Comment  string           `json:"comment,omitempty"`
+	Metadata DatasetMetadata  `json:"metadata"`
+}
+
+// DatasetMetadata 数据集行的元数据
+type DatasetMetadata struct {
+	MessageID      string    `json:"message_id"`
+	ConversationID string    `json:"conversation_id"`
+	Model          string    `json:"model"`
+	AssistantID    *string   `json:"assistant_id,omitempty"`
+	RatedAt        time.Time `json:"rated_at"`
+}
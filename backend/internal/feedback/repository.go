@@ -0,0 +1,225 @@
+package feedback
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Repository 评价仓库
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository 创建评价仓库
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// modelExpr 消息的实际模型：消息未记录时使用对话的模型
+const modelExpr = `COALESCE(NULLIF(m.model, ''), c.model, '')`
+
+// UpsertFeedback 保存评价，同一用户对同一消息重复提交时覆盖
+func (r *Repository) UpsertFeedback(feedback *Feedback) error {
+	query := `
+		INSERT INTO message_feedback (id, message_id, user_id, rating, reasons, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET rating = EXCLUDED.rating, reasons = EXCLUDED.reasons, comment = EXCLUDED.comment, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(query, feedback.ID, feedback.MessageID, feedback.UserID, feedback.Rating,
+		pq.Array(feedback.Reasons), feedback.Comment, feedback.CreatedAt, feedback.UpdatedAt).
+		Scan(&feedback.ID, &feedback.CreatedAt)
+}
+
+// GetFeedback 获取用户对消息的评价
+func (r *Repository) GetFeedback(messageID, userID string) (*Feedback, error) {
+	query := `
+		SELECT id, message_id, user_id, rating, reasons, comment, created_at, updated_at
+		FROM message_feedback
+		WHERE message_id = $1 AND user_id = $2`
+
+	var feedback Feedback
+	err := r.db.QueryRow(query, messageID, userID).Scan(&feedback.ID, &feedback.MessageID, &feedback.UserID,
+		&feedback.Rating, pq.Array(&feedback.Reasons), &feedback.Comment, &feedback.CreatedAt, &feedback.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("feedback not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// DeleteFeedback 删除用户对消息的评价
+func (r *Repository) DeleteFeedback(messageID, userID string) error {
+	_, err := r.db.Exec(`DELETE FROM message_feedback WHERE message_id = $1 AND user_id = $2`, messageID, userID)
+	return err
+}
+
+// GetReportRows 按分组聚合评价数量；keyExpr与nameExpr为分组键与显示名称的SQL表达式
+func (r *Repository) GetReportRows(keyExpr, nameExpr string, filter Filter) ([]ReportRow, error) {
+	where, args := filterConditions(filter)
+	query := fmt.Sprintf(`
+		SELECT %[1]s AS key, MAX(%[2]s), COUNT(*),
+			COUNT(*) FILTER (WHERE f.rating = 'up'), COUNT(*) FILTER (WHERE f.rating = 'down')
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		JOIN conversations c ON c.id = m.conversation_id
+		LEFT JOIN assistants a ON a.id = c.assistant_id
+		WHERE %[3]s
+		GROUP BY 1
+		ORDER BY 3 DESC, 1`, keyExpr, nameExpr, where)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []ReportRow{}
+	for rows.Next() {
+		row := ReportRow{Reasons: map[string]int{}}
+		if err := rows.Scan(&row.Key, &row.Name, &row.Total, &row.Up, &row.Down); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reasonQuery := fmt.Sprintf(`
+		SELECT %[1]s AS key, reason, COUNT(*)
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		JOIN conversations c ON c.id = m.conversation_id
+		LEFT JOIN assistants a ON a.id = c.assistant_id
+		CROSS JOIN UNNEST(f.reasons) AS reason
+		WHERE %[2]s
+		GROUP BY 1, 2`, keyExpr, where)
+
+	reasonRows, err := r.db.Query(reasonQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer reasonRows.Close()
+
+	index := make(map[string]int, len(result))
+	for i, row := range result {
+		index[row.Key] = i
+	}
+	for reasonRows.Next() {
+		var key, reason string
+		var count int
+		if err := reasonRows.Scan(&key, &reason, &count); err != nil {
+			return nil, err
+		}
+		if i, ok := index[key]; ok {
+			result[i].Reasons[reason] = count
+		}
+	}
+
+	return result, reasonRows.Err()
+}
+
+// ListRecords 按条件列出评价记录（按评价时间倒序）
+func (r *Repository) ListRecords(filter Filter) ([]Record, error) {
+	where, args := filterConditions(filter)
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT f.id, f.message_id, f.user_id, f.rating, f.reasons, f.comment, f.created_at, f.updated_at,
+			m.conversation_id, %s, c.assistant_id, COALESCE(c.settings->>'system_prompt', '')
+		FROM message_feedback f
+		JOIN messages m ON m.id = f.message_id
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE %s
+		ORDER BY f.updated_at DESC
+		LIMIT $%d`, modelExpr, where, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var record Record
+		var assistantID sql.NullString
+		err := rows.Scan(&record.ID, &record.MessageID, &record.UserID, &record.Rating,
+			pq.Array(&record.Reasons), &record.Comment, &record.CreatedAt, &record.UpdatedAt,
+			&record.ConversationID, &record.Model, &assistantID, &record.SystemPrompt)
+		if err != nil {
+			return nil, err
+		}
+		if assistantID.Valid {
+			record.AssistantID = &assistantID.String
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// GetHistories 一次查询多条消息从根消息到该消息的路径（包含该消息），按消息ID分组
+func (r *Repository) GetHistories(messageIDs []string) (map[string][]DatasetMessage, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT id AS leaf_id, id, parent_id, 1 AS depth
+			FROM messages
+			WHERE id = ANY($1::uuid[])
+			UNION ALL
+			SELECT p.leaf_id, m.id, m.parent_id, p.depth + 1
+			FROM messages m
+			JOIN path p ON m.id = p.parent_id
+		)
+		SELECT p.leaf_id, m.role, m.content
+		FROM path p
+		JOIN messages m ON m.id = p.id
+		ORDER BY p.leaf_id, p.depth DESC`
+
+	rows, err := r.db.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histories := make(map[string][]DatasetMessage, len(messageIDs))
+	for rows.Next() {
+		var leafID string
+		var msg DatasetMessage
+		if err := rows.Scan(&leafID, &msg.Role, &msg.Content); err != nil {
+			return nil, err
+		}
+		histories[leafID] = append(histories[leafID], msg)
+	}
+
+	return histories, rows.Err()
+}
+
+// filterConditions 构造过滤条件
+func filterConditions(filter Filter) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	if filter.Rating != "" {
+		args = append(args, filter.Rating)
+		conditions = append(conditions, fmt.Sprintf("f.rating = $%d", len(args)))
+	}
+	if filter.Model != "" {
+		args = append(args, filter.Model)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", modelExpr, len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("f.updated_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("f.updated_at < $%d", len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
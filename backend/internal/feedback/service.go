@@ -0,0 +1,242 @@
+package feedback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/qicro/qicro/backend/internal/chat"
+)
+
+const (
+	maxCommentRunes    = 2000
+	defaultExportLimit = 10000
+	maxExportLimit     = 100000
+	// exportBatchSize 导出时每次加载对话上下文的记录数
+	exportBatchSize = 500
+)
+
+// Service 消息评价服务
+type Service struct {
+	repo        *Repository
+	chatService *chat.Service
+}
+
+// NewService 创建评价服务
+func NewService(repo *Repository, chatService *chat.Service) *Service {
+	return &Service{
+		repo:        repo,
+		chatService: chatService,
+	}
+}
+
+// SubmitFeedback 评价自己对话中的助手消息，重复提交覆盖之前的评价
+func (s *Service) SubmitFeedback(messageID, userID string, req SubmitRequest) (*Feedback, error) {
+	if _, err := s.getRatableMessage(messageID, userID); err != nil {
+		return nil, err
+	}
+
+	allowed, ok := reasonsByRating[req.Rating]
+	if !ok {
+		return nil, fmt.Errorf("invalid rating: must be up or down")
+	}
+	reasons := []string{}
+	seen := make(map[string]bool, len(req.Reasons))
+	for _, reason := range req.Reasons {
+		if !contains(allowed, reason) {
+			return nil, fmt.Errorf("invalid reason for %s rating: %s (allowed: %s)", req.Rating, reason, strings.Join(allowed, ", "))
+		}
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxCommentRunes {
+		return nil, fmt.Errorf("invalid comment: must be at most %d characters", maxCommentRunes)
+	}
+
+	now := time.Now()
+	feedback := &Feedback{
+		ID:        uuid.New().String(),
+		MessageID: messageID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Reasons:   reasons,
+		Comment:   comment,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.UpsertFeedback(feedback); err != nil {
+		return nil, fmt.Errorf("failed to save feedback: %w", err)
+	}
+	return feedback, nil
+}
+
+// GetFeedback 获取自己对消息的评价
+func (s *Service) GetFeedback(messageID, userID string) (*Feedback, error) {
+	if _, err := s.getRatableMessage(messageID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetFeedback(messageID, userID)
+}
+
+// DeleteFeedback 撤销评价
+func (s *Service) DeleteFeedback(messageID, userID string) error {
+	if _, err := s.getRatableMessage(messageID, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteFeedback(messageID, userID); err != nil {
+		return fmt.Errorf("failed to delete feedback: %w", err)
+	}
+	return nil
+}
+
+// GetReasons 获取各评分可选的原因
+func (s *Service) GetReasons() map[string][]string {
+	return reasonsByRating
+}
+
+// GetReport 按模型与助手聚合评价
+func (s *Service) GetReport(filter Filter) (*Report, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	byModel, err := s.repo.GetReportRows(modelExpr, modelExpr, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback by model: %w", err)
+	}
+	byAssistant, err := s.repo.GetReportRows("COALESCE(c.assistant_id::text, '')", "COALESCE(a.name, '')", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback by assistant: %w", err)
+	}
+
+	report := &Report{ByModel: byModel, ByAssistant: byAssistant}
+	for i := range byModel {
+		report.Total += byModel[i].Total
+		report.Up += byModel[i].Up
+		report.Down += byModel[i].Down
+	}
+	for _, rows := range [][]ReportRow{byModel, byAssistant} {
+		for i := range rows {
+			if rows[i].Total > 0 {
+				rows[i].Satisfaction = float64(rows[i].Up) / float64(rows[i].Total)
+			}
+		}
+	}
+	return report, nil
+}
+
+// ExportDataset 将评价过的问答写为JSONL：每行包含系统提示、到被评价消息为止的对话上下文与评价
+func (s *Service) ExportDataset(w io.Writer, filter Filter) (int, error) {
+	if err := validateFilter(&filter); err != nil {
+		return 0, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultExportLimit
+	}
+	if filter.Limit > maxExportLimit {
+		filter.Limit = maxExportLimit
+	}
+
+	records, err := s.repo.ListRecords(filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list feedback: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	written := 0
+	// 按批加载对话上下文，避免每条记录单独查询
+	for start := 0; start < len(records); start += exportBatchSize {
+		end := start + exportBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		batch := records[start:end]
+
+		messageIDs := make([]string, len(batch))
+		for i, record := range batch {
+			messageIDs[i] = record.MessageID
+		}
+		histories, err := s.repo.GetHistories(messageIDs)
+		if err != nil {
+			return written, fmt.Errorf("failed to get message histories: %w", err)
+		}
+
+		for _, record := range batch {
+			history := histories[record.MessageID]
+			if len(history) == 0 {
+				continue
+			}
+
+			line := DatasetLine{
+				Rating:  record.Rating,
+				Reasons: record.Reasons,
+				Comment: record.Comment,
+				Metadata: DatasetMetadata{
+					MessageID:      record.MessageID,
+					ConversationID: record.ConversationID,
+					Model:          record.Model,
+					AssistantID:    record.AssistantID,
+					RatedAt:        record.UpdatedAt,
+				},
+			}
+			if record.SystemPrompt != "" {
+				line.Messages = append(line.Messages, DatasetMessage{Role: "system", Content: record.SystemPrompt})
+			}
+			line.Messages = append(line.Messages, history...)
+
+			if err := encoder.Encode(line); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// getRatableMessage 检查消息属于用户的对话且为助手消息
+func (s *Service) getRatableMessage(messageID, userID string) (*chat.Message, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, fmt.Errorf("message not found")
+	}
+	msg, err := s.chatService.GetMessage(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("message not found")
+	}
+	conv, err := s.chatService.GetConversation(msg.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("message not found")
+	}
+	if conv.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to message")
+	}
+	if msg.Role != "assistant" {
+		return nil, fmt.Errorf("invalid message: only assistant messages can be rated")
+	}
+	return msg, nil
+}
+
+// validateFilter 校验过滤条件
+func validateFilter(filter *Filter) error {
+	if filter.Rating != "" && filter.Rating != RatingUp && filter.Rating != RatingDown {
+		return fmt.Errorf("invalid rating: must be up or down")
+	}
+	return nil
+}
+
+// contains 判断切片是否包含指定值
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
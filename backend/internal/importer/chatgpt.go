@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chatGPTConversation ChatGPT导出文件 conversations.json 中的对话，消息以mapping树的形式保存
+type chatGPTConversation struct {
+	ID               string                 `json:"id"`
+	ConversationID   string                 `json:"conversation_id"`
+	Title            string                 `json:"title"`
+	CreateTime       *float64               `json:"create_time"`
+	UpdateTime       *float64               `json:"update_time"`
+	Mapping          map[string]chatGPTNode `json:"mapping"`
+	CurrentNode      string                 `json:"current_node"`
+	DefaultModelSlug string                 `json:"default_model_slug"`
+}
+
+// chatGPTNode 消息树节点，根节点与部分系统节点没有消息
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Message  *chatGPTMessage `json:"message"`
+	Parent   *string         `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+// chatGPTMessage ChatGPT消息
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug        string `json:"model_slug"`
+		IsVisuallyHidden bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+	Recipient string `json:"recipient"`
+}
+
+// parseChatGPT 解析ChatGPT导出，只保留用户可见的用户与助手文本消息
+// 被跳过的节点（系统提示、工具调用等）的子节点挂到最近的保留祖先上，从而保留分支结构
+func parseChatGPT(data []byte) ([]conversation, error) {
+	var raw []chatGPTConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid ChatGPT export: %w", err)
+	}
+
+	conversations := make([]conversation, 0, len(raw))
+	for _, item := range raw {
+		conv := conversation{
+			ExternalID: item.ConversationID,
+			Title:      item.Title,
+			Model:      item.DefaultModelSlug,
+			CreatedAt:  unixTime(item.CreateTime, time.Now()),
+		}
+		if conv.ExternalID == "" {
+			conv.ExternalID = item.ID
+		}
+		if conv.ExternalID == "" {
+			return nil, fmt.Errorf("invalid ChatGPT export: conversation without id")
+		}
+		conv.UpdatedAt = unixTime(item.UpdateTime, conv.CreatedAt)
+
+		kept := make(map[string]message)
+		for id, node := range item.Mapping {
+			if msg, ok := chatGPTText(node.Message); ok {
+				kept[id] = message{
+					ExternalID: id,
+					Role:       node.Message.Author.Role,
+					Content:    msg,
+					Model:      node.Message.Metadata.ModelSlug,
+					CreatedAt:  unixTime(node.Message.CreateTime, conv.CreatedAt),
+				}
+			}
+		}
+
+		// 最近的保留祖先
+		var keptAncestor func(id string, depth int) string
+		keptAncestor = func(id string, depth int) string {
+			node, ok := item.Mapping[id]
+			if !ok || node.Parent == nil || depth > len(item.Mapping) {
+				return ""
+			}
+			if _, ok := kept[*node.Parent]; ok {
+				return *node.Parent
+			}
+			return keptAncestor(*node.Parent, depth+1)
+		}
+		for id, msg := range kept {
+			msg.ParentID = keptAncestor(id, 0)
+			kept[id] = msg
+		}
+
+		conv.Messages = orderMessages(kept)
+		if _, ok := kept[item.CurrentNode]; ok {
+			conv.CurrentID = item.CurrentNode
+		} else if item.CurrentNode != "" {
+			conv.CurrentID = keptAncestor(item.CurrentNode, 0)
+		}
+		if conv.Model == "" {
+			conv.Model = lastModel(conv.Messages)
+		}
+
+		conversations = append(conversations, conv)
+	}
+	return conversations, nil
+}
+
+// chatGPTText 提取消息的文本内容，非用户可见的消息返回false
+func chatGPTText(msg *chatGPTMessage) (string, bool) {
+	if msg == nil || msg.Metadata.IsVisuallyHidden {
+		return "", false
+	}
+	if msg.Author.Role != "user" && msg.Author.Role != "assistant" {
+		return "", false
+	}
+	if msg.Recipient != "" && msg.Recipient != "all" {
+		return "", false
+	}
+	if msg.Content.ContentType != "text" && msg.Content.ContentType != "multimodal_text" {
+		return "", false
+	}
+
+	var parts []string
+	for _, raw := range msg.Content.Parts {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			if text != "" {
+				parts = append(parts, text)
+			}
+			continue
+		}
+		// 图片、文件等非文本内容
+		parts = append(parts, "[attachment]")
+	}
+
+	content := strings.TrimSpace(strings.Join(parts, "\n\n"))
+	return content, content != ""
+}
+
+// orderMessages 按父消息在前、同级按时间排序返回消息
+func orderMessages(kept map[string]message) []message {
+	children := make(map[string][]message)
+	for _, msg := range kept {
+		children[msg.ParentID] = append(children[msg.ParentID], msg)
+	}
+	for _, list := range children {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+				return list[i].ExternalID < list[j].ExternalID
+			}
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+	}
+
+	ordered := make([]message, 0, len(kept))
+	queue := children[""]
+	for len(queue) > 0 {
+		msg := queue[0]
+		queue = append(queue[1:], children[msg.ExternalID]...)
+		ordered = append(ordered, msg)
+	}
+	return ordered
+}
+
+// lastModel 返回最后一条带模型信息的助手消息的模型
+func lastModel(messages []message) string {
+	latest := message{}
+	for _, msg := range messages {
+		if msg.Model != "" && !msg.CreatedAt.Before(latest.CreatedAt) {
+			latest = msg
+		}
+	}
+	return latest.Model
+}
+
+// unixTime 将ChatGPT的浮点秒时间戳转换为时间，缺失时使用fallback
+func unixTime(value *float64, fallback time.Time) time.Time {
+	if value == nil || *value <= 0 {
+		return fallback
+	}
+	seconds, fraction := math.Modf(*value)
+	return time.Unix(int64(seconds), int64(fraction*1e9))
+}
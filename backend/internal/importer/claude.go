@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// claudeRootParentID Claude导出中表示根消息的父消息ID
+const claudeRootParentID = "00000000-0000-4000-8000-000000000000"
+
+// claudeConversation Claude导出文件 conversations.json 中的对话
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	Model        string          `json:"model"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+// claudeMessage Claude消息，较新的导出带有parent_message_uuid，旧版导出只有线性顺序
+type claudeMessage struct {
+	UUID    string `json:"uuid"`
+	Text    string `json:"text"`
+	Sender  string `json:"sender"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Attachments []struct {
+		FileName         string `json:"file_name"`
+		ExtractedContent string `json:"extracted_content"`
+	} `json:"attachments"`
+	ParentMessageUUID string    `json:"parent_message_uuid"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// parseClaude 解析Claude导出；有父消息ID时按其还原分支，否则按消息顺序串成一条分支
+func parseClaude(data []byte) ([]conversation, error) {
+	var raw []claudeConversation
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid Claude export: %w", err)
+	}
+
+	conversations := make([]conversation, 0, len(raw))
+	for _, item := range raw {
+		if item.UUID == "" {
+			return nil, fmt.Errorf("invalid Claude export: conversation without uuid")
+		}
+		conv := conversation{
+			ExternalID: item.UUID,
+			Title:      item.Name,
+			Model:      item.Model,
+			CreatedAt:  item.CreatedAt,
+			UpdatedAt:  item.UpdatedAt,
+		}
+		if conv.CreatedAt.IsZero() {
+			conv.CreatedAt = time.Now()
+		}
+		if conv.UpdatedAt.IsZero() {
+			conv.UpdatedAt = conv.CreatedAt
+		}
+
+		seen := make(map[string]bool, len(item.ChatMessages))
+		previous := ""
+		for _, msg := range item.ChatMessages {
+			content := claudeText(msg)
+			role := map[string]string{"human": "user", "assistant": "assistant"}[msg.Sender]
+			if msg.UUID == "" || role == "" || content == "" || seen[msg.UUID] {
+				continue
+			}
+
+			parentID := previous
+			switch {
+			case msg.ParentMessageUUID == claudeRootParentID:
+				parentID = ""
+			case seen[msg.ParentMessageUUID]:
+				parentID = msg.ParentMessageUUID
+			}
+
+			createdAt := msg.CreatedAt
+			if createdAt.IsZero() {
+				createdAt = conv.CreatedAt
+			}
+			conv.Messages = append(conv.Messages, message{
+				ExternalID: msg.UUID,
+				ParentID:   parentID,
+				Role:       role,
+				Content:    content,
+				CreatedAt:  createdAt,
+			})
+			seen[msg.UUID] = true
+			previous = msg.UUID
+		}
+
+		// 导出中消息的顺序即当前显示的分支，最后一条为分支末端
+		conv.CurrentID = previous
+		conversations = append(conversations, conv)
+	}
+	return conversations, nil
+}
+
+// claudeText 提取消息文本，附件的提取内容以引用块附在消息后
+func claudeText(msg claudeMessage) string {
+	var parts []string
+	for _, item := range msg.Content {
+		if item.Type == "text" && strings.TrimSpace(item.Text) != "" {
+			parts = append(parts, item.Text)
+		}
+	}
+	if len(parts) == 0 && strings.TrimSpace(msg.Text) != "" {
+		parts = append(parts, msg.Text)
+	}
+	for _, attachment := range msg.Attachments {
+		if attachment.ExtractedContent != "" {
+			parts = append(parts, fmt.Sprintf("[%s]\n```\n%s\n```", attachment.FileName, attachment.ExtractedContent))
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qicro/qicro/backend/internal/jobs"
+)
+
+// maxUploadSize 上传文件大小上限
+const maxUploadSize = 128 << 20
+
+// Handler 导入处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建导入处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// StartImport 上传ChatGPT或Claude的导出文件（表单字段 file），可选 source 与 model，返回导入任务
+func (h *Handler) StartImport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file is required (max %d MB)", maxUploadSize>>20)})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.service.StartImport(userID.(string), data, ImportOptions{
+		Source: c.PostForm("source"),
+		Model:  c.PostForm("model"),
+	})
+	if err != nil {
+		jobs.WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
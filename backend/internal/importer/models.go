@@ -0,0 +1,51 @@
+package importer
+
+import "time"
+
+// 导入来源
+const (
+	SourceAuto    = "auto"
+	SourceChatGPT = "chatgpt"
+	SourceClaude  = "claude"
+)
+
+// JobTypeImport 导入任务类型
+const JobTypeImport = "import"
+
+// conversation 从导出文件解析出的对话，ID均为来源平台的原始ID
+type conversation struct {
+	ExternalID string
+	Title      string
+	Model      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// CurrentID 来源平台当前显示的分支末端消息，为空时取最后一条消息
+	CurrentID string
+	// Messages 按父消息在前的顺序排列
+	Messages []message
+}
+
+// message 从导出文件解析出的消息，ParentID为空表示根消息
+type message struct {
+	ExternalID string
+	ParentID   string
+	Role       string
+	Content    string
+	Model      string
+	CreatedAt  time.Time
+}
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	// Source chatgpt / claude / auto（按文件结构识别）
+	Source string
+	// Model 导出文件未记录模型时对话使用的模型
+	Model string
+}
+
+// failure 导入失败的对话
+type failure struct {
+	ExternalID string `json:"external_id"`
+	Title      string `json:"title"`
+	Error      string `json:"error"`
+}
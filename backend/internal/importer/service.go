@@ -0,0 +1,238 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/qicro/qicro/backend/internal/chat"
+	"github.com/qicro/qicro/backend/internal/jobs"
+)
+
+const (
+	// exportFileName 官方导出压缩包中保存对话的文件
+	exportFileName   = "conversations.json"
+	maxTitleRunes    = 255
+	maxModelRunes    = 100
+	maxReportedFails = 50
+	// maxExtractedSize 从zip中解压的conversations.json大小上限
+	maxExtractedSize = 512 << 20
+)
+
+// idNamespace 生成确定性ID的命名空间：同一用户重复导入同一对话得到相同的对话与消息ID
+var idNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://qicro/import"))
+
+// Service 对话导入服务
+type Service struct {
+	chatService *chat.Service
+	jobService  *jobs.Service
+}
+
+// NewService 创建导入服务
+func NewService(chatService *chat.Service, jobService *jobs.Service) *Service {
+	return &Service{
+		chatService: chatService,
+		jobService:  jobService,
+	}
+}
+
+// StartImport 解析导出文件（conversations.json 或官方导出的zip）并以后台任务写入
+// 文件格式错误在创建任务前直接返回
+func (s *Service) StartImport(userID string, data []byte, opts ImportOptions) (*jobs.Job, error) {
+	if utf8.RuneCountInString(opts.Model) > maxModelRunes {
+		return nil, fmt.Errorf("invalid model")
+	}
+
+	data, err := extractConversations(data)
+	if err != nil {
+		return nil, err
+	}
+	source, err := detectSource(data, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []conversation
+	if source == SourceChatGPT {
+		conversations, err = parseChatGPT(data)
+	} else {
+		conversations, err = parseClaude(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.jobService.Submit(userID, JobTypeImport, func(ctx context.Context, report jobs.ReportFunc) (*jobs.Output, error) {
+		return s.importAll(ctx, userID, source, opts.Model, conversations, report)
+	})
+}
+
+// importAll 逐个写入对话，单个对话失败不影响其余对话
+func (s *Service) importAll(ctx context.Context, userID, source, defaultModel string, conversations []conversation, report jobs.ReportFunc) (*jobs.Output, error) {
+	report(0, len(conversations))
+
+	created, updated, unchanged, messagesAdded := 0, 0, 0, 0
+	failures := []failure{}
+	for i, item := range conversations {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("import cancelled: %w", err)
+		}
+
+		conv, messages := toChat(userID, source, defaultModel, item)
+		result, err := s.chatService.ImportConversation(conv, messages)
+		switch {
+		case err != nil:
+			if len(failures) < maxReportedFails {
+				failures = append(failures, failure{ExternalID: item.ExternalID, Title: conv.Title, Error: err.Error()})
+			}
+		case result.Created:
+			created++
+			messagesAdded += result.MessagesAdded
+		case result.MessagesAdded > 0:
+			updated++
+			messagesAdded += result.MessagesAdded
+		default:
+			unchanged++
+		}
+		report(i+1, len(conversations))
+	}
+
+	return &jobs.Output{Result: map[string]interface{}{
+		"source":         source,
+		"conversations":  len(conversations),
+		"created":        created,
+		"updated":        updated,
+		"unchanged":      unchanged,
+		"messages_added": messagesAdded,
+		"failed":         len(conversations) - created - updated - unchanged,
+		"failures":       failures,
+	}}, nil
+}
+
+// toChat 转换为chat模型，ID由用户、来源与原始ID确定
+func toChat(userID, source, defaultModel string, item conversation) (*chat.Conversation, []chat.Message) {
+	model := item.Model
+	if model == "" {
+		model = defaultModel
+	}
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		title = chat.DefaultConversationTitle
+	}
+	if utf8.RuneCountInString(title) > maxTitleRunes {
+		title = string([]rune(title)[:maxTitleRunes])
+	}
+
+	conv := &chat.Conversation{
+		ID:        deterministicID(userID, source, "conversation", item.ExternalID),
+		UserID:    userID,
+		Title:     title,
+		Model:     model,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+
+	messageID := func(externalID string) string {
+		return deterministicID(userID, source, "message", item.ExternalID, externalID)
+	}
+
+	messages := make([]chat.Message, 0, len(item.Messages))
+	for _, msg := range item.Messages {
+		converted := chat.Message{
+			ID:             messageID(msg.ExternalID),
+			ConversationID: conv.ID,
+			Role:           msg.Role,
+			Content:        msg.Content,
+			Model:          msg.Model,
+			Artifacts:      map[string]interface{}{"import": map[string]string{"source": source, "external_id": msg.ExternalID}},
+			CreatedAt:      msg.CreatedAt,
+		}
+		if msg.ParentID != "" {
+			parentID := messageID(msg.ParentID)
+			converted.ParentID = &parentID
+		}
+		if msg.Role == "assistant" {
+			converted.FinishReason = chat.FinishReasonStop
+		}
+		messages = append(messages, converted)
+	}
+
+	leaf := item.CurrentID
+	if leaf == "" && len(item.Messages) > 0 {
+		leaf = item.Messages[len(item.Messages)-1].ExternalID
+	}
+	if leaf != "" {
+		leafID := messageID(leaf)
+		conv.ActiveLeafID = &leafID
+	}
+
+	return conv, messages
+}
+
+// deterministicID 基于名称生成UUIDv5
+func deterministicID(parts ...string) string {
+	return uuid.NewSHA1(idNamespace, []byte(strings.Join(parts, "/"))).String()
+}
+
+// extractConversations 如果上传的是zip，取出其中的 conversations.json
+func extractConversations(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return data, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	for _, file := range archive.File {
+		if path.Base(file.Name) != exportFileName {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		defer reader.Close()
+		extracted, err := io.ReadAll(io.LimitReader(reader, maxExtractedSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if len(extracted) > maxExtractedSize {
+			return nil, fmt.Errorf("invalid archive: %s is too large", exportFileName)
+		}
+		return extracted, nil
+	}
+	return nil, fmt.Errorf("invalid archive: %s not found", exportFileName)
+}
+
+// detectSource 根据对话的结构识别导出来源：ChatGPT有mapping树，Claude有chat_messages列表
+func detectSource(data []byte, source string) (string, error) {
+	switch source {
+	case SourceChatGPT, SourceClaude:
+		return source, nil
+	case "", SourceAuto:
+	default:
+		return "", fmt.Errorf("invalid source: must be chatgpt, claude or auto")
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return "", fmt.Errorf("invalid export: expected a JSON array of conversations")
+	}
+	for _, item := range items {
+		if _, ok := item["mapping"]; ok {
+			return SourceChatGPT, nil
+		}
+		if _, ok := item["chat_messages"]; ok {
+			return SourceClaude, nil
+		}
+	}
+	return "", fmt.Errorf("invalid export: unrecognized format")
+}
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 任务处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建任务处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetJobs 获取当前用户最近的任务，可按 type 过滤
+func (h *Handler) GetJobs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	jobs, err := h.service.GetJobs(userID.(string), c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// GetJob 获取任务状态与进度
+func (h *Handler) GetJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	job, err := h.service.GetJob(c.Param("id"), userID.(string))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadJobFile 下载任务生成的文件
+func (h *Handler) DownloadJobFile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	file, err := h.service.GetJobFile(c.Param("id"), userID.(string))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	Attachment(c, file.Name, file.ContentType, file.Data)
+}
+
+// Attachment 以附件形式返回文件，文件名按RFC 6266编码以支持中文
+func Attachment(c *gin.Context, name, contentType string, data []byte) {
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name})
+	if disposition == "" {
+		disposition = fmt.Sprintf("attachment; filename=%q", "download")
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// WriteError 根据错误类型返回对应状态码
+func WriteError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(err.Error(), "invalid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// 任务状态
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Job 后台任务，例如批量导出、导入；生成的文件保存在数据库中供下载
+type Job struct {
+	ID          string                 `json:"id" db:"id"`
+	UserID      string                 `json:"user_id" db:"user_id"`
+	Type        string                 `json:"type" db:"type"`
+	Status      string                 `json:"status" db:"status"`
+	Progress    int                    `json:"progress" db:"progress"`
+	Total       int                    `json:"total" db:"total"`
+	Error       string                 `json:"error,omitempty" db:"error"`
+	Result      map[string]interface{} `json:"result,omitempty" db:"result"`
+	FileName    string                 `json:"file_name,omitempty" db:"file_name"`
+	ContentType string                 `json:"-" db:"content_type"`
+	FileSize    int64                  `json:"file_size,omitempty" db:"file_size"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+	FinishedAt  *time.Time             `json:"finished_at" db:"finished_at"`
+}
+
+// Output 任务执行结果，Data非空时作为可下载文件保存
+type Output struct {
+	Result      map[string]interface{}
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// ReportFunc 汇报任务进度
+type ReportFunc func(progress, total int)
+
+// Task 任务执行函数
+type Task func(ctx context.Context, report ReportFunc) (*Output, error)
+
+// File 任务生成的文件
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
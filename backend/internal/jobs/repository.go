@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Repository 任务仓库
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository 创建任务仓库
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const jobColumns = `id, user_id, type, status, progress, total, error, result, file_name, content_type,
+	COALESCE(octet_length(file_data), 0), created_at, updated_at, finished_at`
+
+// CreateJob 保存任务
+func (r *Repository) CreateJob(job *Job) error {
+	query := `
+		INSERT INTO jobs (id, user_id, type, status, progress, total, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.Exec(query, job.ID, job.UserID, job.Type, job.Status, job.Progress, job.Total,
+		job.CreatedAt, job.UpdatedAt)
+	return err
+}
+
+// GetJobByID 获取任务（不含文件内容）
+func (r *Repository) GetJobByID(id string) (*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	job, err := scanJob(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job not found")
+	}
+	return job, err
+}
+
+// GetJobsByUserID 获取用户最近的任务，jobType非空时按类型过滤
+func (r *Repository) GetJobsByUserID(userID, jobType string, limit int) ([]Job, error) {
+	query := `SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE user_id = $1 AND ($2 = '' OR type = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(query, userID, jobType, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountActiveJobs 统计用户某类型未结束的任务数
+func (r *Repository) CountActiveJobs(userID, jobType string) (int, error) {
+	query := `SELECT COUNT(*) FROM jobs WHERE user_id = $1 AND type = $2 AND status IN ($3, $4)`
+
+	var count int
+	err := r.db.QueryRow(query, userID, jobType, StatusPending, StatusRunning).Scan(&count)
+	return count, err
+}
+
+// UpdateStatus 更新任务状态
+func (r *Repository) UpdateStatus(id, status string) error {
+	_, err := r.db.Exec(`UPDATE jobs SET status = $2, updated_at = NOW() WHERE id = $1`, id, status)
+	return err
+}
+
+// UpdateProgress 更新任务进度
+func (r *Repository) UpdateProgress(id string, progress, total int) error {
+	query := `UPDATE jobs SET progress = $2, total = $3, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(query, id, progress, total)
+	return err
+}
+
+// CompleteJob 保存任务结果与文件
+func (r *Repository) CompleteJob(id string, output *Output) error {
+	resultJSON, err := json.Marshal(output.Result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	query := `
+		UPDATE jobs
+		SET status = $2, result = $3, file_name = $4, content_type = $5, file_data = $6,
+			updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1`
+
+	_, err = r.db.Exec(query, id, StatusCompleted, resultJSON, output.FileName, output.ContentType, output.Data)
+	return err
+}
+
+// FailJob 记录任务失败原因
+func (r *Repository) FailJob(id, message string) error {
+	query := `UPDATE jobs SET status = $2, error = $3, updated_at = NOW(), finished_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(query, id, StatusFailed, message)
+	return err
+}
+
+// GetJobFile 获取任务生成的文件
+func (r *Repository) GetJobFile(id string) (*File, error) {
+	query := `SELECT file_name, content_type, file_data FROM jobs WHERE id = $1 AND file_data IS NOT NULL`
+
+	var file File
+	err := r.db.QueryRow(query, id).Scan(&file.Name, &file.ContentType, &file.Data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job file not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// RenewJobs 为执行中的任务续期
+func (r *Repository) RenewJobs(ids []string) error {
+	query := `UPDATE jobs SET heartbeat_at = NOW() WHERE id = ANY($1::uuid[]) AND status IN ($2, $3)`
+	_, err := r.db.Exec(query, pq.Array(ids), StatusPending, StatusRunning)
+	return err
+}
+
+// FailStaleJobs 将超过租约时间没有续期的未完成任务标记为失败，使用数据库时间避免实例间的时钟偏差
+func (r *Repository) FailStaleJobs(lease time.Duration) (int64, error) {
+	query := `
+		UPDATE jobs
+		SET status = $1, error = 'interrupted by server restart', updated_at = NOW(), finished_at = NOW()
+		WHERE status IN ($2, $3) AND heartbeat_at < NOW() - $4 * INTERVAL '1 second'`
+
+	result, err := r.db.Exec(query, StatusFailed, StatusPending, StatusRunning, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteFinishedBefore 删除早于指定时间结束的任务及其文件
+func (r *Repository) DeleteFinishedBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM jobs WHERE finished_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob 扫描单行任务
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var resultJSON []byte
+	var finishedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.UserID, &job.Type, &job.Status, &job.Progress, &job.Total,
+		&job.Error, &resultJSON, &job.FileName, &job.ContentType, &job.FileSize,
+		&job.CreatedAt, &job.UpdatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resultJSON) > 0 {
+		if err := json.Unmarshal(resultJSON, &job.Result); err != nil {
+			job.Result = nil
+		}
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxConcurrentJobs 同时执行的任务数上限，其余任务排队等待
+	maxConcurrentJobs = 2
+	// jobTimeout 单个任务的最长执行时间
+	jobTimeout = 30 * time.Minute
+	// jobRetention 已结束任务及其文件的保留时间
+	jobRetention  = 7 * 24 * time.Hour
+	maxListedJobs = 50
+	// jobHeartbeatInterval 为本实例的任务续期的间隔
+	jobHeartbeatInterval = 30 * time.Second
+	// jobLeaseTimeout 任务超过该时间没有续期，视为执行它的实例已停止
+	jobLeaseTimeout = 2 * time.Minute
+)
+
+// Service 后台任务服务，任务在提交它的实例的goroutine中执行，状态与结果持久化到数据库
+// 实例定期为自己的任务续期，多实例部署时各实例只会把停止续期的任务标记为失败
+type Service struct {
+	repo  *Repository
+	slots chan struct{}
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// NewService 创建任务服务
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo:   repo,
+		slots:  make(chan struct{}, maxConcurrentJobs),
+		active: make(map[string]bool),
+	}
+}
+
+// Recover 启动时清理过期任务，并开始为本实例的任务续期、回收已停止实例遗留的任务
+func (s *Service) Recover() {
+	s.failStaleJobs()
+	if _, err := s.repo.DeleteFinishedBefore(time.Now().Add(-jobRetention)); err != nil {
+		log.Printf("Warning: failed to delete expired jobs: %v", err)
+	}
+	go s.heartbeat()
+}
+
+// heartbeat 定期为本实例未结束的任务续期，并把超过租约时间的任务标记为失败
+func (s *Service) heartbeat() {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		ids := make([]string, 0, len(s.active))
+		for id := range s.active {
+			ids = append(ids, id)
+		}
+		s.mu.Unlock()
+
+		if len(ids) > 0 {
+			if err := s.repo.RenewJobs(ids); err != nil {
+				log.Printf("Warning: failed to renew jobs: %v", err)
+			}
+		}
+		s.failStaleJobs()
+	}
+}
+
+// failStaleJobs 将执行实例已停止（超过租约时间没有续期）的任务标记为失败
+func (s *Service) failStaleJobs() {
+	if count, err := s.repo.FailStaleJobs(jobLeaseTimeout); err != nil {
+		log.Printf("Warning: failed to mark interrupted jobs: %v", err)
+	} else if count > 0 {
+		log.Printf("Marked %d interrupted jobs as failed", count)
+	}
+}
+
+// Submit 创建任务并在后台执行；同一用户同类型的任务同时只能有一个
+func (s *Service) Submit(userID, jobType string, task Task) (*Job, error) {
+	active, err := s.repo.CountActiveJobs(userID, jobType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check jobs: %w", err)
+	}
+	if active > 0 {
+		return nil, fmt.Errorf("invalid request: a %s job is already in progress", jobType)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      jobType,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active[job.ID] = true
+	s.mu.Unlock()
+	go s.run(job.ID, task)
+
+	return job, nil
+}
+
+// GetJob 获取任务并检查权限
+func (s *Service) GetJob(jobID, userID string) (*Job, error) {
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, fmt.Errorf("job not found")
+	}
+	job, err := s.repo.GetJobByID(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to job")
+	}
+	return job, nil
+}
+
+// GetJobs 获取用户最近的任务
+func (s *Service) GetJobs(userID, jobType string) ([]Job, error) {
+	jobs, err := s.repo.GetJobsByUserID(userID, jobType, maxListedJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetJobFile 获取已完成任务生成的文件
+func (s *Service) GetJobFile(jobID, userID string) (*File, error) {
+	job, err := s.GetJob(jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusCompleted {
+		return nil, fmt.Errorf("invalid request: job is %s", job.Status)
+	}
+	return s.repo.GetJobFile(jobID)
+}
+
+// run 等待执行槽位后执行任务，panic与超时都记为失败
+func (s *Service) run(jobID string, task Task) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.active, jobID)
+		s.mu.Unlock()
+	}()
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	if err := s.repo.UpdateStatus(jobID, StatusRunning); err != nil {
+		log.Printf("Warning: failed to start job %s: %v", jobID, err)
+	}
+
+	report := func(progress, total int) {
+		if err := s.repo.UpdateProgress(jobID, progress, total); err != nil {
+			log.Printf("Warning: failed to update job %s progress: %v", jobID, err)
+		}
+	}
+
+	output, err := runTask(ctx, task, report)
+	if err != nil {
+		if err := s.repo.FailJob(jobID, err.Error()); err != nil {
+			log.Printf("Warning: failed to record job %s failure: %v", jobID, err)
+		}
+		return
+	}
+
+	if output == nil {
+		output = &Output{}
+	}
+	if err := s.repo.CompleteJob(jobID, output); err != nil {
+		log.Printf("Warning: failed to complete job %s: %v", jobID, err)
+		if err := s.repo.FailJob(jobID, "failed to save job result"); err != nil {
+			log.Printf("Warning: failed to record job %s failure: %v", jobID, err)
+		}
+	}
+}
+
+// runTask 执行任务并将panic转换为错误
+func runTask(ctx context.Context, task Task, report ReportFunc) (output *Output, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return task(ctx, report)
+}
@@ -0,0 +1,315 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 外部知识库连接器类型
+const (
+	ConnectorRAGFlow = "ragflow"
+	ConnectorGeneric = "generic"
+)
+
+// Connector 外部知识库连接器接口
+// 外部知识库（type=external）的检索委托给远程HTTP服务，结果统一映射为Citation格式
+type Connector interface {
+	Retrieve(ctx context.Context, query string, topK int) ([]SearchResult, error)
+}
+
+// ExternalConfig 外部知识库配置，保存在knowledge_bases.config中
+type ExternalConfig struct {
+	Provider            string   `json:"provider"`
+	BaseURL             string   `json:"base_url"`
+	APIKey              string   `json:"api_key"`
+	DatasetIDs          []string `json:"dataset_ids"`
+	SimilarityThreshold float64  `json:"similarity_threshold"`
+	TimeoutSeconds      int      `json:"timeout_seconds"`
+
+	// host base_url的主机名（小写）
+	host string
+}
+
+// parseExternalConfig 从知识库配置中解析外部连接器配置
+func parseExternalConfig(config map[string]interface{}) (*ExternalConfig, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ExternalConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid external knowledge base config: %w", err)
+	}
+
+	if cfg.Provider == "" {
+		cfg.Provider = ConnectorRAGFlow
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("external knowledge base config requires base_url")
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid base_url: must be an http(s) URL")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	cfg.host = strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	return &cfg, nil
+}
+
+// NewConnector 根据知识库配置创建连接器
+// base_url由用户填写，连接时拒绝回环、内网与链路本地地址，allowedHosts中的主机（如内网部署的RAGFlow）除外
+func NewConnector(kb *KnowledgeBase, allowedHosts []string) (Connector, error) {
+	cfg, err := parseExternalConfig(kb.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, host := range allowedHosts {
+		allowed[strings.ToLower(host)] = true
+	}
+	if !allowed[cfg.host] {
+		if ip := net.ParseIP(cfg.host); (ip != nil && isBlockedIP(ip)) || cfg.host == "localhost" {
+			return nil, fmt.Errorf("invalid base_url: private and loopback addresses are not allowed")
+		}
+	}
+
+	client := newConnectorClient(time.Duration(cfg.TimeoutSeconds)*time.Second, allowed)
+
+	switch strings.ToLower(cfg.Provider) {
+	case ConnectorRAGFlow:
+		if len(cfg.DatasetIDs) == 0 {
+			return nil, fmt.Errorf("ragflow connector requires dataset_ids")
+		}
+		return &RAGFlowConnector{knowledgeBaseID: kb.ID, config: cfg, client: client}, nil
+	case ConnectorGeneric:
+		return &GenericConnector{knowledgeBaseID: kb.ID, config: cfg, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported connector provider: %s", cfg.Provider)
+	}
+}
+
+// RAGFlowConnector RAGFlow检索接口连接器
+type RAGFlowConnector struct {
+	knowledgeBaseID string
+	config          *ExternalConfig
+	client          *http.Client
+}
+
+// Retrieve 调用RAGFlow的 /api/v1/retrieval 接口
+func (c *RAGFlowConnector) Retrieve(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	body := map[string]interface{}{
+		"question":    query,
+		"dataset_ids": c.config.DatasetIDs,
+		"page":        1,
+		"page_size":   topK,
+	}
+	if c.config.SimilarityThreshold > 0 {
+		body["similarity_threshold"] = c.config.SimilarityThreshold
+	}
+
+	var ragflowResp struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Chunks []struct {
+				ID              string  `json:"id"`
+				Content         string  `json:"content"`
+				DocumentID      string  `json:"document_id"`
+				DocumentKeyword string  `json:"document_keyword"`
+				Similarity      float64 `json:"similarity"`
+			} `json:"chunks"`
+		} `json:"data"`
+	}
+
+	if err := postJSON(ctx, c.client, c.config.BaseURL+"/api/v1/retrieval", c.config.APIKey, body, &ragflowResp); err != nil {
+		return nil, err
+	}
+
+	if ragflowResp.Code != 0 {
+		return nil, fmt.Errorf("RAGFlow API error: %s", ragflowResp.Message)
+	}
+
+	results := make([]SearchResult, 0, len(ragflowResp.Data.Chunks))
+	for _, chunk := range ragflowResp.Data.Chunks {
+		results = append(results, SearchResult{
+			Citation: Citation{
+				KnowledgeBaseID: c.knowledgeBaseID,
+				DocumentID:      chunk.DocumentID,
+				ChunkID:         chunk.ID,
+				Title:           chunk.DocumentKeyword,
+				Content:         chunk.Content,
+				Score:           chunk.Similarity,
+			},
+		})
+	}
+
+	return results, nil
+}
+
+// GenericConnector 通用HTTP检索连接器
+// 请求：POST base_url {"query": "...", "top_k": 5}
+// 响应：{"results": [{"id", "document_id", "title", "source", "content", "score"}]}
+type GenericConnector struct {
+	knowledgeBaseID string
+	config          *ExternalConfig
+	client          *http.Client
+}
+
+// Retrieve 调用通用检索接口
+func (c *GenericConnector) Retrieve(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	body := map[string]interface{}{
+		"query": query,
+		"top_k": topK,
+	}
+
+	var genericResp struct {
+		Results []struct {
+			ID         string  `json:"id"`
+			DocumentID string  `json:"document_id"`
+			Title      string  `json:"title"`
+			Source     string  `json:"source"`
+			Content    string  `json:"content"`
+			Score      float64 `json:"score"`
+		} `json:"results"`
+	}
+
+	if err := postJSON(ctx, c.client, c.config.BaseURL, c.config.APIKey, body, &genericResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(genericResp.Results))
+	for _, item := range genericResp.Results {
+		results = append(results, SearchResult{
+			Citation: Citation{
+				KnowledgeBaseID: c.knowledgeBaseID,
+				DocumentID:      item.DocumentID,
+				ChunkID:         item.ID,
+				Title:           item.Title,
+				Source:          item.Source,
+				Content:         item.Content,
+				Score:           item.Score,
+			},
+		})
+	}
+
+	return results, nil
+}
+
+// postJSON 发送JSON请求并解析JSON响应
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 不回显上游响应体，避免把内部服务的内容带给调用方
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("external knowledge base error: HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// errBlockedAddress 连接目标为禁止访问的地址
+var errBlockedAddress = errors.New("connection to private or loopback address is not allowed")
+
+// newConnectorClient 创建连接外部知识库的HTTP客户端
+// 在解析域名之后、建立连接之前校验目标IP，防止借助DNS解析或重定向访问内网；不使用环境变量中的代理，避免绕过校验
+func newConnectorClient(timeout time.Duration, allowedHosts map[string]bool) *http.Client {
+	direct := &net.Dialer{Timeout: 10 * time.Second}
+	guarded := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(address)
+			if err == nil && allowedHosts[strings.ToLower(host)] {
+				return direct.DialContext(ctx, network, address)
+			}
+			return guarded.DialContext(ctx, network, address)
+		},
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// blockedNetworks 标准库判断之外的非公网地址段
+var blockedNetworks = []net.IPNet{
+	// 运营商级NAT，云厂商的元数据与VPC内部服务常用
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	// 网络设备基准测试
+	{IP: net.IPv4(198, 18, 0, 0), Mask: net.CIDRMask(15, 32)},
+	// 本网络，部分系统将0.x.x.x路由到本机
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	// IETF协议分配
+	{IP: net.IPv4(192, 0, 0, 0), Mask: net.CIDRMask(24, 32)},
+	// 保留地址与受限广播
+	{IP: net.IPv4(240, 0, 0, 0), Mask: net.CIDRMask(4, 32)},
+}
+
+// isBlockedIP 回环、内网、链路本地、组播、未指定地址以及blockedNetworks中的地址
+// IPv4映射的IPv6地址（如::ffff:127.0.0.1）按对应的IPv4地址判断
+func isBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// localHosts httptest服务监听在回环地址，测试中显式放行
+var localHosts = []string{"127.0.0.1"}
+
+func newExternalKB(config map[string]interface{}) *KnowledgeBase {
+	return &KnowledgeBase{ID: "kb-1", Type: TypeExternal, Config: config}
+}
+
+func TestRAGFlowConnectorRetrieve(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/retrieval" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("unexpected Authorization %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"code":0,"data":{"chunks":[
+			{"id":"c1","content":"alpha","document_id":"d1","document_keyword":"guide.pdf","similarity":0.92}
+		]}}`))
+	}))
+	defer server.Close()
+
+	connector, err := NewConnector(newExternalKB(map[string]interface{}{
+		"provider":             ConnectorRAGFlow,
+		"base_url":             server.URL + "/",
+		"api_key":              "secret",
+		"dataset_ids":          []string{"ds1"},
+		"similarity_threshold": 0.3,
+	}), localHosts)
+	if err != nil {
+		t.Fatalf("NewConnector: %v", err)
+	}
+
+	results, err := connector.Retrieve(context.Background(), "what is alpha", 3)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+
+	if got["question"] != "what is alpha" || got["page_size"] != float64(3) || got["similarity_threshold"] != 0.3 {
+		t.Errorf("unexpected request body %v", got)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	want := Citation{KnowledgeBaseID: "kb-1", DocumentID: "d1", ChunkID: "c1", Title: "guide.pdf", Content: "alpha", Score: 0.92}
+	if results[0].Citation != want {
+		t.Errorf("unexpected citation %+v", results[0].Citation)
+	}
+}
+
+func TestRAGFlowConnectorAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":102,"message":"dataset not found"}`))
+	}))
+	defer server.Close()
+
+	connector, err := NewConnector(newExternalKB(map[string]interface{}{
+		"base_url":    server.URL,
+		"dataset_ids": []string{"ds1"},
+	}), localHosts)
+	if err != nil {
+		t.Fatalf("NewConnector: %v", err)
+	}
+
+	if _, err := connector.Retrieve(context.Background(), "q", 5); err == nil || !strings.Contains(err.Error(), "dataset not found") {
+		t.Errorf("expected RAGFlow error, got %v", err)
+	}
+}
+
+func TestGenericConnectorRetrieve(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"results":[
+			{"id":"c1","document_id":"d1","title":"Intro","source":"https://example.com/intro","content":"one","score":0.8},
+			{"id":"c2","document_id":"d2","title":"Usage","content":"two","score":0.5}
+		]}`))
+	}))
+	defer server.Close()
+
+	connector, err := NewConnector(newExternalKB(map[string]interface{}{
+		"provider": ConnectorGeneric,
+		"base_url": server.URL + "/search",
+	}), localHosts)
+	if err != nil {
+		t.Fatalf("NewConnector: %v", err)
+	}
+
+	results, err := connector.Retrieve(context.Background(), "usage", 2)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+
+	if got["query"] != "usage" || got["top_k"] != float64(2) {
+		t.Errorf("unexpected request body %v", got)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	want := Citation{KnowledgeBaseID: "kb-1", DocumentID: "d1", ChunkID: "c1", Title: "Intro", Source: "https://example.com/intro", Content: "one", Score: 0.8}
+	if results[0].Citation != want {
+		t.Errorf("unexpected citation %+v", results[0].Citation)
+	}
+	if results[1].Citation.ChunkID != "c2" || results[1].Citation.Score != 0.5 {
+		t.Errorf("unexpected citation %+v", results[1].Citation)
+	}
+}
+
+func TestConnectorDoesNotEchoUpstreamBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal secret details", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	connector, err := NewConnector(newExternalKB(map[string]interface{}{
+		"provider": ConnectorGeneric,
+		"base_url": server.URL,
+	}), localHosts)
+	if err != nil {
+		t.Fatalf("NewConnector: %v", err)
+	}
+
+	_, err = connector.Retrieve(context.Background(), "q", 1)
+	if err == nil || strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected status-only error, got %v", err)
+	}
+}
+
+func TestConnectorBlocksPrivateAddresses(t *testing.T) {
+	for _, baseURL := range []string{
+		"http://127.0.0.1:8080",
+		"http://localhost:9380",
+		"http://10.0.0.5",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]:8080",
+		"http://100.100.100.200/latest/meta-data",
+		"http://198.18.0.1",
+		"http://0.0.0.0:9380",
+		"http://[::ffff:127.0.0.1]:8080",
+		"http://[::ffff:a00:5]",
+		"file:///etc/passwd",
+	} {
+		_, err := NewConnector(newExternalKB(map[string]interface{}{
+			"provider": ConnectorGeneric,
+			"base_url": baseURL,
+		}), nil)
+		if err == nil || !strings.Contains(err.Error(), "invalid base_url") {
+			t.Errorf("%s: expected invalid base_url, got %v", baseURL, err)
+		}
+	}
+}
+
+func TestConnectorClientRefusesLoopbackDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach a loopback server")
+	}))
+	defer server.Close()
+
+	// 域名在创建连接器时无法判断，由客户端在连接时按解析出的IP拦截
+	client := newConnectorClient(time.Second, nil)
+	err := postJSON(context.Background(), client, server.URL, "", map[string]string{}, &struct{}{})
+	if err == nil || !strings.Contains(err.Error(), errBlockedAddress.Error()) {
+		t.Errorf("expected blocked dial, got %v", err)
+	}
+}
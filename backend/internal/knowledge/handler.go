@@ -0,0 +1,181 @@
+package knowledge
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 知识库处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建知识库处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateKnowledgeBase 创建知识库
+func (h *Handler) CreateKnowledgeBase(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req CreateKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kb, err := h.service.CreateKnowledgeBase(userID.(string), req)
+	if err != nil {
+		if strings.Contains(err.Error(), "unsupported") || strings.Contains(err.Error(), "invalid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, maskConfig(kb))
+}
+
+// GetKnowledgeBases 获取知识库列表
+func (h *Handler) GetKnowledgeBases(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	knowledgeBases, err := h.service.GetKnowledgeBases(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range knowledgeBases {
+		maskConfig(&knowledgeBases[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"knowledge_bases": knowledgeBases})
+}
+
+// GetKnowledgeBase 获取知识库详情及文档列表
+func (h *Handler) GetKnowledgeBase(c *gin.Context) {
+	knowledgeBaseID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	kb, err := h.service.GetKnowledgeBase(knowledgeBaseID, userID.(string))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	documents, err := h.service.GetDocuments(knowledgeBaseID, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"knowledge_base": maskConfig(kb),
+		"documents":      documents,
+	})
+}
+
+// DeleteKnowledgeBase 删除知识库
+func (h *Handler) DeleteKnowledgeBase(c *gin.Context) {
+	knowledgeBaseID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.DeleteKnowledgeBase(knowledgeBaseID, userID.(string)); err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "knowledge base deleted successfully"})
+}
+
+// AddDocument 添加文档
+func (h *Handler) AddDocument(c *gin.Context) {
+	knowledgeBaseID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req AddDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := h.service.AddDocument(c.Request.Context(), knowledgeBaseID, userID.(string), req)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+// Search 混合检索
+func (h *Handler) Search(c *gin.Context) {
+	knowledgeBaseID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.service.Search(c.Request.Context(), knowledgeBaseID, userID.(string), req)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   req.Query,
+		"results": results,
+	})
+}
+
+// maskConfig 不在响应中返回外部知识库的API密钥
+func maskConfig(kb *KnowledgeBase) *KnowledgeBase {
+	if _, ok := kb.Config["api_key"]; ok {
+		kb.Config["api_key"] = "***"
+	}
+	return kb
+}
+
+// writeAccessError 根据错误类型返回对应状态码
+func writeAccessError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": "knowledge base not found"})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
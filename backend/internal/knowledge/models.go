@@ -0,0 +1,138 @@
+package knowledge
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 知识库类型
+const (
+	TypeInternal = "internal"
+	TypeExternal = "external"
+)
+
+// KnowledgeBase 知识库模型
+type KnowledgeBase struct {
+	ID        string                 `json:"id" db:"id"`
+	UserID    string                 `json:"user_id" db:"user_id"`
+	Name      string                 `json:"name" db:"name"`
+	Type      string                 `json:"type" db:"type"`
+	Config    map[string]interface{} `json:"config" db:"config"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// Document 知识库文档
+type Document struct {
+	ID              string    `json:"id" db:"id"`
+	KnowledgeBaseID string    `json:"knowledge_base_id" db:"knowledge_base_id"`
+	Title           string    `json:"title" db:"title"`
+	Source          string    `json:"source" db:"source"`
+	Content         string    `json:"content,omitempty" db:"content"`
+	ChunkCount      int       `json:"chunk_count" db:"-"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// Chunk 文档分块
+type Chunk struct {
+	ID              string    `json:"id" db:"id"`
+	KnowledgeBaseID string    `json:"knowledge_base_id" db:"knowledge_base_id"`
+	DocumentID      string    `json:"document_id" db:"document_id"`
+	ChunkIndex      int       `json:"chunk_index" db:"chunk_index"`
+	Content         string    `json:"content" db:"content"`
+	Embedding       []float32 `json:"-" db:"embedding"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// Citation 检索结果引用，内部与外部知识库统一使用该格式
+type Citation struct {
+	KnowledgeBaseID string  `json:"knowledge_base_id"`
+	DocumentID      string  `json:"document_id"`
+	ChunkID         string  `json:"chunk_id"`
+	Title           string  `json:"title"`
+	Source          string  `json:"source,omitempty"`
+	Content         string  `json:"content"`
+	Score           float64 `json:"score"`
+}
+
+// SearchResult 混合检索结果
+type SearchResult struct {
+	Citation
+	LexicalRank int      `json:"lexical_rank,omitempty"`
+	VectorRank  int      `json:"vector_rank,omitempty"`
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+}
+
+// SearchRequest 检索请求
+type SearchRequest struct {
+	Query       string `json:"query" binding:"required"`
+	TopK        int    `json:"top_k"`
+	RerankModel string `json:"rerank_model"`
+}
+
+// CreateKnowledgeBaseRequest 创建知识库请求
+type CreateKnowledgeBaseRequest struct {
+	Name   string                 `json:"name" binding:"required"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// AddDocumentRequest 添加文档请求
+type AddDocumentRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Source  string `json:"source"`
+	Content string `json:"content" binding:"required"`
+}
+
+// NewKnowledgeBase 创建新知识库实例
+func NewKnowledgeBase(userID, name, kbType string, config map[string]interface{}) *KnowledgeBase {
+	now := time.Now()
+	if kbType == "" {
+		kbType = TypeInternal
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	return &KnowledgeBase{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Type:      kbType,
+		Config:    config,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NewDocument 创建新文档实例
+func NewDocument(knowledgeBaseID, title, source, content string) *Document {
+	return &Document{
+		ID:              uuid.New().String(),
+		KnowledgeBaseID: knowledgeBaseID,
+		Title:           title,
+		Source:          source,
+		Content:         content,
+		CreatedAt:       time.Now(),
+	}
+}
+
+// configString 读取知识库配置中的字符串项
+func (kb *KnowledgeBase) configString(key string) string {
+	if value, ok := kb.Config[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// NewChunk 创建新分块实例
+func NewChunk(knowledgeBaseID, documentID string, index int, content string) Chunk {
+	return Chunk{
+		ID:              uuid.New().String(),
+		KnowledgeBaseID: knowledgeBaseID,
+		DocumentID:      documentID,
+		ChunkIndex:      index,
+		Content:         content,
+		CreatedAt:       time.Now(),
+	}
+}
@@ -0,0 +1,245 @@
+package knowledge
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateKnowledgeBase 创建知识库
+func (r *Repository) CreateKnowledgeBase(kb *KnowledgeBase) error {
+	configJSON, err := json.Marshal(kb.Config)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO knowledge_bases (id, user_id, name, type, config, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = r.db.Exec(query, kb.ID, kb.UserID, kb.Name, kb.Type,
+		configJSON, kb.CreatedAt, kb.UpdatedAt)
+	return err
+}
+
+// GetKnowledgeBasesByUserID 获取用户的知识库列表
+func (r *Repository) GetKnowledgeBasesByUserID(userID string) ([]KnowledgeBase, error) {
+	query := `
+		SELECT id, user_id, name, type, config, created_at, updated_at
+		FROM knowledge_bases
+		WHERE user_id = $1
+		ORDER BY updated_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var knowledgeBases []KnowledgeBase
+	for rows.Next() {
+		var kb KnowledgeBase
+		var configJSON []byte
+
+		if err := rows.Scan(&kb.ID, &kb.UserID, &kb.Name, &kb.Type,
+			&configJSON, &kb.CreatedAt, &kb.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(configJSON, &kb.Config); err != nil || kb.Config == nil {
+			kb.Config = make(map[string]interface{})
+		}
+
+		knowledgeBases = append(knowledgeBases, kb)
+	}
+
+	return knowledgeBases, nil
+}
+
+// GetKnowledgeBaseByID 获取知识库详情
+func (r *Repository) GetKnowledgeBaseByID(id string) (*KnowledgeBase, error) {
+	query := `
+		SELECT id, user_id, name, type, config, created_at, updated_at
+		FROM knowledge_bases
+		WHERE id = $1`
+
+	var kb KnowledgeBase
+	var configJSON []byte
+
+	err := r.db.QueryRow(query, id).Scan(&kb.ID, &kb.UserID, &kb.Name, &kb.Type,
+		&configJSON, &kb.CreatedAt, &kb.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("knowledge base not found")
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(configJSON, &kb.Config); err != nil || kb.Config == nil {
+		kb.Config = make(map[string]interface{})
+	}
+
+	return &kb, nil
+}
+
+// DeleteKnowledgeBase 删除知识库
+func (r *Repository) DeleteKnowledgeBase(id string) error {
+	query := `DELETE FROM knowledge_bases WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// CreateDocumentWithChunks 在同一事务中保存文档及其分块
+func (r *Repository) CreateDocumentWithChunks(doc *Document, chunks []Chunk) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO knowledge_documents (id, knowledge_base_id, title, source, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		doc.ID, doc.KnowledgeBaseID, doc.Title, doc.Source, doc.Content, doc.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO knowledge_chunks (id, knowledge_base_id, document_id, chunk_index, content, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		var embedding interface{}
+		if len(chunk.Embedding) > 0 {
+			embedding = pq.Float32Array(chunk.Embedding)
+		}
+		if _, err := stmt.Exec(chunk.ID, chunk.KnowledgeBaseID, chunk.DocumentID,
+			chunk.ChunkIndex, chunk.Content, embedding, chunk.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert chunk: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE knowledge_bases SET updated_at = $2 WHERE id = $1`,
+		doc.KnowledgeBaseID, time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetDocumentsByKnowledgeBaseID 获取知识库的文档列表（不含正文）
+func (r *Repository) GetDocumentsByKnowledgeBaseID(knowledgeBaseID string) ([]Document, error) {
+	query := `
+		SELECT d.id, d.knowledge_base_id, d.title, d.source, d.created_at,
+			(SELECT COUNT(*) FROM knowledge_chunks c WHERE c.document_id = d.id)
+		FROM knowledge_documents d
+		WHERE d.knowledge_base_id = $1
+		ORDER BY d.created_at DESC`
+
+	rows, err := r.db.Query(query, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []Document
+	for rows.Next() {
+		var doc Document
+		if err := rows.Scan(&doc.ID, &doc.KnowledgeBaseID, &doc.Title, &doc.Source,
+			&doc.CreatedAt, &doc.ChunkCount); err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+
+	return documents, nil
+}
+
+// LexicalSearch 全文检索：tsvector排序，并对完整包含查询串的分块（错误码、SKU等标识符）额外加权
+func (r *Repository) LexicalSearch(knowledgeBaseID, query string, limit int) ([]SearchResult, error) {
+	sqlQuery := `
+		SELECT c.id, c.document_id, d.title, d.source, c.content,
+			ts_rank_cd(c.content_tsv, q) +
+			CASE WHEN position(lower($2) IN lower(c.content)) > 0 THEN 1.0 ELSE 0.0 END AS rank
+		FROM knowledge_chunks c
+		JOIN knowledge_documents d ON d.id = c.document_id,
+			websearch_to_tsquery('simple', $2) q
+		WHERE c.knowledge_base_id = $1
+			AND (c.content_tsv @@ q OR position(lower($2) IN lower(c.content)) > 0)
+		ORDER BY rank DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(sqlQuery, knowledgeBaseID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.ChunkID, &result.DocumentID, &result.Title,
+			&result.Source, &result.Content, &result.Score); err != nil {
+			return nil, err
+		}
+		result.KnowledgeBaseID = knowledgeBaseID
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// VectorSearch 在数据库中按余弦相似度排序，只返回最相近的limit个分块，避免把整个知识库的向量读入内存
+// 维度与查询向量不一致（例如更换过向量模型）的分块不参与排序
+func (r *Repository) VectorSearch(knowledgeBaseID string, embedding []float32, limit int) ([]SearchResult, error) {
+	query := `
+		WITH scored AS (
+			SELECT c.id, c.document_id, c.content,
+				(SELECT SUM(a::float8 * b) / NULLIF(SQRT(SUM(a::float8 * a)) * SQRT(SUM(b::float8 * b)), 0)
+				 FROM unnest(c.embedding, $2::real[]) AS v(a, b)) AS score
+			FROM knowledge_chunks c
+			WHERE c.knowledge_base_id = $1 AND c.embedding IS NOT NULL
+				AND array_length(c.embedding, 1) = array_length($2::real[], 1)
+		)
+		SELECT s.id, s.document_id, d.title, d.source, s.content, s.score
+		FROM scored s
+		JOIN knowledge_documents d ON d.id = s.document_id
+		WHERE s.score > 0
+		ORDER BY s.score DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(query, knowledgeBaseID, pq.Float32Array(embedding), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.ChunkID, &result.DocumentID, &result.Title,
+			&result.Source, &result.Content, &result.Score); err != nil {
+			return nil, err
+		}
+		result.KnowledgeBaseID = knowledgeBaseID
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
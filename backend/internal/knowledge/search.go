@@ -0,0 +1,106 @@
+package knowledge
+
+import (
+	"sort"
+	"strings"
+)
+
+// rrfK 倒数排名融合常数，取论文推荐值60
+const rrfK = 60
+
+// fuseRRF 使用倒数排名融合(Reciprocal Rank Fusion)合并全文检索与向量检索结果
+func fuseRRF(lexical, vector []SearchResult) []SearchResult {
+	fused := make(map[string]*SearchResult)
+	var order []string
+
+	add := func(results []SearchResult, isLexical bool) {
+		for i, result := range results {
+			rank := i + 1
+			item, ok := fused[result.ChunkID]
+			if !ok {
+				copied := result
+				copied.Score = 0
+				item = &copied
+				fused[result.ChunkID] = item
+				order = append(order, result.ChunkID)
+			}
+			item.Score += 1.0 / float64(rrfK+rank)
+			if isLexical {
+				item.LexicalRank = rank
+			} else {
+				item.VectorRank = rank
+			}
+		}
+	}
+
+	add(lexical, true)
+	add(vector, false)
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *fused[id])
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// chunkText 按段落将文本切分为不超过size个字符的分块，相邻分块保留overlap个字符的重叠
+func chunkText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var current []rune
+	pending := false
+
+	flush := func() {
+		if content := strings.TrimSpace(string(current)); content != "" {
+			chunks = append(chunks, content)
+		}
+		if overlap > 0 && len(current) > overlap {
+			current = append([]rune{}, current[len(current)-overlap:]...)
+		} else {
+			current = nil
+		}
+		pending = false
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		runes := []rune(strings.TrimSpace(paragraph))
+		if len(runes) == 0 {
+			continue
+		}
+
+		// 段落放不进当前分块时先输出已有内容
+		if pending && len(current)+len(runes)+2 > size {
+			flush()
+		}
+		if len(current) > 0 {
+			current = append(current, '\n', '\n')
+		}
+
+		for len(runes) > 0 {
+			room := size - len(current)
+			if len(runes) <= room {
+				current = append(current, runes...)
+				pending = true
+				break
+			}
+			current = append(current, runes[:room]...)
+			runes = runes[room:]
+			flush()
+		}
+	}
+
+	if pending {
+		flush()
+	}
+
+	return chunks
+}
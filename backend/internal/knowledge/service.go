@@ -0,0 +1,265 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/qicro/qicro/backend/internal/llm"
+)
+
+const (
+	// chunkSize 分块大小（字符数）
+	chunkSize = 800
+	// chunkOverlap 相邻分块重叠字符数
+	chunkOverlap = 100
+	// defaultTopK 默认返回结果数量
+	defaultTopK = 5
+	// maxTopK 最大返回结果数量
+	maxTopK = 50
+	// candidateMultiplier 每路检索召回的候选数量相对TopK的倍数
+	candidateMultiplier = 4
+)
+
+// Service 知识库服务
+type Service struct {
+	repo                  *Repository
+	llmService            *llm.Service
+	connectorAllowedHosts []string
+}
+
+// NewService 创建知识库服务
+func NewService(repo *Repository, llmService *llm.Service) *Service {
+	return &Service{
+		repo:       repo,
+		llmService: llmService,
+	}
+}
+
+// SetConnectorAllowedHosts 设置外部知识库允许连接的内网主机，其余回环与内网地址一律拒绝
+func (s *Service) SetConnectorAllowedHosts(hosts []string) {
+	s.connectorAllowedHosts = hosts
+}
+
+// CreateKnowledgeBase 创建知识库
+func (s *Service) CreateKnowledgeBase(userID string, req CreateKnowledgeBaseRequest) (*KnowledgeBase, error) {
+	kb := NewKnowledgeBase(userID, req.Name, req.Type, req.Config)
+	switch kb.Type {
+	case TypeInternal:
+	case TypeExternal:
+		// 创建时校验连接器配置
+		if _, err := NewConnector(kb, s.connectorAllowedHosts); err != nil {
+			return nil, fmt.Errorf("invalid external knowledge base: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported knowledge base type: %s", kb.Type)
+	}
+
+	if err := s.repo.CreateKnowledgeBase(kb); err != nil {
+		return nil, fmt.Errorf("failed to create knowledge base: %w", err)
+	}
+	return kb, nil
+}
+
+// GetKnowledgeBases 获取用户的知识库列表
+func (s *Service) GetKnowledgeBases(userID string) ([]KnowledgeBase, error) {
+	knowledgeBases, err := s.repo.GetKnowledgeBasesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge bases: %w", err)
+	}
+	return knowledgeBases, nil
+}
+
+// GetKnowledgeBase 获取知识库详情并检查权限
+func (s *Service) GetKnowledgeBase(knowledgeBaseID, userID string) (*KnowledgeBase, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(knowledgeBaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base: %w", err)
+	}
+
+	// 检查权限
+	if kb.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to knowledge base")
+	}
+
+	return kb, nil
+}
+
+// DeleteKnowledgeBase 删除知识库
+func (s *Service) DeleteKnowledgeBase(knowledgeBaseID, userID string) error {
+	if _, err := s.GetKnowledgeBase(knowledgeBaseID, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteKnowledgeBase(knowledgeBaseID); err != nil {
+		return fmt.Errorf("failed to delete knowledge base: %w", err)
+	}
+	return nil
+}
+
+// GetDocuments 获取知识库的文档列表
+func (s *Service) GetDocuments(knowledgeBaseID, userID string) ([]Document, error) {
+	if _, err := s.GetKnowledgeBase(knowledgeBaseID, userID); err != nil {
+		return nil, err
+	}
+
+	documents, err := s.repo.GetDocumentsByKnowledgeBaseID(knowledgeBaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+	return documents, nil
+}
+
+// AddDocument 添加文档：分块、向量化并保存
+func (s *Service) AddDocument(ctx context.Context, knowledgeBaseID, userID string, req AddDocumentRequest) (*Document, error) {
+	kb, err := s.GetKnowledgeBase(knowledgeBaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if kb.Type != TypeInternal {
+		return nil, fmt.Errorf("documents can only be added to internal knowledge bases")
+	}
+
+	doc := NewDocument(kb.ID, req.Title, req.Source, req.Content)
+
+	texts := chunkText(req.Content, chunkSize, chunkOverlap)
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("document content is empty")
+	}
+
+	chunks := make([]Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = NewChunk(kb.ID, doc.ID, i, text)
+	}
+
+	// 向量化失败时仍然保存分块，此时只能通过全文检索命中
+	embeddings, err := s.llmService.Embed(ctx, kb.configString("embedding_model"), texts)
+	if err != nil {
+		log.Printf("Warning: failed to embed document %s: %v", doc.ID, err)
+	} else {
+		for i := range chunks {
+			chunks[i].Embedding = embeddings[i]
+		}
+	}
+
+	if err := s.repo.CreateDocumentWithChunks(doc, chunks); err != nil {
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+
+	doc.ChunkCount = len(chunks)
+	return doc, nil
+}
+
+// Search 知识库检索：内部知识库使用混合检索，外部知识库委托给连接器，之后可选地使用模型重排序
+func (s *Service) Search(ctx context.Context, knowledgeBaseID, userID string, req SearchRequest) ([]SearchResult, error) {
+	kb, err := s.GetKnowledgeBase(knowledgeBaseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.SearchKnowledgeBase(ctx, kb, req)
+}
+
+// SearchByID 按ID加载知识库并检索，供其他模块内部调用（不做权限检查，调用方需事先校验）
+func (s *Service) SearchByID(ctx context.Context, knowledgeBaseID string, req SearchRequest) ([]SearchResult, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(knowledgeBaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base: %w", err)
+	}
+	return s.SearchKnowledgeBase(ctx, kb, req)
+}
+
+// SearchKnowledgeBase 在已加载的知识库中检索，供其他模块内部调用（不做权限检查）
+func (s *Service) SearchKnowledgeBase(ctx context.Context, kb *KnowledgeBase, req SearchRequest) ([]SearchResult, error) {
+	if req.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	topK := req.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+	if topK > maxTopK {
+		topK = maxTopK
+	}
+	candidates := topK * candidateMultiplier
+
+	var results []SearchResult
+	if kb.Type == TypeExternal {
+		connector, err := NewConnector(kb, s.connectorAllowedHosts)
+		if err != nil {
+			return nil, err
+		}
+		results, err = connector.Retrieve(ctx, req.Query, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve from external knowledge base: %w", err)
+		}
+	} else {
+		var err error
+		results, err = s.hybridSearch(ctx, kb, req.Query, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.RerankModel != "" && len(results) > 0 {
+		results = s.rerank(ctx, req.RerankModel, req.Query, results)
+	}
+
+	if len(results) > topK {
+		results = results[:topK]
+	}
+
+	return results, nil
+}
+
+// hybridSearch 内部知识库的全文检索与向量检索融合
+func (s *Service) hybridSearch(ctx context.Context, kb *KnowledgeBase, query string, candidates int) ([]SearchResult, error) {
+	lexical, err := s.repo.LexicalSearch(kb.ID, query, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run lexical search: %w", err)
+	}
+
+	vector, err := s.vectorSearch(ctx, kb, query, candidates)
+	if err != nil {
+		// 向量检索不可用时退化为纯全文检索
+		log.Printf("Warning: vector search failed for knowledge base %s: %v", kb.ID, err)
+	}
+
+	return fuseRRF(lexical, vector), nil
+}
+
+// vectorSearch 向量检索
+func (s *Service) vectorSearch(ctx context.Context, kb *KnowledgeBase, query string, limit int) ([]SearchResult, error) {
+	queryEmbeddings, err := s.llmService.Embed(ctx, kb.configString("embedding_model"), []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.VectorSearch(kb.ID, queryEmbeddings[0], limit)
+}
+
+// rerank 使用模型对融合结果重排序，失败时保持融合顺序
+func (s *Service) rerank(ctx context.Context, model, query string, results []SearchResult) []SearchResult {
+	documents := make([]string, len(results))
+	for i, result := range results {
+		documents[i] = result.Content
+	}
+
+	scores, err := s.llmService.Rerank(ctx, model, query, documents)
+	if err != nil {
+		log.Printf("Warning: rerank with model %s failed: %v", model, err)
+		return results
+	}
+
+	for i := range results {
+		score := scores[i]
+		results[i].RerankScore = &score
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return *results[i].RerankScore > *results[j].RerankScore
+	})
+
+	return results
+}
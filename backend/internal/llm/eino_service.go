@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 )
 
 // EinoService Eino增强的LLM服务
@@ -46,6 +47,9 @@ func (s *EinoService) createPredefinedChains() {
 	
 	// 问答链
 	s.chains["qa"] = &QAChain{service: s.basicService}
+
+	// 标题生成链
+	s.chains["title"] = &TitleChain{service: s.basicService}
 }
 
 // ChatChain 聊天链
@@ -252,6 +256,47 @@ func (c *QAChain) Execute(ctx context.Context, input map[string]interface{}) (*C
 	return c.service.Chat(ctx, request)
 }
 
+// TitleChain 对话标题生成链
+type TitleChain struct {
+	service *Service
+}
+
+func (c *TitleChain) Execute(ctx context.Context, input map[string]interface{}) (*ChatResponse, error) {
+	question, ok := input["question"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid question input")
+	}
+
+	answer, _ := input["answer"].(string)
+
+	model, ok := input["model"].(string)
+	if !ok || model == "" {
+		model = "gpt-3.5-turbo"
+	}
+
+	messages := []ChatMessage{
+		{
+			Role: "system",
+			Content: `你是一个对话标题生成助手。请根据用户的问题和助手的回答生成一个简短的对话标题，要求：
+1. 使用与用户问题相同的语言
+2. 中文不超过15个字，其他语言不超过8个单词
+3. 只输出标题本身，不要引号、标点结尾或任何解释`,
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("用户问题：%s\n\n助手回答：%s", truncateRunes(question, 1000), truncateRunes(answer, 1000)),
+		},
+	}
+
+	request := &ChatRequest{
+		Messages:  messages,
+		Model:     model,
+		MaxTokens: 32,
+	}
+
+	return c.service.Chat(ctx, request)
+}
+
 // ExecuteChain 执行指定的链
 func (s *EinoService) ExecuteChain(ctx context.Context, chainName string, input map[string]interface{}) (*ChatResponse, error) {
 	chain, exists := s.chains[chainName]
@@ -328,6 +373,40 @@ func (s *EinoService) AnswerQuestion(ctx context.Context, context_str, question,
 	return s.ExecuteChain(ctx, "qa", input)
 }
 
+// GenerateTitle 根据首轮问答生成对话标题
+func (s *EinoService) GenerateTitle(ctx context.Context, question, answer, model string) (string, error) {
+	input := map[string]interface{}{
+		"question": question,
+		"answer":   answer,
+		"model":    model,
+	}
+
+	result, err := s.ExecuteChain(ctx, "title", input)
+	if err != nil {
+		return "", err
+	}
+
+	title := cleanTitle(result.Message.Content)
+	if title == "" {
+		return "", fmt.Errorf("model returned an empty title")
+	}
+	return title, nil
+}
+
+// cleanTitle 取第一行并去掉模型常加的引号、前缀与结尾标点
+func cleanTitle(raw string) string {
+	title := strings.TrimSpace(raw)
+	if line, _, ok := strings.Cut(title, "\n"); ok {
+		title = strings.TrimSpace(line)
+	}
+	for _, prefix := range []string{"标题：", "标题:", "Title:", "title:"} {
+		title = strings.TrimPrefix(title, prefix)
+	}
+	title = strings.Trim(strings.TrimSpace(title), "\"'“”‘’「」《》*#")
+	title = strings.TrimRight(title, "。.!！?？,，;；:：")
+	return truncateRunes(strings.TrimSpace(title), 50)
+}
+
 // AddCustomChain 添加自定义链
 func (s *EinoService) AddCustomChain(name string, chain ChainHandler) {
 	s.chains[name] = chain
@@ -0,0 +1,321 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultEmbeddingModel 默认向量模型
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// mockEmbeddingDimensions mock向量维度
+const mockEmbeddingDimensions = 256
+
+// EmbeddingProvider 支持文本向量化的提供商
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// RerankProvider 支持重排序接口的提供商
+type RerankProvider interface {
+	Rerank(ctx context.Context, model, query string, documents []string) ([]float64, error)
+}
+
+// Embed 文本向量化
+func (s *Service) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+
+	provider, err := s.GetProviderForModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider for model %s: %w", model, err)
+	}
+
+	embedder, ok := provider.(EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support embeddings", provider.Name())
+	}
+
+	actualModelName, err := s.resolveModelName(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve model name for %s: %w", model, err)
+	}
+
+	return embedder.Embed(ctx, actualModelName, texts)
+}
+
+// Rerank 对候选文档按与查询的相关度打分，返回与documents一一对应的分数
+// 如果模型在配置中的类型为rerank且提供商支持重排序接口，则直接调用；否则使用聊天模型打分
+func (s *Service) Rerank(ctx context.Context, model, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	provider, err := s.GetProviderForModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider for model %s: %w", model, err)
+	}
+
+	actualModelName, err := s.resolveModelName(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve model name for %s: %w", model, err)
+	}
+
+	if reranker, ok := provider.(RerankProvider); ok && s.isModelType(model, "rerank") {
+		return reranker.Rerank(ctx, actualModelName, query, documents)
+	}
+
+	return s.rerankWithChat(ctx, model, query, documents)
+}
+
+// isModelType 检查配置中的模型类型
+func (s *Service) isModelType(modelName, modelType string) bool {
+	chatModels, err := s.configService.GetChatModels()
+	if err != nil {
+		return false
+	}
+	for _, model := range chatModels {
+		if (model.ID == modelName || model.Value == modelName) && model.Enabled {
+			return model.Type == modelType
+		}
+	}
+	return false
+}
+
+// rerankWithChat 使用聊天模型对候选文档进行相关度打分
+func (s *Service) rerankWithChat(ctx context.Context, model, query string, documents []string) ([]float64, error) {
+	var passages strings.Builder
+	for i, doc := range documents {
+		fmt.Fprintf(&passages, "[%d] %s\n\n", i, truncateRunes(doc, 800))
+	}
+
+	messages := []ChatMessage{
+		{
+			Role: "system",
+			Content: `你是一个检索结果重排序助手。请根据每个段落与查询的相关程度打分，分数范围0到10。
+只输出一个JSON数组，数组第i个元素为第i个段落的分数，不要输出任何其他内容。`,
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("查询：%s\n\n段落：\n%s", query, passages.String()),
+		},
+	}
+
+	response, err := s.Chat(ctx, &ChatRequest{
+		Messages: messages,
+		Model:    model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rerank chat failed: %w", err)
+	}
+
+	content := strings.TrimSpace(response.Message.Content)
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("invalid rerank response: %s", content)
+	}
+
+	var scores []float64
+	if err := json.Unmarshal([]byte(content[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse rerank scores: %w", err)
+	}
+	if len(scores) != len(documents) {
+		return nil, fmt.Errorf("rerank returned %d scores for %d documents", len(scores), len(documents))
+	}
+
+	return scores, nil
+}
+
+// Embed 调用OpenAI兼容的向量接口
+func (p *OpenAIProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("OpenAI API error: %s", string(body))
+	}
+
+	var embeddingResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embeddingResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddingResp.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, item := range embeddingResp.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// Rerank 调用OpenAI兼容网关的重排序接口（Jina/Cohere格式）
+func (p *OpenAIProvider) Rerank(ctx context.Context, model, query string, documents []string) ([]float64, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model":     model,
+		"query":     query,
+		"documents": documents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/rerank", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("rerank API error: %s", string(body))
+	}
+
+	var rerankResp struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&rerankResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	scores := make([]float64, len(documents))
+	for _, result := range rerankResp.Results {
+		if result.Index >= 0 && result.Index < len(documents) {
+			scores[result.Index] = result.RelevanceScore
+		}
+	}
+
+	return scores, nil
+}
+
+// Embed 生成确定性的mock向量（词袋哈希）
+func (p *MockOpenAIProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	return mockEmbeddings(texts), nil
+}
+
+// Embed 生成确定性的mock向量（词袋哈希）
+func (p *MockAnthropicProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	return mockEmbeddings(texts), nil
+}
+
+// mockEmbeddings 将文本按词哈希到固定维度并归一化，保证相同词汇的文本向量相近
+func mockEmbeddings(texts []string) [][]float32 {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, mockEmbeddingDimensions)
+		for _, token := range tokenize(text) {
+			h := fnv.New32a()
+			h.Write([]byte(token))
+			vector[h.Sum32()%mockEmbeddingDimensions]++
+		}
+
+		var norm float64
+		for _, v := range vector {
+			norm += float64(v * v)
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vector {
+				vector[j] = float32(float64(vector[j]) / norm)
+			}
+		}
+		embeddings[i] = vector
+	}
+	return embeddings
+}
+
+// tokenize 简单分词：拉丁字符按单词切分，CJK字符按单字切分
+func tokenize(text string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(current.String()))
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// truncateRunes 按字符数截断文本
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
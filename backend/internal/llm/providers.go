@@ -14,6 +14,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// streamClient 流式请求使用的HTTP客户端
+// 长回答可能持续数分钟，因此只限制等待响应头的时间，读取过程由context控制取消
+var streamClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 60 * time.Second,
+	},
+}
+
 // OpenAIProvider OpenAI提供商
 type OpenAIProvider struct {
 	apiKey  string
@@ -41,16 +50,11 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 	// 构建OpenAI API请求
 	openaiReq := map[string]interface{}{
 		"model":    req.Model,
-		"messages": p.convertMessages(req.Messages),
+		"messages": p.convertMessages(req.System, req.Messages),
 		"stream":   false,
 	}
 
-	if req.MaxTokens > 0 {
-		openaiReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature > 0 {
-		openaiReq["temperature"] = req.Temperature
-	}
+	applyOpenAIOptions(openaiReq, req)
 
 	// 调用OpenAI API
 	jsonData, err := json.Marshal(openaiReq)
@@ -134,16 +138,11 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-ch
 	// 构建OpenAI API请求
 	openaiReq := map[string]interface{}{
 		"model":    req.Model,
-		"messages": p.convertMessages(req.Messages),
+		"messages": p.convertMessages(req.System, req.Messages),
 		"stream":   true,
 	}
 
-	if req.MaxTokens > 0 {
-		openaiReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature > 0 {
-		openaiReq["temperature"] = req.Temperature
-	}
+	applyOpenAIOptions(openaiReq, req)
 
 	jsonData, err := json.Marshal(openaiReq)
 	if err != nil {
@@ -161,8 +160,7 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-ch
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(httpReq)
+	resp, err := streamClient.Do(httpReq)
 	if err != nil {
 		close(responseChan)
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -269,8 +267,14 @@ func (p *OpenAIProvider) GetModels() []Model {
 }
 
 // convertMessages 转换消息格式
-func (p *OpenAIProvider) convertMessages(messages []ChatMessage) []map[string]interface{} {
+func (p *OpenAIProvider) convertMessages(system string, messages []ChatMessage) []map[string]interface{} {
 	var openaiMessages []map[string]interface{}
+	if system != "" {
+		openaiMessages = append(openaiMessages, map[string]interface{}{
+			"role":    "system",
+			"content": system,
+		})
+	}
 	for _, msg := range messages {
 		openaiMessages = append(openaiMessages, map[string]interface{}{
 			"role":    msg.Role,
@@ -334,12 +338,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRe
 		"messages":   p.convertMessages(req.Messages),
 	}
 
-	if req.MaxTokens > 0 {
-		anthropicReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature > 0 {
-		anthropicReq["temperature"] = req.Temperature
-	}
+	applyAnthropicOptions(anthropicReq, req)
 
 	// 调用Anthropic API
 	jsonData, err := json.Marshal(anthropicReq)
@@ -414,7 +413,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRe
 			CompletionTokens: anthropicResp.Usage.OutputTokens,
 			TotalTokens:      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
 		},
-		FinishReason: anthropicResp.StopReason,
+		FinishReason: anthropicFinishReason(anthropicResp.StopReason),
 	}
 
 	return response, nil
@@ -432,12 +431,7 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 		"stream":     true,
 	}
 
-	if req.MaxTokens > 0 {
-		anthropicReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature > 0 {
-		anthropicReq["temperature"] = req.Temperature
-	}
+	applyAnthropicOptions(anthropicReq, req)
 
 	jsonData, err := json.Marshal(anthropicReq)
 	if err != nil {
@@ -456,8 +450,7 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(httpReq)
+	resp, err := streamClient.Do(httpReq)
 	if err != nil {
 		close(responseChan)
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -474,6 +467,9 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 		defer close(responseChan)
 		defer resp.Body.Close()
 
+		// stop_reason 在 message_delta 事件中返回，message_stop 时再作为结束原因发出
+		stopReason := ""
+
 		scanner := bufio.NewScanner(resp.Body)
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -491,8 +487,9 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 					Type  string `json:"type"`
 					Index int    `json:"index"`
 					Delta struct {
-						Type string `json:"type"`
-						Text string `json:"text"`
+						Type       string `json:"type"`
+						Text       string `json:"text"`
+						StopReason string `json:"stop_reason"`
 					} `json:"delta"`
 				}
 
@@ -517,6 +514,8 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 					case <-ctx.Done():
 						return
 					}
+				} else if streamResp.Type == "message_delta" && streamResp.Delta.StopReason != "" {
+					stopReason = streamResp.Delta.StopReason
 				} else if streamResp.Type == "message_stop" {
 					response := &ChatResponse{
 						ID:             uuid.New().String(),
@@ -527,7 +526,7 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 							Content:   "",
 							CreatedAt: time.Now(),
 						},
-						FinishReason: "stop",
+						FinishReason: anthropicFinishReason(stopReason),
 					}
 
 					select {
@@ -544,6 +543,114 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 	return responseChan, nil
 }
 
+// anthropicThinkingBudgets reasoning_effort对应的Anthropic extended thinking预算
+var anthropicThinkingBudgets = map[string]int{
+	"low":    1024,
+	"medium": 4096,
+	"high":   16384,
+}
+
+// reasoningModelPrefixes 支持reasoning_effort（OpenAI）或extended thinking（Anthropic）的模型前缀
+// 其他模型收到这些参数会直接返回400
+var reasoningModelPrefixes = []string{
+	"o1", "o3", "o4", "gpt-5",
+	"claude-3-7-sonnet", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4",
+}
+
+// SupportsReasoning 模型是否支持reasoning_effort
+func SupportsReasoning(model string) bool {
+	for _, prefix := range reasoningModelPrefixes {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") || strings.HasPrefix(model, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOpenAIOptions 将生成参数写入OpenAI请求
+// 推理模型不接受max_tokens，且temperature/top_p只能取默认值，因此改用max_completion_tokens并不发送采样参数
+func applyOpenAIOptions(openaiReq map[string]interface{}, req *ChatRequest) {
+	if len(req.Stop) > 0 {
+		openaiReq["stop"] = req.Stop
+	}
+
+	if SupportsReasoning(req.Model) {
+		if req.MaxTokens > 0 {
+			openaiReq["max_completion_tokens"] = req.MaxTokens
+		}
+		if req.ReasoningEffort != "" {
+			openaiReq["reasoning_effort"] = req.ReasoningEffort
+		}
+		return
+	}
+
+	// 对话切换到不支持的模型时reasoning_effort仍保留在设置中，此处忽略
+	if req.MaxTokens > 0 {
+		openaiReq["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		openaiReq["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		openaiReq["top_p"] = *req.TopP
+	}
+}
+
+// applyAnthropicOptions 将生成参数写入Anthropic请求
+// 系统提示词与消息中的system消息合并为顶层system字段；reasoning_effort映射为extended thinking预算
+func applyAnthropicOptions(anthropicReq map[string]interface{}, req *ChatRequest) {
+	var system []string
+	if req.System != "" {
+		system = append(system, req.System)
+	}
+	for _, msg := range req.Messages {
+		if msg.Role == "system" && msg.Content != "" {
+			system = append(system, msg.Content)
+		}
+	}
+	if len(system) > 0 {
+		anthropicReq["system"] = strings.Join(system, "\n\n")
+	}
+
+	maxTokens := 1024
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		anthropicReq["stop_sequences"] = req.Stop
+	}
+
+	if budget, ok := anthropicThinkingBudgets[req.ReasoningEffort]; ok && SupportsReasoning(req.Model) {
+		// thinking预算计入max_tokens，且不能与temperature/top_p同时调整
+		anthropicReq["thinking"] = map[string]interface{}{
+			"type":          "enabled",
+			"budget_tokens": budget,
+		}
+		anthropicReq["max_tokens"] = maxTokens + budget
+		return
+	}
+
+	anthropicReq["max_tokens"] = maxTokens
+	if req.Temperature != nil {
+		anthropicReq["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		anthropicReq["top_p"] = *req.TopP
+	}
+}
+
+// anthropicFinishReason 将Anthropic的stop_reason映射为与OpenAI一致的结束原因
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
+
 // GetModels 获取支持的模型
 func (p *AnthropicProvider) GetModels() []Model {
 	return []Model{
@@ -572,9 +679,13 @@ func (p *AnthropicProvider) GetModels() []Model {
 }
 
 // convertMessages 转换消息格式
+// Anthropic不接受system角色的消息，system消息由applyAnthropicOptions合并到顶层system字段
 func (p *AnthropicProvider) convertMessages(messages []ChatMessage) []map[string]interface{} {
 	var anthropicMessages []map[string]interface{}
 	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
 		anthropicMessages = append(anthropicMessages, map[string]interface{}{
 			"role":    msg.Role,
 			"content": msg.Content,
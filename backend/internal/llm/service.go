@@ -25,7 +25,13 @@ type ChatRequest struct {
 	Model          string        `json:"model"`
 	Stream         bool          `json:"stream,omitempty"`
 	MaxTokens      int           `json:"max_tokens,omitempty"`
-	Temperature    float64       `json:"temperature,omitempty"`
+	Temperature    *float64      `json:"temperature,omitempty"`
+	TopP           *float64      `json:"top_p,omitempty"`
+	Stop           []string      `json:"stop,omitempty"`
+	// System 系统提示词，OpenAI作为首条system消息发送，Anthropic使用顶层system字段
+	System string `json:"system,omitempty"`
+	// ReasoningEffort 推理强度 low/medium/high，Anthropic映射为extended thinking预算
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 
 // ChatResponse 聊天响应
@@ -303,6 +309,21 @@ func (s *Service) StreamChat(ctx context.Context, req *ChatRequest) (<-chan *Cha
 	return responseStream, nil
 }
 
+// FindChatModel 按ID或模型名称查找已启用的模型配置，未找到时返回nil
+func (s *Service) FindChatModel(modelName string) *config.ChatModel {
+	chatModels, err := s.configService.GetChatModels()
+	if err != nil {
+		return nil
+	}
+
+	for i := range chatModels {
+		if (chatModels[i].ID == modelName || chatModels[i].Value == modelName) && chatModels[i].Enabled {
+			return &chatModels[i]
+		}
+	}
+	return nil
+}
+
 // resolveModelName 解析模型名称（UUID或模型名称转换为实际模型名称）
 func (s *Service) resolveModelName(modelName string) (string, error) {
 	// 从配置系统获取模型信息
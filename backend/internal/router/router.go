@@ -2,20 +2,36 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/qicro/qicro/backend/internal/artifact"
+	"github.com/qicro/qicro/backend/internal/assistant"
 	"github.com/qicro/qicro/backend/internal/auth"
 	"github.com/qicro/qicro/backend/internal/chat"
+	"github.com/qicro/qicro/backend/internal/export"
+	"github.com/qicro/qicro/backend/internal/feedback"
 	configManagement "github.com/qicro/qicro/backend/internal/config"
+	"github.com/qicro/qicro/backend/internal/importer"
+	"github.com/qicro/qicro/backend/internal/jobs"
+	"github.com/qicro/qicro/backend/internal/knowledge"
 	"github.com/qicro/qicro/backend/internal/llm"
+	"github.com/qicro/qicro/backend/internal/share"
 	"github.com/qicro/qicro/backend/internal/websocket"
 )
 
 // Dependencies 路由依赖
 type Dependencies struct {
-	AuthHandler   *auth.Handler
-	ChatHandler   *chat.Handler
-	ConfigHandler *configManagement.Handler
-	LLMHandler    *llm.Handler
-	WSHub         *websocket.Hub
+	ArtifactHandler  *artifact.Handler
+	AssistantHandler *assistant.Handler
+	AuthHandler      *auth.Handler
+	ChatHandler      *chat.Handler
+	ConfigHandler    *configManagement.Handler
+	ExportHandler    *export.Handler
+	FeedbackHandler  *feedback.Handler
+	ImportHandler    *importer.Handler
+	JobHandler       *jobs.Handler
+	KnowledgeHandler *knowledge.Handler
+	LLMHandler       *llm.Handler
+	ShareHandler     *share.Handler
+	WSHub            *websocket.Hub
 }
 
 // SetupRouter 设置路由
@@ -28,6 +44,9 @@ func SetupRouter(deps *Dependencies) *gin.Engine {
 	// 健康检查端点
 	r.GET("/health", healthCheck)
 
+	// artifact沙箱预览（独立于/api路径，通过签名令牌授权）
+	r.GET("/sandbox/artifacts/:id", deps.ArtifactHandler.Preview)
+
 	// 设置API路由
 	setupAPIRoutes(r, deps)
 
@@ -39,7 +58,7 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Last-Event-ID, "+share.PasswordHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -63,7 +82,7 @@ func setupAPIRoutes(r *gin.Engine, deps *Dependencies) {
 	api := r.Group("/api")
 	{
 		// 公开路由
-		setupPublicRoutes(api)
+		setupPublicRoutes(api, deps)
 		
 		// 认证路由
 		setupAuthRoutes(api, deps.AuthHandler)
@@ -74,18 +93,21 @@ func setupAPIRoutes(r *gin.Engine, deps *Dependencies) {
 		// 管理员路由
 		setupAdminRoutes(api, deps)
 		
-		// WebSocket路由
+		// WebSocket路由，在握手中自行认证
 		api.GET("/ws", deps.WSHub.HandleWebSocket)
 	}
 }
 
 // setupPublicRoutes 设置公开路由
-func setupPublicRoutes(api *gin.RouterGroup) {
+func setupPublicRoutes(api *gin.RouterGroup, deps *Dependencies) {
 	api.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
+
+	// 对话分享的只读快照，通过不可猜测的slug（及可选密码）访问
+	api.GET("/share/:slug", deps.ShareHandler.GetSharedConversation)
 }
 
 // setupAuthRoutes 设置认证路由
@@ -113,6 +135,28 @@ func setupProtectedRoutes(api *gin.RouterGroup, deps *Dependencies) {
 		
 		// 聊天相关路由
 		setupChatRoutes(protected, deps.ChatHandler)
+
+		// 知识库相关路由
+		setupKnowledgeRoutes(protected, deps.KnowledgeHandler)
+
+		// Artifact相关路由
+		setupArtifactRoutes(protected, deps.ArtifactHandler)
+
+		// 助手相关路由
+		setupAssistantRoutes(protected, deps.AssistantHandler)
+
+		// 分享相关路由
+		setupShareRoutes(protected, deps.ShareHandler)
+
+		// 导入导出与后台任务路由
+		setupExportRoutes(protected, deps.ExportHandler, deps.ImportHandler, deps.JobHandler)
+
+		// 消息评价路由
+		setupFeedbackRoutes(protected, deps.FeedbackHandler)
+
+		// WebSocket一次性连接票据与在线状态
+		protected.POST("/ws/ticket", deps.WSHub.CreateTicket)
+		protected.GET("/ws/presence", deps.WSHub.GetPresence)
 	}
 }
 
@@ -131,25 +175,115 @@ func setupChatRoutes(group *gin.RouterGroup, chatHandler *chat.Handler) {
 	group.DELETE("/conversations/:id", chatHandler.DeleteConversation)
 	group.POST("/conversations/:id/messages", chatHandler.SendMessage)
 	group.GET("/conversations/:id/messages", chatHandler.GetMessages)
+	group.PUT("/conversations/:id/messages/:msgId", chatHandler.EditMessage)
+	group.POST("/conversations/:id/messages/:msgId/activate", chatHandler.SwitchBranch)
+	group.POST("/conversations/:id/messages/:msgId/regenerate", chatHandler.Regenerate)
+	group.GET("/generations/:id/stream", chatHandler.ResumeGeneration)
+	group.POST("/generations/:id/cancel", chatHandler.CancelGeneration)
+	group.GET("/search", chatHandler.Search)
+	group.POST("/conversations/bulk", chatHandler.BulkUpdateConversations)
+	group.GET("/folders", chatHandler.GetFolders)
+	group.POST("/folders", chatHandler.CreateFolder)
+	group.PUT("/folders/:id", chatHandler.UpdateFolder)
+	group.DELETE("/folders/:id", chatHandler.DeleteFolder)
+	group.GET("/tags", chatHandler.GetTags)
+	group.PUT("/tags/:tag", chatHandler.RenameTag)
+	group.DELETE("/tags/:tag", chatHandler.DeleteTag)
+	group.POST("/conversations/:id/compare", chatHandler.CompareMessages)
+	group.GET("/comparisons/leaderboard", chatHandler.GetLeaderboard)
+	group.GET("/comparisons/:id", chatHandler.GetComparison)
+	group.POST("/comparisons/:id/vote", chatHandler.VoteComparison)
+	group.GET("/events", chatHandler.GetEvents)
+}
+
+// setupKnowledgeRoutes 设置知识库路由
+func setupKnowledgeRoutes(group *gin.RouterGroup, knowledgeHandler *knowledge.Handler) {
+	group.POST("/knowledge", knowledgeHandler.CreateKnowledgeBase)
+	group.GET("/knowledge", knowledgeHandler.GetKnowledgeBases)
+	group.GET("/knowledge/:id", knowledgeHandler.GetKnowledgeBase)
+	group.DELETE("/knowledge/:id", knowledgeHandler.DeleteKnowledgeBase)
+	group.POST("/knowledge/:id/documents", knowledgeHandler.AddDocument)
+	group.POST("/knowledge/:id/search", knowledgeHandler.Search)
+}
+
+// setupArtifactRoutes 设置artifact路由
+func setupArtifactRoutes(group *gin.RouterGroup, artifactHandler *artifact.Handler) {
+	group.POST("/artifacts/render", artifactHandler.Render)
+	group.GET("/artifacts/:id", artifactHandler.GetArtifact)
+}
+
+// setupAssistantRoutes 设置助手路由
+func setupAssistantRoutes(group *gin.RouterGroup, assistantHandler *assistant.Handler) {
+	group.GET("/app-types", assistantHandler.GetCategories)
+	group.GET("/assistants", assistantHandler.GetAssistants)
+	group.POST("/assistants", assistantHandler.CreateAssistant)
+	group.GET("/assistants/:id", assistantHandler.GetAssistant)
+	group.PUT("/assistants/:id", assistantHandler.UpdateAssistant)
+	group.DELETE("/assistants/:id", assistantHandler.DeleteAssistant)
+	group.POST("/assistants/:id/conversations", assistantHandler.StartConversation)
+}
+
+// setupShareRoutes 设置分享路由
+func setupShareRoutes(group *gin.RouterGroup, shareHandler *share.Handler) {
+	group.POST("/conversations/:id/share", shareHandler.CreateShare)
+	group.GET("/shares", shareHandler.GetShares)
+	group.DELETE("/shares/:id", shareHandler.RevokeShare)
+}
+
+// setupExportRoutes 设置导入导出与后台任务路由
+func setupExportRoutes(group *gin.RouterGroup, exportHandler *export.Handler, importHandler *importer.Handler, jobHandler *jobs.Handler) {
+	group.GET("/conversations/:id/export", exportHandler.ExportConversation)
+	group.POST("/exports", exportHandler.StartBulkExport)
+	group.POST("/imports", importHandler.StartImport)
+	group.GET("/jobs", jobHandler.GetJobs)
+	group.GET("/jobs/:id", jobHandler.GetJob)
+	group.GET("/jobs/:id/download", jobHandler.DownloadJobFile)
+}
+
+// setupFeedbackRoutes 设置消息评价路由
+func setupFeedbackRoutes(group *gin.RouterGroup, feedbackHandler *feedback.Handler) {
+	group.GET("/feedback/reasons", feedbackHandler.GetReasons)
+	group.POST("/messages/:id/feedback", feedbackHandler.SubmitFeedback)
+	group.GET("/messages/:id/feedback", feedbackHandler.GetFeedback)
+	group.DELETE("/messages/:id/feedback", feedbackHandler.DeleteFeedback)
 }
 
 // setupAdminRoutes 设置管理员路由
 func setupAdminRoutes(api *gin.RouterGroup, deps *Dependencies) {
 	admin := api.Group("/admin")
 	admin.Use(deps.AuthHandler.AuthMiddleware())
-	admin.Use(deps.ConfigHandler.AdminMiddleware())
 	{
 		// API Keys 管理
-		setupAPIKeyRoutes(admin, deps.ConfigHandler)
+		setupAPIKeyRoutes(admin.Group("", auth.RequirePermission(auth.PermKeysManage)), deps.ConfigHandler)
 		
 		// App Types 管理
-		setupAppTypeRoutes(admin, deps.ConfigHandler)
+		setupAppTypeRoutes(admin.Group("", auth.RequirePermission(auth.PermModelsManage)), deps.ConfigHandler)
 		
 		// Chat Models 管理
-		setupChatModelRoutes(admin, deps.ConfigHandler)
+		setupChatModelRoutes(admin.Group("", auth.RequirePermission(auth.PermModelsManage)), deps.ConfigHandler)
+
+		// 公开助手管理
+		setupAdminAssistantRoutes(admin.Group("", auth.RequirePermission(auth.PermAssistantsManage)), deps.AssistantHandler)
+
+		// 消息评价报表与数据集导出
+		admin.GET("/feedback/report", auth.RequirePermission(auth.PermUsageRead), deps.FeedbackHandler.GetReport)
+		admin.GET("/feedback/export", auth.RequirePermission(auth.PermDataExport), deps.FeedbackHandler.ExportDataset)
+
+		// WebSocket在线统计
+		admin.GET("/ws/stats", auth.RequirePermission(auth.PermUsageRead), deps.WSHub.GetStats)
+
+		// 用户角色管理
+		setupUserRoutes(admin.Group("", auth.RequirePermission(auth.PermUsersManage)), deps.AuthHandler)
 	}
 }
 
+// setupUserRoutes 设置用户角色管理路由
+func setupUserRoutes(group *gin.RouterGroup, authHandler *auth.Handler) {
+	group.GET("/users", authHandler.GetUsers)
+	group.PUT("/users/:id/role", authHandler.UpdateUserRole)
+	group.GET("/roles", authHandler.GetRoles)
+}
+
 // setupAPIKeyRoutes 设置API Key路由
 func setupAPIKeyRoutes(group *gin.RouterGroup, configHandler *configManagement.Handler) {
 	group.POST("/api-keys", configHandler.CreateAPIKey)
@@ -172,4 +306,12 @@ func setupChatModelRoutes(group *gin.RouterGroup, configHandler *configManagemen
 	group.GET("/chat-models/:id", configHandler.GetChatModel)
 	group.PUT("/chat-models/:id", configHandler.UpdateChatModel)
 	group.DELETE("/chat-models/:id", configHandler.DeleteChatModel)
+}
+
+// setupAdminAssistantRoutes 设置助手管理路由
+func setupAdminAssistantRoutes(group *gin.RouterGroup, assistantHandler *assistant.Handler) {
+	group.GET("/assistants", assistantHandler.AdminGetAssistants)
+	group.POST("/assistants", assistantHandler.AdminCreateAssistant)
+	group.PUT("/assistants/:id", assistantHandler.AdminUpdateAssistant)
+	group.DELETE("/assistants/:id", assistantHandler.AdminDeleteAssistant)
 }
\ No newline at end of file
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 搜索后端类型
+const (
+	BackendSearxNG = "searxng"
+	BackendBing    = "bing"
+	BackendTavily  = "tavily"
+	BackendFake    = "fake"
+)
+
+// Result 搜索结果
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// Backend 搜索后端接口
+type Backend interface {
+	Name() string
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// NewBackend 根据名称创建搜索后端
+func NewBackend(name, apiURL, apiKey string) (Backend, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	switch strings.ToLower(name) {
+	case BackendSearxNG:
+		if apiURL == "" {
+			return nil, fmt.Errorf("searxng backend requires SEARCH_API_URL")
+		}
+		return &SearxNGBackend{baseURL: strings.TrimRight(apiURL, "/"), client: client}, nil
+	case BackendBing:
+		if apiKey == "" {
+			return nil, fmt.Errorf("bing backend requires SEARCH_API_KEY")
+		}
+		if apiURL == "" {
+			apiURL = "https://api.bing.microsoft.com/v7.0/search"
+		}
+		return &BingBackend{endpoint: apiURL, apiKey: apiKey, client: client}, nil
+	case BackendTavily:
+		if apiKey == "" {
+			return nil, fmt.Errorf("tavily backend requires SEARCH_API_KEY")
+		}
+		if apiURL == "" {
+			apiURL = "https://api.tavily.com/search"
+		}
+		return &TavilyBackend{endpoint: apiURL, apiKey: apiKey, client: client}, nil
+	case BackendFake:
+		return NewFakeBackend(nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s", name)
+	}
+}
+
+// decodeResponse 检查响应状态并解析JSON
+func decodeResponse(resp *http.Response, backend string, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s API error (%d): %s", backend, resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", backend, err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SearxNGBackend SearxNG搜索后端
+type SearxNGBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Name 返回后端名称
+func (b *SearxNGBackend) Name() string {
+	return BackendSearxNG
+}
+
+// Search 调用SearxNG的JSON接口
+func (b *SearxNGBackend) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	var searxResp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := decodeResponse(resp, "SearxNG", &searxResp); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, item := range searxResp.Results {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, Result{Title: item.Title, URL: item.URL, Snippet: item.Content})
+	}
+	return results, nil
+}
+
+// BingBackend Bing Web Search后端
+type BingBackend struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// Name 返回后端名称
+func (b *BingBackend) Name() string {
+	return BackendBing
+}
+
+// Search 调用Bing Web Search API
+func (b *BingBackend) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(limit))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", b.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Ocp-Apim-Subscription-Key", b.apiKey)
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	var bingResp struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := decodeResponse(resp, "Bing", &bingResp); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, item := range bingResp.WebPages.Value {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, Result{Title: item.Name, URL: item.URL, Snippet: item.Snippet})
+	}
+	return results, nil
+}
+
+// TavilyBackend Tavily搜索后端
+type TavilyBackend struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// Name 返回后端名称
+func (b *TavilyBackend) Name() string {
+	return BackendTavily
+}
+
+// Search 调用Tavily搜索API
+func (b *TavilyBackend) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"api_key":      b.apiKey,
+		"query":        query,
+		"max_results":  limit,
+		"search_depth": "basic",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", b.endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	var tavilyResp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := decodeResponse(resp, "Tavily", &tavilyResp); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, item := range tavilyResp.Results {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, Result{Title: item.Title, URL: item.URL, Snippet: item.Content})
+	}
+	return results, nil
+}
+
+// FakeBackend 本地假搜索后端，用于开发和测试，不访问网络
+type FakeBackend struct {
+	results []Result
+}
+
+// NewFakeBackend 创建假搜索后端，results为空时根据查询生成占位结果
+func NewFakeBackend(results []Result) *FakeBackend {
+	return &FakeBackend{results: results}
+}
+
+// Name 返回后端名称
+func (b *FakeBackend) Name() string {
+	return BackendFake
+}
+
+// Search 返回预设结果
+func (b *FakeBackend) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	results := b.results
+	if len(results) == 0 {
+		results = []Result{
+			{
+				Title:   fmt.Sprintf("%s - Example Reference", query),
+				URL:     "https://example.com/search?q=" + url.QueryEscape(query),
+				Snippet: fmt.Sprintf("This is a placeholder search result for \"%s\".", query),
+			},
+		}
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
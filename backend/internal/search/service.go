@@ -0,0 +1,149 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/qicro/qicro/backend/internal/llm"
+)
+
+const (
+	// defaultMaxResults 默认搜索结果数量
+	defaultMaxResults = 5
+	// snippetLimit 单条摘要最大字符数
+	snippetLimit = 300
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]+>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Citation 引用来源，持久化到助手消息的artifacts中
+type Citation struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// Service 网络搜索服务
+type Service struct {
+	backend      Backend
+	llmService   *llm.Service
+	maxResults   int
+	summarize    bool
+	summaryModel string
+}
+
+// NewService 创建网络搜索服务
+// summarize开启时先用模型总结搜索结果再注入，summaryModel为空时使用回答问题的模型；关闭时直接注入清理后的摘要
+// 总结会在回答前多一次非流式调用，增加首个token的延迟与成本，可通过summaryModel指定更便宜的小模型
+func NewService(backend Backend, llmService *llm.Service, maxResults int, summarize bool, summaryModel string) *Service {
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+	return &Service{
+		backend:      backend,
+		llmService:   llmService,
+		maxResults:   maxResults,
+		summarize:    summarize,
+		summaryModel: summaryModel,
+	}
+}
+
+// BackendName 返回当前搜索后端名称
+func (s *Service) BackendName() string {
+	return s.backend.Name()
+}
+
+// Search 执行搜索并转换为带编号的引用
+func (s *Service) Search(ctx context.Context, query string) ([]Citation, error) {
+	results, err := s.backend.Search(ctx, query, s.maxResults)
+	if err != nil {
+		return nil, fmt.Errorf("search with %s failed: %w", s.backend.Name(), err)
+	}
+
+	citations := make([]Citation, 0, len(results))
+	for _, result := range results {
+		if result.URL == "" {
+			continue
+		}
+		citations = append(citations, Citation{
+			Index:   len(citations) + 1,
+			Title:   cleanText(result.Title, snippetLimit),
+			URL:     result.URL,
+			Snippet: cleanText(result.Snippet, snippetLimit),
+		})
+	}
+
+	return citations, nil
+}
+
+// BuildContext 将搜索结果整理为注入到提示词中的系统消息内容
+// model为回答问题的模型，未配置总结模型时用于总结；总结失败时使用原始摘要
+func (s *Service) BuildContext(ctx context.Context, query, model string, citations []Citation) string {
+	var sources strings.Builder
+	for _, citation := range citations {
+		fmt.Fprintf(&sources, "[%d] %s (%s)\n%s\n\n", citation.Index, citation.Title, citation.URL, citation.Snippet)
+	}
+
+	summary := sources.String()
+	if s.summaryModel != "" {
+		model = s.summaryModel
+	}
+	if s.summarize && s.llmService != nil && model != "" {
+		if summarized, err := s.summarizeSources(ctx, query, model, summary); err != nil {
+			log.Printf("Warning: failed to summarize search results: %v", err)
+		} else {
+			summary = summarized
+		}
+	}
+
+	return fmt.Sprintf(`以下是针对用户问题的网络搜索结果。请结合这些信息回答，引用时使用方括号编号（如[1]），不要编造未出现的来源。
+
+%s`, strings.TrimSpace(summary))
+}
+
+// summarizeSources 使用模型总结搜索结果，保留来源编号
+func (s *Service) summarizeSources(ctx context.Context, query, model, sources string) (string, error) {
+	messages := []llm.ChatMessage{
+		{
+			Role: "system",
+			Content: `你是一个搜索结果整理助手。请针对用户的问题总结下列搜索结果中的相关事实，要求：
+1. 每条事实后保留对应来源的方括号编号
+2. 去除与问题无关的内容
+3. 不超过300字`,
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("问题：%s\n\n搜索结果：\n%s", query, sources),
+		},
+	}
+
+	response, err := s.llmService.Chat(ctx, &llm.ChatRequest{
+		Messages: messages,
+		Model:    model,
+	})
+	if err != nil {
+		return "", err
+	}
+	return response.Message.Content, nil
+}
+
+// cleanText 去除HTML标签与多余空白并截断
+func cleanText(text string, limit int) string {
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+
+	runes := []rune(text)
+	if len(runes) > limit {
+		return string(runes[:limit]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newFakeService(summarize bool) *Service {
+	return NewService(NewFakeBackend([]Result{
+		{Title: "<b>Go</b> 1.22 Release Notes", URL: "https://go.dev/doc/go1.22", Snippet: "Go 1.22 &amp; range-over-int"},
+		{Title: "No URL", URL: "", Snippet: "dropped"},
+		{Title: "Loop variables", URL: "https://go.dev/blog/loopvar-preview", Snippet: "  per-iteration\n\tloop variables  "},
+	}), nil, 5, summarize, "")
+}
+
+func TestSearchNumbersCitations(t *testing.T) {
+	citations, err := newFakeService(false).Search(context.Background(), "go 1.22")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	want := []Citation{
+		{Index: 1, Title: "Go 1.22 Release Notes", URL: "https://go.dev/doc/go1.22", Snippet: "Go 1.22 & range-over-int"},
+		{Index: 2, Title: "Loop variables", URL: "https://go.dev/blog/loopvar-preview", Snippet: "per-iteration loop variables"},
+	}
+	if len(citations) != len(want) {
+		t.Fatalf("expected %d citations, got %d: %+v", len(want), len(citations), citations)
+	}
+	for i := range want {
+		if citations[i] != want[i] {
+			t.Errorf("citation %d: got %+v, want %+v", i, citations[i], want[i])
+		}
+	}
+}
+
+func TestBuildContextKeepsCitationNumbers(t *testing.T) {
+	service := newFakeService(false)
+	citations, err := service.Search(context.Background(), "go 1.22")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	prompt := service.BuildContext(context.Background(), "go 1.22", "model", citations)
+	for _, expected := range []string{
+		"[1] Go 1.22 Release Notes (https://go.dev/doc/go1.22)\nGo 1.22 & range-over-int",
+		"[2] Loop variables (https://go.dev/blog/loopvar-preview)\nper-iteration loop variables",
+	} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("context missing %q:\n%s", expected, prompt)
+		}
+	}
+	if strings.Contains(prompt, "dropped") {
+		t.Errorf("context contains a result without URL:\n%s", prompt)
+	}
+}
+
+func TestBuildContextWithoutLLMUsesSnippets(t *testing.T) {
+	// 开启总结但没有可用的LLM服务时，仍使用原始摘要
+	service := newFakeService(true)
+	citations, _ := service.Search(context.Background(), "go 1.22")
+
+	prompt := service.BuildContext(context.Background(), "go 1.22", "model", citations)
+	if !strings.Contains(prompt, "[2] Loop variables") {
+		t.Errorf("expected raw snippets, got:\n%s", prompt)
+	}
+}
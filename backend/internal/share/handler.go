@@ -0,0 +1,113 @@
+package share
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordHeader 访问受密码保护的分享时携带密码的请求头
+const PasswordHeader = "X-Share-Password"
+
+// Handler 分享处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建分享处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// CreateShare 为对话创建分享链接
+func (h *Handler) CreateShare(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req CreateShareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	share, err := h.service.CreateShare(c.Param("id"), userID.(string), req)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+// GetShares 获取当前用户的分享，可按 conversation_id 过滤
+func (h *Handler) GetShares(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	shares, err := h.service.GetShares(userID.(string), c.Query("conversation_id"))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// RevokeShare 撤销分享
+func (h *Handler) RevokeShare(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.service.RevokeShare(c.Param("id"), userID.(string)); err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "share revoked successfully"})
+}
+
+// GetSharedConversation 公开访问分享快照（无需登录）
+func (h *Handler) GetSharedConversation(c *gin.Context) {
+	conv, err := h.service.GetSharedConversation(c.Request.Context(), c.Param("slug"), c.GetHeader(PasswordHeader))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	c.JSON(http.StatusOK, conv)
+}
+
+// writeShareError 根据错误类型返回对应状态码
+func writeShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPasswordRequired), errors.Is(err, ErrWrongPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "password_required": true})
+	case errors.Is(err, ErrTooManyAttempts):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "password_required": true})
+	case errors.Is(err, ErrShareExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(err.Error(), "invalid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,109 @@
+package share
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// attemptKeyPrefix 分享密码错误次数计数器，窗口内首次失败时设置过期
+	attemptKeyPrefix = "share:attempts:"
+	// maxPasswordAttempts 窗口内每个分享允许的密码错误次数
+	maxPasswordAttempts = 10
+	// attemptWindow 错误次数的统计窗口，超过上限后需等待窗口过期
+	attemptWindow = 15 * time.Minute
+)
+
+// recordAttemptScript 原子地累加错误次数，首次失败时设置过期时间
+var recordAttemptScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// AttemptLimiter 按分享标识限制密码错误次数，防止对短密码的暴力猜测
+// 传入Redis客户端时计数跨实例共享；为nil时只保存在本实例内存中，适用于单实例部署
+type AttemptLimiter struct {
+	redis *redis.Client
+
+	mu       sync.Mutex
+	attempts map[string]*attemptCount
+}
+
+// attemptCount 本实例内存中的错误次数
+type attemptCount struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewAttemptLimiter 创建密码尝试限制器
+func NewAttemptLimiter(redisClient *redis.Client) *AttemptLimiter {
+	return &AttemptLimiter{
+		redis:    redisClient,
+		attempts: make(map[string]*attemptCount),
+	}
+}
+
+// Blocked 分享的密码错误次数是否已达上限
+func (l *AttemptLimiter) Blocked(ctx context.Context, slug string) (bool, error) {
+	if l.redis == nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		entry, ok := l.attempts[slug]
+		if !ok {
+			return false, nil
+		}
+		if time.Now().After(entry.expiresAt) {
+			delete(l.attempts, slug)
+			return false, nil
+		}
+		return entry.count >= maxPasswordAttempts, nil
+	}
+
+	count, err := l.redis.Get(ctx, attemptKeyPrefix+slug).Int()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get password attempts: %w", err)
+	}
+	return count >= maxPasswordAttempts, nil
+}
+
+// RecordFailure 记录一次密码错误
+func (l *AttemptLimiter) RecordFailure(ctx context.Context, slug string) error {
+	if l.redis == nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		now := time.Now()
+		entry, ok := l.attempts[slug]
+		if !ok || now.After(entry.expiresAt) {
+			entry = &attemptCount{expiresAt: now.Add(attemptWindow)}
+			l.attempts[slug] = entry
+		}
+		entry.count++
+		return nil
+	}
+
+	if err := recordAttemptScript.Run(ctx, l.redis, []string{attemptKeyPrefix + slug}, int(attemptWindow.Seconds())).Err(); err != nil {
+		return fmt.Errorf("failed to record password attempt: %w", err)
+	}
+	return nil
+}
+
+// Reset 密码正确后清除错误次数
+func (l *AttemptLimiter) Reset(ctx context.Context, slug string) {
+	if l.redis == nil {
+		l.mu.Lock()
+		delete(l.attempts, slug)
+		l.mu.Unlock()
+		return
+	}
+	l.redis.Del(ctx, attemptKeyPrefix+slug)
+}
@@ -0,0 +1,58 @@
+package share
+
+import (
+	"errors"
+	"time"
+)
+
+// 访问分享时的错误
+var (
+	ErrShareNotFound    = errors.New("share not found")
+	ErrShareExpired     = errors.New("share expired")
+	ErrPasswordRequired = errors.New("password required")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many password attempts, try again later")
+)
+
+// Share 对话分享，保存创建时对话活动分支的只读快照，之后对原对话的修改不会影响快照
+type Share struct {
+	ID             string          `json:"id" db:"id"`
+	Slug           string          `json:"slug" db:"slug"`
+	ConversationID string          `json:"conversation_id" db:"conversation_id"`
+	UserID         string          `json:"user_id" db:"user_id"`
+	Title          string          `json:"title" db:"title"`
+	Model          string          `json:"model" db:"model"`
+	Messages       []SharedMessage `json:"-" db:"messages"`
+	MessageCount   int             `json:"message_count" db:"-"`
+	PasswordHash   string          `json:"-" db:"password_hash"`
+	HasPassword    bool            `json:"has_password" db:"-"`
+	ExpiresAt      *time.Time      `json:"expires_at" db:"expires_at"`
+	ViewCount      int             `json:"view_count" db:"view_count"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// SharedMessage 快照中的消息，不包含分支信息
+type SharedMessage struct {
+	Role      string                 `json:"role"`
+	Content   string                 `json:"content"`
+	Model     string                 `json:"model,omitempty"`
+	Artifacts map[string]interface{} `json:"artifacts,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// SharedConversation 公开访问返回的快照
+type SharedConversation struct {
+	Slug      string          `json:"slug"`
+	Title     string          `json:"title"`
+	Model     string          `json:"model"`
+	Messages  []SharedMessage `json:"messages"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// CreateShareRequest 创建分享请求，Title为空时使用对话标题
+type CreateShareRequest struct {
+	Title     string     `json:"title"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Password  string     `json:"password"`
+}
@@ -0,0 +1,121 @@
+package share
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Repository 分享仓库
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository 创建分享仓库
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateShare 保存分享快照
+func (r *Repository) CreateShare(share *Share) error {
+	messagesJSON, err := json.Marshal(share.Messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal messages: %w", err)
+	}
+
+	query := `
+		INSERT INTO conversation_shares (id, slug, conversation_id, user_id, title, model, messages,
+			password_hash, expires_at, view_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err = r.db.Exec(query, share.ID, share.Slug, share.ConversationID, share.UserID, share.Title,
+		share.Model, messagesJSON, share.PasswordHash, share.ExpiresAt, share.ViewCount, share.CreatedAt)
+	return err
+}
+
+// GetShareBySlug 按slug获取分享（包含消息快照）
+func (r *Repository) GetShareBySlug(slug string) (*Share, error) {
+	query := `
+		SELECT id, slug, conversation_id, user_id, title, model, messages, password_hash,
+			expires_at, view_count, created_at
+		FROM conversation_shares
+		WHERE slug = $1`
+
+	var share Share
+	var messagesJSON []byte
+	var expiresAt sql.NullTime
+	err := r.db.QueryRow(query, slug).Scan(&share.ID, &share.Slug, &share.ConversationID, &share.UserID,
+		&share.Title, &share.Model, &messagesJSON, &share.PasswordHash, &expiresAt,
+		&share.ViewCount, &share.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(messagesJSON, &share.Messages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal messages: %w", err)
+	}
+	if expiresAt.Valid {
+		share.ExpiresAt = &expiresAt.Time
+	}
+	share.MessageCount = len(share.Messages)
+	share.HasPassword = share.PasswordHash != ""
+	return &share, nil
+}
+
+// GetSharesByUserID 获取用户创建的分享（不加载消息快照），conversationID非空时只返回该对话的分享
+func (r *Repository) GetSharesByUserID(userID, conversationID string) ([]Share, error) {
+	query := `
+		SELECT id, slug, conversation_id, user_id, title, model, jsonb_array_length(messages),
+			password_hash <> '', expires_at, view_count, created_at
+		FROM conversation_shares
+		WHERE user_id = $1 AND ($2 = '' OR conversation_id::text = $2)
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		var share Share
+		var expiresAt sql.NullTime
+		err := rows.Scan(&share.ID, &share.Slug, &share.ConversationID, &share.UserID, &share.Title,
+			&share.Model, &share.MessageCount, &share.HasPassword, &expiresAt, &share.ViewCount, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			share.ExpiresAt = &expiresAt.Time
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// GetShareOwner 获取分享的创建者
+func (r *Repository) GetShareOwner(id string) (string, error) {
+	var userID string
+	err := r.db.QueryRow(`SELECT user_id FROM conversation_shares WHERE id = $1`, id).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrShareNotFound
+	}
+	return userID, err
+}
+
+// DeleteShare 删除分享
+func (r *Repository) DeleteShare(id string) error {
+	_, err := r.db.Exec(`DELETE FROM conversation_shares WHERE id = $1`, id)
+	return err
+}
+
+// IncrementViewCount 增加访问次数
+func (r *Repository) IncrementViewCount(id string) error {
+	_, err := r.db.Exec(`UPDATE conversation_shares SET view_count = view_count + 1 WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,210 @@
+package share
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/qicro/qicro/backend/internal/chat"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	slugBytes         = 16
+	maxTitleRunes     = 255
+	minPasswordLength = 8
+	// bcrypt只使用前72字节
+	maxPasswordLength = 72
+)
+
+// Service 分享服务
+type Service struct {
+	repo        *Repository
+	chatService *chat.Service
+	attempts    *AttemptLimiter
+}
+
+// NewService 创建分享服务
+func NewService(repo *Repository, chatService *chat.Service) *Service {
+	return &Service{
+		repo:        repo,
+		chatService: chatService,
+		attempts:    NewAttemptLimiter(nil),
+	}
+}
+
+// SetAttemptLimiter 替换密码尝试限制器（例如使用基于Redis的跨实例计数）
+func (s *Service) SetAttemptLimiter(limiter *AttemptLimiter) {
+	s.attempts = limiter
+}
+
+// CreateShare 为对话的当前分支创建只读快照
+func (s *Service) CreateShare(conversationID, userID string, req CreateShareRequest) (*Share, error) {
+	conv, err := s.chatService.GetConversation(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found")
+	}
+	if conv.UserID != userID {
+		return nil, fmt.Errorf("unauthorized access to conversation")
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = conv.Title
+	}
+	if utf8.RuneCountInString(title) > maxTitleRunes {
+		return nil, fmt.Errorf("invalid title: must be at most %d characters", maxTitleRunes)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("invalid expires_at: must be in the future")
+	}
+
+	messages, err := s.chatService.GetMessages(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("invalid conversation: nothing to share yet")
+	}
+
+	share := &Share{
+		ID:             uuid.New().String(),
+		ConversationID: conversationID,
+		UserID:         userID,
+		Title:          title,
+		Model:          conv.Model,
+		Messages:       snapshotMessages(messages),
+		ExpiresAt:      req.ExpiresAt,
+		CreatedAt:      time.Now(),
+	}
+	share.MessageCount = len(share.Messages)
+
+	if req.Password != "" {
+		if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+			return nil, fmt.Errorf("invalid password: must be %d-%d characters", minPasswordLength, maxPasswordLength)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		share.PasswordHash = string(hash)
+		share.HasPassword = true
+	}
+
+	if share.Slug, err = generateSlug(); err != nil {
+		return nil, fmt.Errorf("failed to generate slug: %w", err)
+	}
+
+	if err := s.repo.CreateShare(share); err != nil {
+		return nil, fmt.Errorf("failed to create share: %w", err)
+	}
+	return share, nil
+}
+
+// GetShares 获取用户创建的分享
+func (s *Service) GetShares(userID, conversationID string) ([]Share, error) {
+	if conversationID != "" {
+		if _, err := uuid.Parse(conversationID); err != nil {
+			return nil, fmt.Errorf("invalid conversation id: %s", conversationID)
+		}
+	}
+	shares, err := s.repo.GetSharesByUserID(userID, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shares: %w", err)
+	}
+	return shares, nil
+}
+
+// RevokeShare 撤销分享，撤销后链接立即失效
+func (s *Service) RevokeShare(shareID, userID string) error {
+	if _, err := uuid.Parse(shareID); err != nil {
+		return ErrShareNotFound
+	}
+	ownerID, err := s.repo.GetShareOwner(shareID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return fmt.Errorf("unauthorized access to share")
+	}
+	if err := s.repo.DeleteShare(shareID); err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+	return nil
+}
+
+// GetSharedConversation 公开访问分享快照，设置了密码的分享需要提供正确密码
+// 密码错误次数在窗口内达到上限后，即使密码正确也拒绝访问，直到窗口过期
+func (s *Service) GetSharedConversation(ctx context.Context, slug, password string) (*SharedConversation, error) {
+	share, err := s.repo.GetShareBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		return nil, ErrShareExpired
+	}
+	if share.PasswordHash != "" {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+		blocked, err := s.attempts.Blocked(ctx, share.Slug)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrTooManyAttempts
+		}
+		if bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) != nil {
+			if err := s.attempts.RecordFailure(ctx, share.Slug); err != nil {
+				return nil, err
+			}
+			return nil, ErrWrongPassword
+		}
+		s.attempts.Reset(ctx, share.Slug)
+	}
+
+	if err := s.repo.IncrementViewCount(share.ID); err != nil {
+		fmt.Printf("Warning: failed to count share view: %v\n", err)
+	}
+
+	return &SharedConversation{
+		Slug:      share.Slug,
+		Title:     share.Title,
+		Model:     share.Model,
+		Messages:  share.Messages,
+		ExpiresAt: share.ExpiresAt,
+		CreatedAt: share.CreatedAt,
+	}, nil
+}
+
+// snapshotMessages 复制活动分支上的消息，去掉分支与内部标识
+func snapshotMessages(messages []chat.Message) []SharedMessage {
+	snapshot := make([]SharedMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		snapshot = append(snapshot, SharedMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Model:     msg.Model,
+			Artifacts: msg.Artifacts,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	return snapshot
+}
+
+// generateSlug 生成不可猜测的分享标识（128位随机数）
+func generateSlug() (string, error) {
+	buf := make([]byte, slugBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
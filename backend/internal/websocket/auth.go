@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Subprotocol 客户端须在Sec-WebSocket-Protocol中声明的协议，服务端握手时回显
+	Subprotocol = "qicro.v1"
+	// bearerProtocolPrefix 通过子协议携带JWT：Sec-WebSocket-Protocol: qicro.v1, bearer.<token>
+	bearerProtocolPrefix = "bearer."
+	// ticketKeyPrefix 一次性连接票据
+	ticketKeyPrefix = "ws:ticket:"
+	// ticketTTL 票据有效期，只用于紧接着的握手
+	ticketTTL = 30 * time.Second
+	// expiryWarning 令牌过期前提前通知客户端续期
+	expiryWarning = time.Minute
+	// writeWait 写入控制帧的超时时间
+	writeWait = 10 * time.Second
+)
+
+// 关闭码，4000-4999为应用自定义范围
+const (
+	// CloseUnauthorized 缺少令牌、令牌无效或已过期
+	CloseUnauthorized = 4401
+	// CloseForbidden 续期令牌属于其他用户
+	CloseForbidden = 4403
+)
+
+// 服务端与客户端之间的认证消息类型
+const (
+	// MessageAuth 客户端发送新令牌为连接续期：{"type":"auth","data":{"token":"..."}}
+	MessageAuth = "auth"
+	// MessageAuthOK 续期成功，data中返回新的过期时间
+	MessageAuthOK = "auth.ok"
+	// MessageAuthExpiring 令牌即将过期，客户端应尽快发送auth消息
+	MessageAuthExpiring = "auth.expiring"
+)
+
+// Authenticator 校验JWT，返回用户ID与令牌过期时间
+type Authenticator interface {
+	AuthenticateToken(token string) (string, time.Time, error)
+}
+
+// SetAuthenticator 注入令牌校验器，未注入时拒绝所有连接
+func (h *Hub) SetAuthenticator(authenticator Authenticator) {
+	h.authenticator = authenticator
+}
+
+// SetAllowedOrigins 设置允许发起连接的Origin，同源请求与不带Origin的非浏览器客户端始终允许
+func (h *Hub) SetAllowedOrigins(origins []string) {
+	h.allowedOrigins = origins
+}
+
+// CreateTicket 为已登录用户签发一次性连接票据，用于无法设置子协议的客户端：/api/ws?ticket=...
+// 票据只能使用一次，连接的过期时间沿用签发票据时所用令牌的过期时间
+func (h *Hub) CreateTicket(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	expiresAt, ok := c.Get("token_expires_at")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	if h.redis == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "websocket tickets are not available"})
+		return
+	}
+
+	ticket, err := newTicket()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	value := fmt.Sprintf("%s|%d", userID.(string), expiresAt.(time.Time).Unix())
+	if err := h.redis.Set(c.Request.Context(), ticketKeyPrefix+ticket, value, ticketTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to create ticket: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_in": int(ticketTTL.Seconds())})
+}
+
+// authenticate 从握手请求的票据或子协议中认证用户，返回用户ID与连接过期时间
+func (h *Hub) authenticate(r *http.Request) (string, time.Time, error) {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return h.redeemTicket(r.Context(), ticket)
+	}
+
+	if h.authenticator == nil {
+		return "", time.Time{}, errors.New("authentication unavailable")
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token := strings.TrimPrefix(protocol, bearerProtocolPrefix); token != protocol && token != "" {
+			return h.authenticateToken(token)
+		}
+	}
+
+	return "", time.Time{}, errors.New("missing token")
+}
+
+// authenticateToken 校验JWT，已过期的令牌视为无效
+func (h *Hub) authenticateToken(token string) (string, time.Time, error) {
+	userID, expiresAt, err := h.authenticator.AuthenticateToken(token)
+	if err != nil {
+		return "", time.Time{}, errors.New("invalid token")
+	}
+	if !expiresAt.After(time.Now()) {
+		return "", time.Time{}, errors.New("token expired")
+	}
+	return userID, expiresAt, nil
+}
+
+// redeemTicket 兑换并作废一次性票据
+func (h *Hub) redeemTicket(ctx context.Context, ticket string) (string, time.Time, error) {
+	if h.redis == nil {
+		return "", time.Time{}, errors.New("invalid ticket")
+	}
+
+	value, err := h.redis.GetDel(ctx, ticketKeyPrefix+ticket).Result()
+	if err != nil {
+		return "", time.Time{}, errors.New("invalid ticket")
+	}
+
+	userID, unix, found := strings.Cut(value, "|")
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if !found || err != nil || userID == "" {
+		return "", time.Time{}, errors.New("invalid ticket")
+	}
+
+	expiresAt := time.Unix(seconds, 0)
+	if !expiresAt.After(time.Now()) {
+		return "", time.Time{}, errors.New("token expired")
+	}
+	return userID, expiresAt, nil
+}
+
+// checkOrigin 校验浏览器发起连接的Origin
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range h.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// closeWithCode 发送关闭帧后断开连接
+func closeWithCode(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+	conn.Close()
+}
+
+// newTicket 生成随机票据
+func newTicket() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,234 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/qicro/qicro/backend/internal/chat"
+	"github.com/qicro/qicro/backend/internal/llm"
+)
+
+// maxStreamsPerClient 单个连接上同时进行的流式生成数
+const maxStreamsPerClient = 4
+
+// 聊天协议消息类型
+// 客户端发送：chat.send、chat.cancel（以及auth续期）；服务端推送：chat.started、chat.delta、chat.done、
+// chat.cancel（取消请求已受理）与error，另有chat.DomainEvent定义的对话状态事件推送给用户的所有连接
+const (
+	MessageChatSend            = "chat.send"
+	MessageChatStarted         = "chat.started"
+	MessageChatDelta           = "chat.delta"
+	MessageChatDone            = "chat.done"
+	MessageChatCancel          = "chat.cancel"
+	MessageConversationUpdated = chat.EventConversationUpdated
+	MessageError               = "error"
+)
+
+// error消息中的错误码
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeUnknownType    = "unknown_type"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeTooManyStreams = "too_many_streams"
+	CodeInternal       = "internal_error"
+)
+
+// ClientMessage 客户端发来的消息，request_id由客户端生成，服务端的响应与后续事件原样带回
+type ClientMessage struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ChatSendRequest chat.send的数据
+type ChatSendRequest struct {
+	ConversationID string `json:"conversation_id"`
+	Content        string `json:"content"`
+}
+
+// ChatCancelRequest chat.cancel的数据，generation_id来自chat.started
+type ChatCancelRequest struct {
+	GenerationID string `json:"generation_id"`
+}
+
+// SetChatService 注入聊天服务，未注入时chat.*消息返回错误
+func (h *Hub) SetChatService(chatService *chat.Service) {
+	h.chatService = chatService
+}
+
+// handleMessage 按类型分发客户端消息，耗时的处理在独立goroutine中进行，不阻塞读取
+func (c *Client) handleMessage(msg ClientMessage) {
+	switch msg.Type {
+	case MessageAuth:
+		c.requestReauth(msg.Data)
+	case MessageChatSend:
+		c.handleChatSend(msg)
+	case MessageChatCancel:
+		c.handleChatCancel(msg)
+	default:
+		c.sendError(msg.RequestID, CodeUnknownType, fmt.Sprintf("unknown message type: %q", msg.Type))
+	}
+}
+
+// handleChatSend 发送消息并把流式回复转发到当前连接
+func (c *Client) handleChatSend(msg ClientMessage) {
+	var req ChatSendRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		c.sendError(msg.RequestID, CodeInvalidRequest, "invalid data: "+err.Error())
+		return
+	}
+	if req.ConversationID == "" || strings.TrimSpace(req.Content) == "" {
+		c.sendError(msg.RequestID, CodeInvalidRequest, "conversation_id and content are required")
+		return
+	}
+	if c.hub.chatService == nil {
+		c.sendError(msg.RequestID, CodeInternal, "chat is not available")
+		return
+	}
+	if c.streams.Add(1) > maxStreamsPerClient {
+		c.streams.Add(-1)
+		c.sendError(msg.RequestID, CodeTooManyStreams, fmt.Sprintf("at most %d concurrent generations per connection", maxStreamsPerClient))
+		return
+	}
+
+	go c.streamChat(msg.RequestID, req)
+}
+
+// streamChat 将生成事件转换为chat.started、chat.delta、chat.done
+// 连接关闭时停止转发，生成在服务端继续，可通过SSE接口重连
+func (c *Client) streamChat(requestID string, req ChatSendRequest) {
+	defer c.streams.Add(-1)
+
+	generation, err := c.hub.chatService.SendMessageStream(c.ctx, req.ConversationID, c.userID, req.Content)
+	if err != nil {
+		c.sendServiceError(requestID, err)
+		return
+	}
+	defer generation.Detach()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-generation.Events:
+			if !ok {
+				return
+			}
+			c.forwardEvent(requestID, req.ConversationID, generation.ID, event)
+		}
+	}
+}
+
+// forwardEvent 转换并推送单个生成事件
+func (c *Client) forwardEvent(requestID, conversationID, generationID string, event chat.GenerationEvent) {
+	switch event.Event {
+	case chat.EventUserMessage:
+		c.deliver(MessageChatStarted, requestID, map[string]interface{}{
+			"generation_id":   generationID,
+			"conversation_id": conversationID,
+			"user_message":    event.Data,
+		})
+
+	case chat.EventAssistantMessage:
+		response, ok := event.Data.(*llm.ChatResponse)
+		if !ok {
+			return
+		}
+		delta := map[string]interface{}{
+			"generation_id":   generationID,
+			"conversation_id": conversationID,
+			"content":         response.Message.Content,
+		}
+		if response.FinishReason != "" {
+			delta["finish_reason"] = response.FinishReason
+		}
+		if response.Usage != nil {
+			delta["usage"] = response.Usage
+		}
+		c.deliver(MessageChatDelta, requestID, delta)
+
+	case chat.EventDone:
+		done := map[string]interface{}{
+			"generation_id":   generationID,
+			"conversation_id": conversationID,
+		}
+		if result, ok := event.Data.(map[string]interface{}); ok {
+			for key, value := range result {
+				done[key] = value
+			}
+		}
+		c.deliver(MessageChatDone, requestID, done)
+	}
+}
+
+// handleChatCancel 取消进行中的生成，已生成的部分会保存，随后的chat.done中finish_reason为cancelled
+func (c *Client) handleChatCancel(msg ClientMessage) {
+	var req ChatCancelRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil || req.GenerationID == "" {
+		c.sendError(msg.RequestID, CodeInvalidRequest, "generation_id is required")
+		return
+	}
+	if c.hub.chatService == nil {
+		c.sendError(msg.RequestID, CodeInternal, "chat is not available")
+		return
+	}
+
+	if err := c.hub.chatService.CancelGeneration(c.ctx, req.GenerationID, c.userID); err != nil {
+		c.sendServiceError(msg.RequestID, err)
+		return
+	}
+
+	c.deliver(MessageChatCancel, msg.RequestID, map[string]interface{}{
+		"generation_id": req.GenerationID,
+		"status":        "cancelling",
+	})
+}
+
+// deliver 经由Hub向当前连接推送消息，连接关闭后放弃
+func (c *Client) deliver(messageType, requestID string, data interface{}) {
+	message, err := json.Marshal(Message{
+		Type:      messageType,
+		RequestID: requestID,
+		Data:      data,
+		UserID:    c.userID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+
+	select {
+	case c.hub.unicast <- clientMessage{client: c, data: message}:
+	case <-c.ctx.Done():
+	}
+}
+
+// sendError 推送error消息
+func (c *Client) sendError(requestID, code, message string) {
+	c.deliver(MessageError, requestID, errorData(code, message))
+}
+
+// sendServiceError 根据服务返回的错误类型推送error消息
+func (c *Client) sendServiceError(requestID string, err error) {
+	switch {
+	case strings.Contains(err.Error(), "unauthorized"):
+		c.sendError(requestID, CodeForbidden, "access denied")
+	case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "no rows"):
+		c.sendError(requestID, CodeNotFound, err.Error())
+	case strings.Contains(err.Error(), "invalid"):
+		c.sendError(requestID, CodeInvalidRequest, err.Error())
+	default:
+		c.sendError(requestID, CodeInternal, err.Error())
+	}
+}
+
+// errorData error消息的数据
+func errorData(code, message string) map[string]string {
+	return map[string]string{"code": code, "error": message}
+}
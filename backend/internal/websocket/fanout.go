@@ -0,0 +1,262 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// userChannelPrefix 按用户划分的推送频道，实例只订阅本地有连接的用户
+	userChannelPrefix = "ws:user:"
+	// presenceKeyPrefix 用户的在线连接（有序集合，成员为连接ID，分数为最近心跳时间）
+	presenceKeyPrefix = "ws:presence:"
+	// connectionsKey 所有实例的在线连接
+	connectionsKey = "ws:connections"
+	// onlineUsersKey 在线用户
+	onlineUsersKey = "ws:online"
+	// heartbeatInterval 本实例刷新连接心跳的间隔
+	heartbeatInterval = 30 * time.Second
+	// presenceTTL 超过该时长未刷新的连接视为已断开（例如实例异常退出）
+	presenceTTL = 90 * time.Second
+)
+
+// Stats 全部实例的在线统计
+type Stats struct {
+	OnlineUsers int64 `json:"online_users"`
+	Connections int64 `json:"connections"`
+	// LocalConnections 处理本次请求的实例上的连接数
+	LocalConnections int `json:"local_connections"`
+}
+
+// listen 接收订阅频道的消息并投递给本实例上该用户的连接
+func (h *Hub) listen() {
+	for msg := range h.pubsub.Channel() {
+		userID := strings.TrimPrefix(msg.Channel, userChannelPrefix)
+		h.direct <- directMessage{userID: userID, data: []byte(msg.Payload)}
+	}
+}
+
+// publish 将发给用户的消息发布到该用户的频道，由持有其连接的实例投递；未启用Redis或发布失败时只投递本实例
+func (h *Hub) publish(userID string, data []byte) {
+	if h.redis != nil {
+		err := h.redis.Publish(context.Background(), userChannelPrefix+userID, data).Err()
+		if err == nil {
+			return
+		}
+		log.Printf("Warning: failed to publish websocket message: %v", err)
+	}
+
+	h.direct <- directMessage{userID: userID, data: data}
+}
+
+// subscriptionChange 对用户频道的订阅或退订，由manageSubscriptions按顺序执行
+type subscriptionChange struct {
+	userID    string
+	subscribe bool
+}
+
+// manageSubscriptions 执行订阅变更，与Redis的网络往返不阻塞Run中的消息投递
+func (h *Hub) manageSubscriptions() {
+	for change := range h.subscriptions {
+		channel := userChannelPrefix + change.userID
+		if change.subscribe {
+			if err := h.pubsub.Subscribe(context.Background(), channel); err != nil {
+				log.Printf("Warning: failed to subscribe websocket channel: %v", err)
+			}
+			continue
+		}
+		if err := h.pubsub.Unsubscribe(context.Background(), channel); err != nil {
+			log.Printf("Warning: failed to unsubscribe websocket channel: %v", err)
+		}
+	}
+}
+
+// subscribe 用户在本实例的首个连接建立时订阅其频道，只在Run中调用
+func (h *Hub) subscribe(userID string) {
+	h.userConnections[userID]++
+	if h.pubsub == nil || h.userConnections[userID] > 1 {
+		return
+	}
+	h.subscriptions <- subscriptionChange{userID: userID, subscribe: true}
+}
+
+// unsubscribe 用户在本实例的最后一个连接断开时退订其频道，只在Run中调用
+func (h *Hub) unsubscribe(userID string) {
+	h.userConnections[userID]--
+	if h.userConnections[userID] > 0 {
+		return
+	}
+	delete(h.userConnections, userID)
+	if h.pubsub == nil {
+		return
+	}
+	h.subscriptions <- subscriptionChange{userID: userID, subscribe: false}
+}
+
+// trackConnection 在Redis中记录新连接
+func (h *Hub) trackConnection(client *Client) {
+	h.presenceMu.Lock()
+	h.presence[client.id] = client.userID
+	h.presenceMu.Unlock()
+
+	if h.redis == nil {
+		return
+	}
+	ctx := context.Background()
+	pipe := h.redis.TxPipeline()
+	touchConnection(ctx, pipe, client.id, client.userID, time.Now())
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Warning: failed to track websocket connection: %v", err)
+	}
+}
+
+// untrackConnection 从Redis中移除已断开的连接，用户没有其他连接时标记为离线
+func (h *Hub) untrackConnection(client *Client) {
+	h.presenceMu.Lock()
+	delete(h.presence, client.id)
+	h.presenceMu.Unlock()
+
+	if h.redis == nil {
+		return
+	}
+	ctx := context.Background()
+	key := presenceKeyPrefix + client.userID
+	pipe := h.redis.TxPipeline()
+	pipe.ZRem(ctx, key, client.id)
+	pipe.ZRem(ctx, connectionsKey, client.id)
+	remaining := pipe.ZCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Warning: failed to untrack websocket connection: %v", err)
+		return
+	}
+	if remaining.Val() == 0 {
+		h.redis.ZRem(ctx, onlineUsersKey, client.userID)
+	}
+}
+
+// heartbeat 定期刷新本实例连接的心跳，并清理超时未刷新的连接
+func (h *Hub) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.presenceMu.Lock()
+		connections := make(map[string]string, len(h.presence))
+		for id, userID := range h.presence {
+			connections[id] = userID
+		}
+		h.presenceMu.Unlock()
+
+		ctx := context.Background()
+		now := time.Now()
+		stale := strconv.FormatInt(now.Add(-presenceTTL).UnixMilli(), 10)
+
+		pipe := h.redis.Pipeline()
+		for id, userID := range connections {
+			touchConnection(ctx, pipe, id, userID, now)
+		}
+		pipe.ZRemRangeByScore(ctx, connectionsKey, "-inf", "("+stale)
+		pipe.ZRemRangeByScore(ctx, onlineUsersKey, "-inf", "("+stale)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Warning: failed to refresh websocket presence: %v", err)
+		}
+	}
+}
+
+// touchConnection 写入连接、用户在线状态与心跳时间
+func touchConnection(ctx context.Context, pipe redis.Pipeliner, connectionID, userID string, now time.Time) {
+	score := float64(now.UnixMilli())
+	key := presenceKeyPrefix + userID
+	pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: connectionID})
+	pipe.Expire(ctx, key, presenceTTL)
+	pipe.ZAdd(ctx, connectionsKey, redis.Z{Score: score, Member: connectionID})
+	pipe.ZAdd(ctx, onlineUsersKey, redis.Z{Score: score, Member: userID})
+}
+
+// UserConnections 获取用户在所有实例上的在线连接数
+func (h *Hub) UserConnections(ctx context.Context, userID string) (int64, error) {
+	if h.redis == nil {
+		h.presenceMu.Lock()
+		defer h.presenceMu.Unlock()
+		var count int64
+		for _, id := range h.presence {
+			if id == userID {
+				count++
+			}
+		}
+		return count, nil
+	}
+
+	stale := strconv.FormatInt(time.Now().Add(-presenceTTL).UnixMilli(), 10)
+	key := presenceKeyPrefix + userID
+	pipe := h.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+stale)
+	count := pipe.ZCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Stats 获取全部实例的在线用户数与连接数
+func (h *Hub) Stats(ctx context.Context) (*Stats, error) {
+	h.presenceMu.Lock()
+	stats := &Stats{LocalConnections: len(h.presence)}
+	users := make(map[string]bool)
+	for _, userID := range h.presence {
+		users[userID] = true
+	}
+	h.presenceMu.Unlock()
+
+	if h.redis == nil {
+		stats.OnlineUsers = int64(len(users))
+		stats.Connections = int64(stats.LocalConnections)
+		return stats, nil
+	}
+
+	stale := "(" + strconv.FormatInt(time.Now().Add(-presenceTTL).UnixMilli(), 10)
+	pipe := h.redis.Pipeline()
+	onlineUsers := pipe.ZCount(ctx, onlineUsersKey, stale, "+inf")
+	connections := pipe.ZCount(ctx, connectionsKey, stale, "+inf")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	stats.OnlineUsers = onlineUsers.Val()
+	stats.Connections = connections.Val()
+	return stats, nil
+}
+
+// GetPresence 获取当前用户在所有实例上的在线连接数
+func (h *Hub) GetPresence(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	count, err := h.UserConnections(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"online": count > 0, "connections": count})
+}
+
+// GetStats 获取全部实例的在线统计
+func (h *Hub) GetStats(c *gin.Context) {
+	stats, err := h.Stats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
@@ -1,48 +1,107 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
-	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/qicro/qicro/backend/internal/chat"
+	"github.com/redis/go-redis/v9"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type Client struct {
+	id     string
 	hub    *Hub
 	conn   *websocket.Conn
 	send   chan []byte
 	userID string
+	// expiresAt 连接认证的过期时间，到期未续期则以CloseUnauthorized关闭
+	expiresAt time.Time
+	// reauth 客户端发来的续期令牌，由writePump校验
+	reauth chan string
+	// ctx 连接关闭时取消，用于结束该连接上转发生成事件的goroutine
+	ctx    context.Context
+	cancel context.CancelFunc
+	// streams 该连接上正在转发的生成数
+	streams atomic.Int32
 }
 
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	direct     chan directMessage
+	unicast    chan clientMessage
 	register   chan *Client
 	unregister chan *Client
+
+	// userConnections 本实例上每个用户的连接数，决定是否订阅该用户的频道，只在Run中访问
+	userConnections map[string]int
+	// subscriptions 待执行的频道订阅变更，按发生顺序交给manageSubscriptions
+	subscriptions chan subscriptionChange
+
+	upgrader       websocket.Upgrader
+	authenticator  Authenticator
+	redis          *redis.Client
+	pubsub         *redis.PubSub
+	allowedOrigins []string
+	chatService    *chat.Service
+
+	// presence 本实例的连接（连接ID到用户ID），用于刷新Redis中的心跳
+	presence   map[string]string
+	presenceMu sync.Mutex
+}
+
+// directMessage 发给指定用户的消息，由Run统一投递以避免并发访问clients
+type directMessage struct {
+	userID string
+	data   []byte
 }
 
+// clientMessage 发给指定连接的消息，连接已注销时丢弃
+type clientMessage struct {
+	client *Client
+	data   []byte
+}
+
+// Message 服务端推送的消息，request_id对应触发它的客户端请求
 type Message struct {
 	Type      string      `json:"type"`
+	RequestID string      `json:"request_id,omitempty"`
 	Data      interface{} `json:"data"`
 	UserID    string      `json:"user_id,omitempty"`
 	Timestamp string      `json:"timestamp"`
 }
 
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+// NewHub 创建WebSocket Hub，redisClient为nil时只能推送给本实例上的连接，且不支持连接票据
+// 启用Redis时发给用户的消息经由该用户的频道分发，任一实例都能推送给任意用户，在线状态也记录在Redis中
+func NewHub(redisClient *redis.Client) *Hub {
+	h := &Hub{
+		clients:         make(map[*Client]bool),
+		direct:          make(chan directMessage, 256),
+		unicast:         make(chan clientMessage, 256),
+		register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		userConnections: make(map[string]int),
+		subscriptions:   make(chan subscriptionChange, 256),
+		redis:           redisClient,
+		presence:        make(map[string]string),
+	}
+	h.upgrader = websocket.Upgrader{
+		CheckOrigin:  h.checkOrigin,
+		Subprotocols: []string{Subprotocol},
+	}
+	if redisClient != nil {
+		h.pubsub = redisClient.Subscribe(context.Background())
+		go h.listen()
+		go h.manageSubscriptions()
+		go h.heartbeat()
 	}
+	return h
 }
 
 func (h *Hub) Run() {
@@ -50,57 +109,90 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
+			h.subscribe(client.userID)
 			log.Printf("Client connected: %s", client.userID)
 
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+				h.removeClient(client)
 				log.Printf("Client disconnected: %s", client.userID)
 			}
 
-		case message := <-h.broadcast:
+		case message := <-h.direct:
 			for client := range h.clients {
+				if client.userID != message.userID {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- message.data:
 				default:
-					close(client.send)
-					delete(h.clients, client)
+					h.removeClient(client)
 				}
 			}
+
+		case message := <-h.unicast:
+			if _, ok := h.clients[message.client]; !ok {
+				continue
+			}
+			select {
+			case message.client.send <- message.data:
+			default:
+				h.removeClient(message.client)
+			}
 		}
 	}
 }
 
+// removeClient 移除连接并关闭其发送队列，只在Run中调用
+func (h *Hub) removeClient(client *Client) {
+	delete(h.clients, client)
+	close(client.send)
+	h.unsubscribe(client.userID)
+}
+
+// HandleWebSocket 认证并建立WebSocket连接
+// 令牌通过子协议（Sec-WebSocket-Protocol: qicro.v1, bearer.<token>）或一次性票据（?ticket=）传递
+// Origin不在允许列表时拒绝握手；认证失败时完成握手后以CloseUnauthorized关闭，便于浏览器读取关闭码
 func (h *Hub) HandleWebSocket(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
-		return
-	}
+	userID, expiresAt, authErr := h.authenticate(c.Request)
 
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
+	if authErr != nil {
+		closeWithCode(conn, CloseUnauthorized, authErr.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	client := &Client{
-		hub:    h,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		userID: userID,
+		id:        uuid.New().String(),
+		hub:       h,
+		conn:      conn,
+		send:      make(chan []byte, 256),
+		userID:    userID,
+		expiresAt: expiresAt,
+		reauth:    make(chan string, 1),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	client.hub.register <- client
+	h.trackConnection(client)
 
 	go client.writePump()
 	go client.readPump()
 }
 
+// readPump 读取客户端消息并按类型分发，连接关闭时结束该连接上的事件转发
 func (c *Client) readPump() {
 	defer func() {
+		c.cancel()
 		c.hub.unregister <- c
+		c.hub.untrackConnection(c)
 		c.conn.Close()
 	}()
 
@@ -113,22 +205,25 @@ func (c *Client) readPump() {
 			break
 		}
 
-		var msg Message
+		var msg ClientMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("JSON unmarshal error: %v", err)
+			c.sendError("", CodeInvalidRequest, "invalid message: "+err.Error())
 			continue
 		}
 
-		msg.UserID = c.userID
-		
-		if data, err := json.Marshal(msg); err == nil {
-			c.hub.broadcast <- data
-		}
+		c.handleMessage(msg)
 	}
 }
 
+// writePump 负责连接上的全部写入，同时在令牌过期前提醒续期、到期后关闭连接
 func (c *Client) writePump() {
-	defer c.conn.Close()
+	expiry := time.NewTimer(time.Until(c.expiresAt))
+	warning := time.NewTimer(time.Until(c.expiresAt.Add(-expiryWarning)))
+	defer func() {
+		expiry.Stop()
+		warning.Stop()
+		c.conn.Close()
+	}()
 
 	for {
 		select {
@@ -142,8 +237,71 @@ func (c *Client) writePump() {
 				log.Printf("WebSocket write error: %v", err)
 				return
 			}
+
+		case token := <-c.reauth:
+			userID, expiresAt, err := c.hub.authenticateToken(token)
+			if err != nil {
+				c.writeControl(MessageError, errorData(CodeUnauthorized, err.Error()))
+				continue
+			}
+			if userID != c.userID {
+				closeWithCode(c.conn, CloseForbidden, "token belongs to another user")
+				return
+			}
+
+			c.expiresAt = expiresAt
+			resetTimer(expiry, time.Until(expiresAt))
+			resetTimer(warning, time.Until(expiresAt.Add(-expiryWarning)))
+			c.writeControl(MessageAuthOK, map[string]interface{}{"expires_at": expiresAt.Format(time.RFC3339)})
+
+		case <-warning.C:
+			c.writeControl(MessageAuthExpiring, map[string]interface{}{"expires_at": c.expiresAt.Format(time.RFC3339)})
+
+		case <-expiry.C:
+			closeWithCode(c.conn, CloseUnauthorized, "token expired")
+			return
+		}
+	}
+}
+
+// requestReauth 将auth消息中的令牌交给writePump校验，已有待处理的续期时丢弃
+func (c *Client) requestReauth(data json.RawMessage) {
+	var payload struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(data, &payload)
+
+	select {
+	case c.reauth <- payload.Token:
+	default:
+	}
+}
+
+// writeControl 直接向连接写入认证相关消息，只能在writePump中调用
+func (c *Client) writeControl(messageType string, data interface{}) {
+	message, err := json.Marshal(Message{
+		Type:      messageType,
+		Data:      data,
+		UserID:    c.userID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		log.Printf("WebSocket write error: %v", err)
+	}
+}
+
+// resetTimer 停止计时器并清空已触发的信号后重新计时
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
 		}
 	}
+	timer.Reset(d)
 }
 
 func (h *Hub) SendToUser(userID string, message Message) {
@@ -153,14 +311,15 @@ func (h *Hub) SendToUser(userID string, message Message) {
 		return
 	}
 
-	for client := range h.clients {
-		if client.userID == userID {
-			select {
-			case client.send <- data:
-			default:
-				close(client.send)
-				delete(h.clients, client)
-			}
-		}
-	}
-}
\ No newline at end of file
+	h.publish(userID, data)
+}
+
+// Notify 向用户的所有连接推送事件
+func (h *Hub) Notify(userID, eventType string, data interface{}) {
+	h.SendToUser(userID, Message{
+		Type:      eventType,
+		Data:      data,
+		UserID:    userID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
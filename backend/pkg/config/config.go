@@ -3,15 +3,23 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	LLM      LLMConfig
-	OAuth    OAuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Auth      AuthConfig
+	LLM       LLMConfig
+	OAuth     OAuthConfig
+	Chat      ChatConfig
+	Knowledge KnowledgeConfig
+	Search    SearchConfig
+	Artifact  ArtifactConfig
+	Export    ExportConfig
+	WebSocket WebSocketConfig
 }
 
 type ServerConfig struct {
@@ -39,6 +47,11 @@ type JWTConfig struct {
 	Secret string
 }
 
+type AuthConfig struct {
+	// AdminEmails 始终为管理员的邮箱，用于初始化第一个管理员
+	AdminEmails []string
+}
+
 type LLMConfig struct {
 	OpenAI    OpenAIConfig
 	Anthropic AnthropicConfig
@@ -52,6 +65,44 @@ type AnthropicConfig struct {
 	APIKey string
 }
 
+type ChatConfig struct {
+	TitleModel string
+	// TextSearchConfig 对话全文检索使用的Postgres text search配置，如 simple / english / chinese(zhparser)
+	TextSearchConfig string
+}
+
+type KnowledgeConfig struct {
+	// ConnectorAllowedHosts 外部知识库可以连接的内网主机（默认拒绝回环与内网地址）
+	ConnectorAllowedHosts []string
+}
+
+type SearchConfig struct {
+	Backend      string
+	APIURL       string
+	APIKey       string
+	MaxResults   int
+	// Summarize 注入前先用模型总结搜索结果，SummaryModel为空时使用对话的模型
+	Summarize    bool
+	SummaryModel string
+}
+
+type ArtifactConfig struct {
+	PreviewSecret     string
+	PreviewTTLMinutes int
+	FrameAncestors    string
+	PNGRenderer       string
+}
+
+type ExportConfig struct {
+	// PDFFont PDF导出嵌入的TrueType字体文件（.ttf/.ttc），为空时引用阅读器内置的中文字体
+	PDFFont string
+}
+
+type WebSocketConfig struct {
+	// AllowedOrigins 允许发起WebSocket连接的Origin，"*"表示不限制
+	AllowedOrigins []string
+}
+
 type OAuthConfig struct {
 	Google GoogleOAuthConfig
 	GitHub GitHubOAuthConfig
@@ -69,6 +120,9 @@ type GitHubOAuthConfig struct {
 
 func Load() *Config {
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	searchMaxResults, _ := strconv.Atoi(getEnv("SEARCH_MAX_RESULTS", "5"))
+	previewTTL, _ := strconv.Atoi(getEnv("ARTIFACT_PREVIEW_TTL_MINUTES", "10"))
+	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
 
 	return &Config{
 		Server: ServerConfig{
@@ -90,7 +144,10 @@ func Load() *Config {
 			DB:       redisDB,
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
+			Secret: jwtSecret,
+		},
+		Auth: AuthConfig{
+			AdminEmails: splitList(getEnv("ADMIN_EMAILS", "")),
 		},
 		LLM: LLMConfig{
 			OpenAI: OpenAIConfig{
@@ -110,6 +167,33 @@ func Load() *Config {
 				ClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
 			},
 		},
+		Chat: ChatConfig{
+			TitleModel:       getEnv("TITLE_MODEL", ""),
+			TextSearchConfig: getEnv("CHAT_TEXT_SEARCH_CONFIG", "simple"),
+		},
+		Knowledge: KnowledgeConfig{
+			ConnectorAllowedHosts: splitList(getEnv("KNOWLEDGE_CONNECTOR_ALLOWED_HOSTS", "")),
+		},
+		Search: SearchConfig{
+			Backend:      getEnv("SEARCH_BACKEND", ""),
+			APIURL:       getEnv("SEARCH_API_URL", ""),
+			APIKey:       getEnv("SEARCH_API_KEY", ""),
+			MaxResults:   searchMaxResults,
+			Summarize:    getEnv("SEARCH_SUMMARIZE", "true") != "false",
+			SummaryModel: getEnv("SEARCH_SUMMARY_MODEL", ""),
+		},
+		Artifact: ArtifactConfig{
+			PreviewSecret:     getEnv("ARTIFACT_PREVIEW_SECRET", jwtSecret),
+			PreviewTTLMinutes: previewTTL,
+			FrameAncestors:    getEnv("ARTIFACT_FRAME_ANCESTORS", "http://localhost:3000"),
+			PNGRenderer:       getEnv("ARTIFACT_PNG_RENDERER", ""),
+		},
+		Export: ExportConfig{
+			PDFFont: getEnv("EXPORT_PDF_FONT", ""),
+		},
+		WebSocket: WebSocketConfig{
+			AllowedOrigins: splitList(getEnv("WS_ALLOWED_ORIGINS", "http://localhost:3000")),
+		},
 	}
 }
 
@@ -118,4 +202,15 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+// splitList 解析逗号分隔的配置项，忽略空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"regexp"
 
 	_ "github.com/lib/pq"
 )
@@ -113,9 +114,142 @@ func (db *DB) CreateTables() error {
 			created_at TIMESTAMP DEFAULT NOW(),
 			updated_at TIMESTAMP DEFAULT NOW()
 		);`,
+		`CREATE TABLE IF NOT EXISTS knowledge_documents (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			knowledge_base_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+			title VARCHAR(255) NOT NULL,
+			source VARCHAR(1000) DEFAULT '',
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS knowledge_chunks (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			knowledge_base_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+			document_id UUID NOT NULL REFERENCES knowledge_documents(id) ON DELETE CASCADE,
+			chunk_index INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			embedding REAL[],
+			content_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS artifacts (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+			message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+			identifier VARCHAR(500) NOT NULL,
+			version INTEGER NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			title VARCHAR(500) NOT NULL,
+			language VARCHAR(50) DEFAULT '',
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(conversation_id, identifier, version)
+		);`,
+		`CREATE TABLE IF NOT EXISTS assistants (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			app_type_id UUID REFERENCES app_types(id) ON DELETE SET NULL,
+			name VARCHAR(100) NOT NULL,
+			description TEXT DEFAULT '',
+			icon VARCHAR(500),
+			system_prompt TEXT DEFAULT '',
+			model VARCHAR(100) DEFAULT '',
+			tools JSONB,
+			knowledge_base_ids JSONB,
+			opening_message TEXT DEFAULT '',
+			is_public BOOLEAN DEFAULT false,
+			sort_num INTEGER DEFAULT 0,
+			enabled BOOLEAN DEFAULT true,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS folders (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			parent_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			sort_num INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS conversation_tags (
+			conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+			tag VARCHAR(50) NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (conversation_id, tag)
+		);`,
+		`CREATE TABLE IF NOT EXISTS conversation_shares (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			slug VARCHAR(64) UNIQUE NOT NULL,
+			conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			title VARCHAR(255) DEFAULT '',
+			model VARCHAR(100) DEFAULT '',
+			messages JSONB NOT NULL,
+			password_hash VARCHAR(255) DEFAULT '',
+			expires_at TIMESTAMP,
+			view_count INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS jobs (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			type VARCHAR(50) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			progress INTEGER DEFAULT 0,
+			total INTEGER DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			result JSONB,
+			file_name VARCHAR(255) NOT NULL DEFAULT '',
+			content_type VARCHAR(100) NOT NULL DEFAULT '',
+			file_data BYTEA,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			finished_at TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS message_feedback (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			rating VARCHAR(10) NOT NULL,
+			reasons TEXT[] NOT NULL DEFAULT '{}',
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (message_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS comparisons (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+			user_message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+			models TEXT[] NOT NULL,
+			winner_message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+			winner_model VARCHAR(255),
+			created_at TIMESTAMP DEFAULT NOW(),
+			voted_at TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS comparison_responses (
+			comparison_id UUID NOT NULL REFERENCES comparisons(id) ON DELETE CASCADE,
+			model VARCHAR(255) NOT NULL,
+			message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+			PRIMARY KEY (comparison_id, model)
+		);`,
 		`CREATE INDEX IF NOT EXISTS idx_conversations_user_id ON conversations(user_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_knowledge_bases_user_id ON knowledge_bases(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_documents_kb_id ON knowledge_documents(knowledge_base_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_kb_id ON knowledge_chunks(knowledge_base_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_tsv ON knowledge_chunks USING GIN(content_tsv);`,
+		`CREATE INDEX IF NOT EXISTS idx_artifacts_message_id ON artifacts(message_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_user_id ON folders(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_tags_tag ON conversation_tags(tag);`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_shares_user_id ON conversation_shares(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs(user_id, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_message_feedback_updated_at ON message_feedback(updated_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_comparisons_voted_at ON comparisons(voted_at) WHERE winner_model IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_assistants_user_id ON assistants(user_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_assistants_app_type_id ON assistants(app_type_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_api_keys_provider ON api_keys(provider);`,
 		`CREATE INDEX IF NOT EXISTS idx_chat_models_type ON chat_models(type);`,
 		`CREATE INDEX IF NOT EXISTS idx_chat_models_provider ON chat_models(provider);`,
@@ -174,6 +308,50 @@ func (db *DB) CreateTables() error {
 		}
 	}
 
+	// Add columns introduced after the initial schema
+	migrationQueries := []string{
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES messages(id) ON DELETE CASCADE;`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS active_leaf_id UUID REFERENCES messages(id) ON DELETE SET NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_parent_id ON messages(parent_id);`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS model VARCHAR(100) DEFAULT '';`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS finish_reason VARCHAR(20) DEFAULT '';`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS assistant_id UUID REFERENCES assistants(id) ON DELETE SET NULL;`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS pinned BOOLEAN DEFAULT false;`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_folder_id ON conversations(folder_id);`,
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP DEFAULT NOW();`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			name VARCHAR(100) PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+	}
+
+	for _, query := range migrationQueries {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to migrate schema: %s, error: %w", query, err)
+		}
+	}
+
+	// Backfill: chain flat conversations in created_at order, then point the active leaf at the last message.
+	// Runs once: afterwards a NULL active leaf only means the leaf was deleted, and re-chaining would flatten branches
+	if err := db.migrateOnce("chain_flat_conversations", []string{
+		`UPDATE messages m SET parent_id = chained.prev_id
+		FROM (
+			SELECT msg.id, LAG(msg.id) OVER (PARTITION BY msg.conversation_id ORDER BY msg.created_at, msg.id) AS prev_id
+			FROM messages msg
+			JOIN conversations c ON c.id = msg.conversation_id
+			WHERE c.active_leaf_id IS NULL
+				AND NOT EXISTS (SELECT 1 FROM messages b WHERE b.conversation_id = c.id AND b.parent_id IS NOT NULL)
+		) chained
+		WHERE m.id = chained.id AND m.parent_id IS NULL AND chained.prev_id IS NOT NULL;`,
+		`UPDATE conversations c SET active_leaf_id = (
+			SELECT id FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC, id DESC LIMIT 1
+		) WHERE active_leaf_id IS NULL;`,
+	}); err != nil {
+		return err
+	}
+
 	// Insert default app types
 	defaultAppTypes := []string{
 		`INSERT INTO app_types (name, icon, sort_num, enabled) VALUES 
@@ -211,7 +389,8 @@ func (db *DB) CreateTables() error {
 			('chat', 'GPT-4o', 'gpt-4o', 'openai', 2, true, 15, 1.0, 4096, 16384, true),
 			('chat', 'Claude-3.5 Sonnet', 'claude-3-5-sonnet-20240620', 'anthropic', 3, true, 2, 1.0, 4000, 200000, true),
 			('chat', 'GPT-3.5 Turbo', 'gpt-3.5-turbo', 'openai', 4, true, 1, 1.0, 1024, 4096, true),
-			('img', 'DALL-E 3', 'dall-e-3', 'openai', 5, true, 10, 1.0, 1024, 8192, true)
+			('img', 'DALL-E 3', 'dall-e-3', 'openai', 5, true, 10, 1.0, 1024, 8192, true),
+			('embedding', 'Text Embedding 3 Small', 'text-embedding-3-small', 'openai', 6, true, 1, 1.0, 8191, 8191, false)
 		ON CONFLICT (provider, value) DO NOTHING;`,
 	}
 
@@ -223,4 +402,70 @@ func (db *DB) CreateTables() error {
 
 	log.Println("Database tables created successfully")
 	return nil
-}
\ No newline at end of file
+}
+
+// migrateOnce 在事务中执行数据迁移，并记录到schema_migrations，已执行过的迁移直接跳过
+func (db *DB) migrateOnce(name string, queries []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	// 多实例同时启动时，只有插入成功的实例执行迁移，其他实例等待其提交后跳过
+	result, err := tx.Exec(`INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+	if applied, err := result.RowsAffected(); err != nil || applied == 0 {
+		return err
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to run migration %s: %s, error: %w", name, query, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", name, err)
+	}
+	log.Printf("Applied migration %s", name)
+	return nil
+}
+
+// textSearchConfigPattern 合法的text search配置名，配置名会拼接进索引表达式
+var textSearchConfigPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// CreateSearchIndexes 创建对话全文检索所需的索引
+// 分词检索使用按配置名创建的表达式索引；中文等无空格分词的语言依赖pg_trgm三元组索引做子串匹配
+func (db *DB) CreateSearchIndexes(textSearchConfig string) error {
+	if !textSearchConfigPattern.MatchString(textSearchConfig) {
+		return fmt.Errorf("invalid text search config: %s", textSearchConfig)
+	}
+
+	queries := []string{
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_messages_content_fts_%[1]s ON messages USING GIN (to_tsvector('%[1]s', content));`, textSearchConfig),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_conversations_title_fts_%[1]s ON conversations USING GIN (to_tsvector('%[1]s', COALESCE(title, '')));`, textSearchConfig),
+		`CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create search index: %s, error: %w", query, err)
+		}
+	}
+
+	// pg_trgm需要扩展权限，不可用时只影响中文子串检索的性能
+	trigramQueries := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm;`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_content_trgm ON messages USING GIN (content gin_trgm_ops);`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_title_trgm ON conversations USING GIN (title gin_trgm_ops);`,
+	}
+	for _, query := range trigramQueries {
+		if _, err := db.Exec(query); err != nil {
+			log.Printf("Warning: failed to create trigram index: %v", err)
+			break
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import { useEffect } from 'react';
 import { useAuthStore } from '@/store/auth';
 import { useChatStore } from '@/store/chat';
 import { useWebSocket } from '@/hooks/useWebSocket';
+import { useConversationSync } from '@/hooks/useConversationSync';
 import ConversationSidebar from './ConversationSidebar';
 import MessageList from './MessageList';
 import MessageInput from './MessageInput';
@@ -30,19 +31,20 @@ export default function Chat() {
     loadModels
   } = useChatStore();
 
-  const { sendMessage: sendWebSocketMessage, isConnected } = useWebSocket({
+  const sync = useConversationSync();
+
+  const { isConnected } = useWebSocket({
     onMessage: (data: unknown) => {
       console.log('Received WebSocket message:', data);
-      // Handle real-time updates here
+      // 其他设备上的对话变更
       if (typeof data === 'object' && data !== null && 'type' in data) {
-        const messageData = data as { type: string; [key: string]: unknown };
-        if (messageData.type === 'message_update') {
-          // Update message in store
-        }
+        sync.handleMessage(data as { type: string; data?: unknown });
       }
     },
     onConnect: () => {
       console.log('Connected to WebSocket');
+      // 重连后补齐断开期间错过的事件
+      sync.resync();
     },
     onDisconnect: () => {
       console.log('Disconnected from WebSocket');
@@ -77,7 +79,7 @@ export default function Chat() {
         const validModels = models.filter(model => model.id && model.id.trim() !== '');
         const modelToUse = selectedModel || (validModels.length > 0 ? validModels[0].id : 'gpt-3.5-turbo');
         console.log('Debug: Creating new conversation with model:', modelToUse);
-        conversationToUse = await createConversation('New Chat', modelToUse);
+        conversationToUse = await createConversation(modelToUse);
         console.log('Debug: New conversation created:', conversationToUse);
       } catch (error) {
         console.error('Failed to create conversation:', error);
@@ -87,15 +89,6 @@ export default function Chat() {
     
     console.log('Debug: Using conversation:', conversationToUse.id);
     
-    // Send via WebSocket for real-time updates
-    console.log('Debug: Sending WebSocket message');
-    sendWebSocketMessage({
-      type: 'new_message',
-      conversation_id: conversationToUse.id,
-      content: content,
-      timestamp: new Date().toISOString(),
-    });
-    
     // Also send via HTTP API for persistence and LLM response
     console.log('Debug: Calling sendMessage API with stream=true');
     try {
@@ -206,11 +199,13 @@ export default function Chat() {
           
           <div className="flex items-center gap-2">
             <span className="text-muted-foreground text-xs">Real-time Chat</span>
-            <Link href="/admin">
-              <Button variant="ghost" size="sm" className="h-6 px-2">
-                <Settings className="h-3 w-3" />
-              </Button>
-            </Link>
+            {user.role && user.role !== 'user' && (
+              <Link href="/admin">
+                <Button variant="ghost" size="sm" className="h-6 px-2">
+                  <Settings className="h-3 w-3" />
+                </Button>
+              </Link>
+            )}
           </div>
         </div>
 
@@ -35,9 +35,10 @@ export default function ConversationSidebar() {
   }, [loadConversations, loadModels]);
 
   const handleCreateConversation = async () => {
-    if (newTitle.trim() && selectedModel) {
+    if (selectedModel) {
       try {
-        await createConversation(newTitle.trim(), selectedModel);
+        // An empty title is filled in by the server after the first reply
+        await createConversation(selectedModel, newTitle.trim() || undefined);
         setNewTitle('');
         setSelectedModel('');
         setIsCreateOpen(false);
@@ -87,7 +88,7 @@ export default function ConversationSidebar() {
                     id="title"
                     value={newTitle}
                     onChange={(e) => setNewTitle(e.target.value)}
-                    placeholder="Enter conversation title"
+                    placeholder="Optional, named automatically after the first reply"
                   />
                 </div>
                 <div>
@@ -111,7 +112,7 @@ export default function ConversationSidebar() {
                   </Button>
                   <Button 
                     onClick={handleCreateConversation}
-                    disabled={!newTitle.trim() || !selectedModel}
+                    disabled={!selectedModel}
                   >
                     Create
                   </Button>
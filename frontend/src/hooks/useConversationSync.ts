@@ -0,0 +1,97 @@
+import { useCallback, useRef } from 'react';
+import { chatAPI } from '@/lib/chat-api';
+import { useChatStore } from '@/store/chat';
+import { SyncEvent } from '@/types/chat';
+
+const SYNC_EVENT_TYPES = new Set([
+  'conversation.created',
+  'conversation.updated',
+  'conversation.deleted',
+  'message.created',
+  'generation.started',
+  'generation.finished',
+]);
+
+// Keeps conversations in step with the user's other devices. Every domain event carries a
+// per-user sequence number; a gap (or a reconnect) triggers a catch-up via /api/events.
+export function useConversationSync() {
+  const lastSeq = useRef<number | null>(null);
+  const syncing = useRef(false);
+  const pending = useRef(false);
+
+  const apply = useCallback(async (event: SyncEvent) => {
+    const { currentConversation, isStreaming, loadConversations, selectConversation } = useChatStore.getState();
+
+    if (event.type.startsWith('conversation.')) {
+      await loadConversations();
+    }
+
+    // The local stream already renders this device's own generation
+    if (currentConversation?.id !== event.conversation_id || isStreaming) return;
+
+    if (event.type === 'conversation.deleted') {
+      useChatStore.setState({ currentConversation: null, messages: [] });
+    } else if (event.type === 'message.created' || event.type === 'conversation.updated') {
+      await selectConversation(event.conversation_id);
+    }
+  }, []);
+
+  const reloadAll = useCallback(async () => {
+    const { currentConversation, loadConversations, selectConversation } = useChatStore.getState();
+    await loadConversations();
+    if (currentConversation) {
+      await selectConversation(currentConversation.id);
+    }
+  }, []);
+
+  const resync = useCallback(async () => {
+    if (lastSeq.current === null) return;
+    if (syncing.current) {
+      // Events that arrive mid-sync are picked up by another pass
+      pending.current = true;
+      return;
+    }
+    syncing.current = true;
+    try {
+      do {
+        pending.current = false;
+        const page = await chatAPI.getEvents(lastSeq.current);
+        if (page.resync_required) {
+          await reloadAll();
+        } else {
+          for (const event of page.events) {
+            await apply(event);
+          }
+        }
+        lastSeq.current = page.seq;
+      } while (pending.current);
+    } catch (error) {
+      console.warn('Failed to resync conversation events:', error);
+    } finally {
+      syncing.current = false;
+    }
+  }, [apply, reloadAll]);
+
+  const handleMessage = useCallback((message: { type: string; data?: unknown }) => {
+    if (!SYNC_EVENT_TYPES.has(message.type)) return;
+    const event = message.data as SyncEvent | undefined;
+    if (!event) return;
+
+    // seq 0 means the server could not record the event; apply it without moving the cursor
+    if (!event.seq || lastSeq.current === null) {
+      if (event.seq) lastSeq.current = event.seq;
+      apply(event);
+      return;
+    }
+    if (event.seq <= lastSeq.current) return;
+
+    if (event.seq === lastSeq.current + 1 && !syncing.current) {
+      lastSeq.current = event.seq;
+      apply(event);
+    } else {
+      resync();
+    }
+  }, [apply, resync]);
+
+  return { handleMessage, resync };
+}
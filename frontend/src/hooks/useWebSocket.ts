@@ -9,7 +9,7 @@ interface UseWebSocketOptions {
 }
 
 export function useWebSocket(options: UseWebSocketOptions = {}) {
-  const { user } = useAuthStore();
+  const { user, token } = useAuthStore();
   const wsRef = useRef<WebSocket | null>(null);
   const reconnectTimeoutRef = useRef<NodeJS.Timeout | null>(null);
   const reconnectAttempts = useRef(0);
@@ -17,12 +17,13 @@ export function useWebSocket(options: UseWebSocketOptions = {}) {
   const [isConnected, setIsConnected] = useState(false);
 
   const connect = useCallback(() => {
-    if (!user?.id) return;
+    if (!user?.id || !token) return;
 
-    const wsUrl = `ws://localhost:8080/api/ws?user_id=${user.id}`;
+    const wsUrl = `ws://localhost:8080/api/ws`;
     
     try {
-      wsRef.current = new WebSocket(wsUrl);
+      // The JWT travels as a subprotocol; the server echoes back only 'qicro.v1'
+      wsRef.current = new WebSocket(wsUrl, ['qicro.v1', `bearer.${token}`]);
 
       wsRef.current.onopen = () => {
         console.log('WebSocket connected');
@@ -34,6 +35,14 @@ export function useWebSocket(options: UseWebSocketOptions = {}) {
       wsRef.current.onmessage = (event) => {
         try {
           const data = JSON.parse(event.data);
+          if (data?.type === 'auth.expiring') {
+            // Renew the socket with the latest token before the server closes it with 4401
+            const latest = useAuthStore.getState().token;
+            if (latest) {
+              wsRef.current?.send(JSON.stringify({ type: 'auth', data: { token: latest } }));
+            }
+            return;
+          }
           options.onMessage?.(data);
         } catch (error) {
           console.error('Failed to parse WebSocket message:', error);
@@ -45,8 +54,8 @@ export function useWebSocket(options: UseWebSocketOptions = {}) {
         setIsConnected(false);
         options.onDisconnect?.();
 
-        // Attempt to reconnect if not manually closed
-        if (event.code !== 1000 && reconnectAttempts.current < maxReconnectAttempts) {
+        // Attempt to reconnect if not manually closed or rejected for auth (4401/4403)
+        if (event.code !== 1000 && event.code !== 4401 && event.code !== 4403 && reconnectAttempts.current < maxReconnectAttempts) {
           const timeout = Math.pow(2, reconnectAttempts.current) * 1000; // Exponential backoff
           reconnectTimeoutRef.current = setTimeout(() => {
             reconnectAttempts.current++;
@@ -64,7 +73,7 @@ export function useWebSocket(options: UseWebSocketOptions = {}) {
     } catch (error) {
       console.error('Failed to create WebSocket connection:', error);
     }
-  }, [user?.id]);
+  }, [user?.id, token]);
 
   const disconnect = useCallback(() => {
     if (reconnectTimeoutRef.current) {
@@ -1,4 +1,4 @@
-import { ChatMessage, Conversation, ChatRequest, Model, Provider } from '@/types/chat';
+import { ChatMessage, Conversation, ChatRequest, Model, Provider, SyncEventPage } from '@/types/chat';
 
 const API_BASE = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
@@ -39,7 +39,8 @@ class ChatAPI {
   }
 
   // 对话相关
-  async createConversation(title: string, model: string): Promise<Conversation> {
+  // Omit the title to let the server name the conversation after the first reply
+  async createConversation(model: string, title?: string): Promise<Conversation> {
     const response = await fetch(`${API_BASE}/api/conversations`, {
       method: 'POST',
       headers: this.getHeaders(),
@@ -155,6 +156,20 @@ class ChatAPI {
 
     return response.json();
   }
+
+  // 同步相关
+  async getEvents(after: number): Promise<SyncEventPage> {
+    const response = await fetch(`${API_BASE}/api/events?after=${after}`, {
+      headers: this.getHeaders(),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to get events');
+    }
+
+    return response.json();
+  }
 }
 
 export const chatAPI = new ChatAPI();
\ No newline at end of file
@@ -18,8 +18,7 @@ interface ChatState {
   loadModels: () => Promise<void>;
   loadProviders: () => Promise<void>;
   setSelectedModel: (model: string) => void;
-  createConversation: (title: string, model: string) => Promise<Conversation>;
-  generateTitle: (conversationId: string, firstMessage: string) => Promise<void>;
+  createConversation: (model: string, title?: string) => Promise<Conversation>;
   selectConversation: (id: string) => Promise<void>;
   sendMessage: (content: string, stream?: boolean) => Promise<void>;
   sendMessageStream: (content: string) => Promise<void>;
@@ -49,7 +48,7 @@ export const useChatStore = create<ChatState>((set, get) => ({
       if (conversations.length === 0) {
         const { models } = await chatAPI.getModels();
         const defaultModel = models.length > 0 ? models[0].id : 'gpt-3.5-turbo';
-        const defaultConversation = await chatAPI.createConversation('New Chat', defaultModel);
+        const defaultConversation = await chatAPI.createConversation(defaultModel);
         set({ 
           conversations: [defaultConversation], 
           currentConversation: defaultConversation,
@@ -108,10 +107,10 @@ export const useChatStore = create<ChatState>((set, get) => ({
     }
   },
 
-  createConversation: async (title: string, model: string) => {
+  createConversation: async (model: string, title?: string) => {
     try {
       set({ isLoading: true, error: null });
-      const conversation = await chatAPI.createConversation(title, model);
+      const conversation = await chatAPI.createConversation(model, title);
       const { conversations } = get();
       set({ 
         conversations: [conversation, ...conversations],
@@ -130,20 +129,6 @@ export const useChatStore = create<ChatState>((set, get) => ({
     }
   },
 
-  generateTitle: async (conversationId: string, firstMessage: string) => {
-    try {
-      // Use a simple approach - extract key words or use first few words
-      const words = firstMessage.split(' ').slice(0, 4).join(' ');
-      const title = words.length > 30 ? words.substring(0, 30) + '...' : words;
-      
-      await get().updateConversation(conversationId, { title });
-    } catch (error) {
-      console.error('Failed to generate title:', error);
-      // If title generation fails, use a default
-      await get().updateConversation(conversationId, { title: 'New Chat' });
-    }
-  },
-
   selectConversation: async (id: string) => {
     try {
       set({ isLoading: true, error: null });
@@ -163,7 +148,7 @@ export const useChatStore = create<ChatState>((set, get) => ({
   },
 
   sendMessage: async (content: string, stream = false) => {
-    const { currentConversation, messages } = get();
+    const { currentConversation } = get();
     if (!currentConversation) {
       set({ error: 'No conversation selected' });
       return;
@@ -171,9 +156,6 @@ export const useChatStore = create<ChatState>((set, get) => ({
 
     console.log('Sending message:', { content, currentConversation: currentConversation.id, model: currentConversation.model, stream });
 
-    // Check if this is the first message and title is still "New Chat"
-    const isFirstMessage = messages.length === 0 && currentConversation.title === 'New Chat';
-
     try {
       set({ isLoading: true, error: null });
       
@@ -191,14 +173,6 @@ export const useChatStore = create<ChatState>((set, get) => ({
           isLoading: false
         });
       }
-
-      // Generate title if this is the first message
-      if (isFirstMessage) {
-        // Don't await title generation to avoid blocking the UI
-        get().generateTitle(currentConversation.id, content).catch(error => {
-          console.error('Failed to generate title:', error);
-        });
-      }
     } catch (error) {
       console.error('Error sending message:', error);
       set({ 
@@ -209,7 +183,7 @@ export const useChatStore = create<ChatState>((set, get) => ({
   },
 
   sendMessageStream: async (content: string) => {
-    const { currentConversation, messages } = get();
+    const { currentConversation } = get();
     if (!currentConversation) {
       set({ error: 'No conversation selected' });
       return;
@@ -217,9 +191,6 @@ export const useChatStore = create<ChatState>((set, get) => ({
 
     console.log('Sending stream message:', { content, currentConversation: currentConversation.id, model: currentConversation.model });
 
-    // Check if this is the first message and title is still "New Chat"
-    const isFirstMessage = messages.length === 0 && currentConversation.title === 'New Chat';
-
     try {
       set({ isStreaming: true, error: null });
       
@@ -343,14 +314,6 @@ export const useChatStore = create<ChatState>((set, get) => ({
       // Ensure streaming is stopped when loop ends
       console.log('Debug: Stream reading loop ended, stopping streaming');
       set({ isStreaming: false, isLoading: false });
-
-      // Generate title if this is the first message
-      if (isFirstMessage) {
-        // Don't await title generation to avoid blocking the UI
-        get().generateTitle(currentConversation.id, content).catch(error => {
-          console.error('Failed to generate title:', error);
-        });
-      }
     } catch (error) {
       console.error('Error in stream message:', error);
       set({ 
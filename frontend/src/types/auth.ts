@@ -3,6 +3,7 @@ export interface AuthUser {
   email: string;
   oauth_provider?: string;
   oauth_id?: string;
+  role?: 'user' | 'operator' | 'admin';
   created_at: string;
   updated_at: string;
 }
@@ -46,4 +46,19 @@ export interface Model {
 export interface Provider {
   name: string;
   models: Model[];
-}
\ No newline at end of file
+}
+
+export interface SyncEvent {
+  seq: number;
+  id: string;
+  type: string;
+  conversation_id: string;
+  data?: any;
+  created_at: string;
+}
+
+export interface SyncEventPage {
+  events: SyncEvent[];
+  seq: number;
+  resync_required: boolean;
+}